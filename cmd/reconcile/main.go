@@ -0,0 +1,43 @@
+// Command reconcile compares the ledger, payments and security deposits with the
+// payment provider's records and exits with a non-zero status when they disagree.
+// The fake provider only knows intents created by its own process, so use
+// GET /admin/reconciliation to reconcile against it instead
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+func main() {
+	ctx := context.Background()
+
+	pool := services.CreatePostgresConnectionPool(ctx)
+	defer pool.Close()
+
+	provider := services.CreatePaymentProvider()
+	discrepancies, err := models.ReconcileLedger(ctx, pool, provider)
+	helpers.ExitIfError(err)
+
+	for _, discrepancy := range discrepancies {
+		reference := "payment " + discrepancy.PaymentID
+		if discrepancy.SecurityDepositID != "" {
+			reference = "security deposit " + discrepancy.SecurityDepositID
+		}
+
+		log.Printf("%v: %v is %v in the ledger and %v at %v\n",
+			reference, discrepancy.Field, discrepancy.Ledger, discrepancy.Provider, provider.Name())
+	}
+
+	if len(discrepancies) > 0 {
+		pool.Close()
+		os.Exit(1)
+	}
+
+	log.Println("Ledger is reconciled with", provider.Name())
+}
@@ -10,6 +10,32 @@ const (
 	VerifyLoginTokenCookieName   = "pnt_2fa_token"
 	VerifyLoginTokenTTLInSeconds = 60 * 5
 
+	BookingRequestTTL       = 24 * time.Hour
+	CalendarFetchTimeout    = 15 * time.Second
+	CalendarTokenLength     = 32
+	ClaimResponseWindow     = 72 * time.Hour
+	ClaimWindow             = 48 * time.Hour
+	Currency                = "NGN"
+	DefaultSearchRadiusInKm = 25
+	EventsHeartbeatInterval = 30 * time.Second
+	MaxCalendarSizeInBytes  = 1 << 20
+	MaxClaimPhotos          = 10
+	MaxDocumentSizeInBytes  = 5 << 20
+	MaxFleetImportRows      = 500
+	MaxSavedSearches        = 20
+	MinimumPaymentAmount    = 100
+	MessageResponseWindow   = 24 * time.Hour
+	PaymentWebhookTolerance = 5 * time.Minute
+	PayoutMaxAttempts       = 5
+	PayoutRetryDelay        = 1 * time.Hour
+	PlatformFeeBasisPoints  = 1000
+	SavedSearchMatchOverlap = 5 * time.Minute
+	ReferralCreditAmount    = 200000
+	SearchDistanceDecayInKm = 10
+	TripStartWindow         = 1 * time.Hour
+
+	RedisEventsChannelPrefix = "events:"
+	RedisJobLockPrefix       = "job_lock:"
 	RedisResetPasswordPrefix = "reset_password:"
 	RedisResetPasswordTTL    = 1 * time.Hour
 	RedisVerifyEmailPrefix   = "verify_email:"
@@ -17,5 +43,39 @@ const (
 	RedisVerifyLoginPrefix   = "verify_login:"
 	RedisVerifyLoginTTL      = 5 * time.Minute
 
-	UsersTable = "users"
+	RoleAdmin   = "admin"
+	RoleHost    = "host"
+	RoleRenter  = "renter"
+	RoleSupport = "support"
+
+	AuditLogsTable               = "audit_logs"
+	BookingTaxLinesTable         = "booking_tax_lines"
+	BookingsTable                = "bookings"
+	CancellationsTable           = "cancellations"
+	ClaimsTable                  = "claims"
+	ConversationsTable           = "conversations"
+	CreditEntriesTable           = "credit_entries"
+	LedgerAccountsTable          = "ledger_accounts"
+	LedgerEntriesTable           = "ledger_entries"
+	LedgerTransactionsTable      = "ledger_transactions"
+	MessagesTable                = "messages"
+	NotificationPreferencesTable = "notification_preferences"
+	NotificationsTable           = "notifications"
+	PaymentTransitionsTable      = "payment_transitions"
+	PaymentWebhookEventsTable    = "payment_webhook_events"
+	PaymentsTable                = "payments"
+	PayoutAccountsTable          = "payout_accounts"
+	PayoutsTable                 = "payouts"
+	PromoCodesTable              = "promo_codes"
+	PromoRedemptionsTable        = "promo_redemptions"
+	SavedSearchMatchesTable      = "saved_search_matches"
+	SavedSearchesTable           = "saved_searches"
+	SecurityDepositsTable        = "security_deposits"
+	TaxRulesTable                = "tax_rules"
+	UsersTable                   = "users"
+	VehicleBlackoutsTable        = "vehicle_blackouts"
+	VehicleCalendarsTable        = "vehicle_calendars"
+	VehicleCohostsTable          = "vehicle_cohosts"
+	VehiclesTable                = "vehicles"
+	VerificationCasesTable       = "verification_cases"
 )
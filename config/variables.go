@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -15,19 +17,33 @@ var (
 )
 
 var (
-	AccessTokenSecret       string
-	AppTokenSecret          string
-	AWSBucket               string
-	ClientOrigin            string
-	DatabaseURL             string
-	CaptchaSecretKey        string
-	Port                    string
-	RedisURL                string
-	RefreshTokenSecret      string
-	ResetPasswordTemplateID string
-	SendgridAPIKey          string
-	SendgridSender          string
-	VerifyEmailTemplateID   string
+	AccessTokenSecret        string
+	APIOrigin                string
+	AppTokenSecret           string
+	AWSBucket                string
+	BookingUpdatedTemplateID string
+	ClientOrigin             string
+	CohostInviteTemplateID   string
+	DatabaseURL              string
+	CaptchaSecretKey         string
+	PaymentProvider          string
+	PaymentWebhookSecret     string
+	PayoutFailedTemplateID   string
+	PayoutHoldPeriod         time.Duration
+	PayoutProvider           string
+	Port                     string
+	RedisURL                 string
+	RefreshTokenSecret       string
+	ResetPasswordTemplateID  string
+	SavedSearchTemplateID    string
+	SendgridAPIKey           string
+	SendgridSender           string
+	StorageDirectory         string
+	StorageDriver            string
+	UnreadMessagesDelay      time.Duration
+	UnreadMessagesTemplateID string
+	VerifyEmailTemplateID    string
+	VINDecoder               string
 )
 
 func init() {
@@ -48,20 +64,72 @@ func init() {
 	}
 
 	AccessTokenSecret = os.Getenv("APP_ACCESS_SECRET")
+	APIOrigin = os.Getenv("API_ORIGIN")
 	AppTokenSecret = os.Getenv("APP_TOKEN_SECRET")
 	AWSBucket = os.Getenv("AWS_BUCKET")
+	BookingUpdatedTemplateID = os.Getenv("BOOKING_UPDATED_TEMPLATE_ID")
 	ClientOrigin = os.Getenv("CLIENT_ORIGIN")
+	CohostInviteTemplateID = os.Getenv("COHOST_INVITE_TEMPLATE_ID")
 	DatabaseURL = os.Getenv("DATABASE_URL")
 	CaptchaSecretKey = os.Getenv("CAPTCHA_SECRET_KEY")
+	PaymentProvider = os.Getenv("PAYMENT_PROVIDER")
+	PaymentWebhookSecret = os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	PayoutFailedTemplateID = os.Getenv("PAYOUT_FAILED_TEMPLATE_ID")
+	PayoutProvider = os.Getenv("PAYOUT_PROVIDER")
 	Port = os.Getenv("PORT")
 	RedisURL = os.Getenv("REDIS_URL")
 	RefreshTokenSecret = os.Getenv("REFRESH_TOKEN_SECRET")
 	ResetPasswordTemplateID = os.Getenv("RESET_PASSWORD_TEMPLATE_ID")
+	SavedSearchTemplateID = os.Getenv("SAVED_SEARCH_TEMPLATE_ID")
 	SendgridAPIKey = os.Getenv("SENDGRID_API_KEY")
 	SendgridSender = os.Getenv("SENDGRID_SENDER")
+	StorageDirectory = os.Getenv("STORAGE_DIRECTORY")
+	StorageDriver = os.Getenv("STORAGE_DRIVER")
+	UnreadMessagesTemplateID = os.Getenv("UNREAD_MESSAGES_TEMPLATE_ID")
 	VerifyEmailTemplateID = os.Getenv("VERIFY_EMAIL_TEMPLATE_ID")
+	VINDecoder = os.Getenv("VIN_DECODER")
+
+	if PaymentProvider == "" {
+		PaymentProvider = "fake"
+	}
+
+	if PayoutProvider == "" {
+		PayoutProvider = "fake"
+	}
 
 	if Port == "" {
 		Port = "5000"
 	}
+
+	if StorageDirectory == "" {
+		StorageDirectory = "uploads"
+	}
+
+	if StorageDriver == "" {
+		StorageDriver = "local"
+	}
+
+	if VINDecoder == "" {
+		VINDecoder = "offline"
+	}
+
+	UnreadMessagesDelay = 15 * time.Minute
+	if value := os.Getenv("UNREAD_MESSAGES_DELAY_IN_MINUTES"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		UnreadMessagesDelay = time.Duration(minutes) * time.Minute
+	}
+
+	PayoutHoldPeriod = 72 * time.Hour
+	if value := os.Getenv("PAYOUT_HOLD_PERIOD_IN_HOURS"); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		PayoutHoldPeriod = time.Duration(hours) * time.Hour
+	}
 }
@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +19,10 @@ import (
 	"github.com/Ekenzy-101/Pentahire-API/models"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
 )
 
 func CloseAccount(c *gin.Context) {
@@ -56,6 +67,154 @@ func ConfirmOTPKey(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Success"})
 }
 
+// The user is told about listings that match the search from now on, either as
+// they appear or in a daily digest
+func CreateSavedSearch(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateSavedSearchRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if messages := checkVehicleSearchFilters(&requestBody.Filters); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestBody.Filters.HasDates() && !requestBody.Filters.StartAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"start_at": "Start_at should be in the future"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the user serialises concurrent requests so that the limit holds
+	if _, err = tx.Exec(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", cliams.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	count := 0
+	if err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM saved_searches WHERE user_id = $1", cliams.ID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if count >= config.MaxSavedSearches {
+		c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("You can't have more than %v saved searches", config.MaxSavedSearches)})
+		return
+	}
+
+	savedSearch := &models.SavedSearch{
+		Filters:   requestBody.Filters,
+		Frequency: requestBody.Frequency,
+		Name:      requestBody.Name,
+		UserID:    cliams.ID,
+	}
+	if err = models.InsertSavedSearch(ctx, tx, savedSearch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"saved_search": savedSearch})
+}
+
+func CreateVerificationCase(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateVerificationCaseRequestBody{}
+	if messages := helpers.ValidateRequestForm(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	for _, document := range requestBody.Documents {
+		if document.Size > config.MaxDocumentSizeInBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"documents": "Each document should not be larger than 5MB"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	status := ""
+	sql := "SELECT status FROM verification_cases WHERE user_id = $1 AND type = $2 AND status <> 'rejected' LIMIT 1"
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, sql, cliams.ID, requestBody.Type).Scan(&status)
+	if err == nil && status == models.VerificationStatusApproved {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "This verification has already been approved"})
+		return
+	}
+
+	if err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You already have a pending verification of this type"})
+		return
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	verificationCase := &models.VerificationCase{
+		ID:     uuid.NewString(),
+		Type:   requestBody.Type,
+		UserID: cliams.ID,
+	}
+	storage := services.GetFileStorage()
+	for index, document := range requestBody.Documents {
+		key, err := storeVerificationDocument(ctx, storage, verificationCase, index, document)
+		if err != nil {
+			deleteFiles(ctx, storage, verificationCase.Documents)
+		}
+
+		if errors.Is(err, errUnsupportedDocument) {
+			c.JSON(http.StatusBadRequest, gin.H{"documents": "Documents should be JPEG or PNG images or PDF files"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		verificationCase.Documents = append(verificationCase.Documents, key)
+	}
+
+	err = models.InsertVerificationCase(ctx, pool, verificationCase)
+	if err != nil {
+		deleteFiles(ctx, storage, verificationCase.Documents)
+	}
+
+	pgErr := new(pgconn.PgError)
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You already have a pending verification of this type"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"verification_case": verificationCase})
+}
+
 func DeleteOTPKey(c *gin.Context) {
 	authUser := c.MustGet("user")
 	cliams := authUser.(*services.AccessTokenClaims)
@@ -77,6 +236,198 @@ func DeleteOTPKey(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Success"})
 }
 
+func DeleteSavedSearch(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	savedSearchId := c.Param("id")
+	if _, err := uuid.Parse(savedSearchId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Saved search with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	tag, err := pool.Exec(ctx, "DELETE FROM saved_searches WHERE id = $1 AND user_id = $2", savedSearchId, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Saved search not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// Returns what the host earned and the taxes collected on their bookings in each
+// month of the year as CSV, with the year's totals on the last row. Amounts are in
+// the minor unit of the currency
+func ExportTaxSummary(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &GetTaxSummaryQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestQuery.Year == 0 {
+		requestQuery.Year = time.Now().UTC().Year()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	summaries, err := models.SelectHostTaxSummaries(ctx, pool, cliams.ID, requestQuery.Year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	records := [][]string{{"month", "currency", "bookings", "gross_amount", "tax_amount", "platform_fee", "earnings"}}
+	total := models.TaxSummary{}
+	for _, summary := range summaries {
+		records = append(records, []string{
+			fmt.Sprintf("%d-%02d", requestQuery.Year, summary.Month),
+			config.Currency,
+			strconv.Itoa(summary.Bookings),
+			strconv.Itoa(summary.GrossAmount),
+			strconv.Itoa(summary.TaxAmount),
+			strconv.Itoa(summary.PlatformFee),
+			strconv.Itoa(summary.Earnings),
+		})
+		total.GrossAmount += summary.GrossAmount
+		total.TaxAmount += summary.TaxAmount
+		total.PlatformFee += summary.PlatformFee
+		total.Earnings += summary.Earnings
+	}
+
+	// A booking can have entries in more than one month, so the months' bookings
+	// aren't added up
+	records = append(records, []string{
+		"total",
+		config.Currency,
+		"",
+		strconv.Itoa(total.GrossAmount),
+		strconv.Itoa(total.TaxAmount),
+		strconv.Itoa(total.PlatformFee),
+		strconv.Itoa(total.Earnings),
+	})
+	if err = writer.WriteAll(records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tax-summary-%d.csv"`, requestQuery.Year))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
+
+// Returns what the platform owes the host along with the ledger entries behind it
+func GetBalance(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	balance, err := models.SelectHostBalance(ctx, pool, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(e)), '[]') FROM (
+		SELECT e.id, 
+			-e.amount AS amount, 
+			t.created_at, 
+			t.description, 
+			t.reference_id, 
+			t.reference_type, 
+			t.type
+		FROM ledger_entries AS e
+		JOIN ledger_accounts AS a ON e.account_id = a.id
+		JOIN ledger_transactions AS t ON e.transaction_id = t.id
+		WHERE a.type = $1 AND a.user_id = $2
+		ORDER BY t.created_at DESC
+		LIMIT $3 OFFSET $4
+	) AS e`
+	entries := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, models.LedgerAccountHost, cliams.ID, limit, offset).Scan(&entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance, "currency": config.Currency, "entries": entries, "page": requestQuery.Page})
+}
+
+// Returns the user's credit balance and referral code along with the entries
+// behind the balance
+func GetCredits(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	balance, err := models.SelectCreditBalance(ctx, pool, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	referralCode := ""
+	err = pool.QueryRow(ctx, "SELECT referral_code FROM users WHERE id = $1", cliams.ID).Scan(&referralCode)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(e)), '[]') FROM (
+		SELECT id, amount, booking_id, created_at, description
+		FROM credit_entries
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	) AS e`
+	entries := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, cliams.ID, limit, offset).Scan(&entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":       balance,
+		"currency":      config.Currency,
+		"entries":       entries,
+		"page":          requestQuery.Page,
+		"referral_code": referralCode,
+	})
+}
+
 func GetOTPKey(c *gin.Context) {
 	authUser := c.MustGet("user")
 	cliams := authUser.(*services.AccessTokenClaims)
@@ -112,6 +463,133 @@ func GetOTPKey(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"secret": key.Secret(), "url": key.URL()})
 }
 
+func GetPayoutAccount(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT to_jsonb(a) FROM (
+		SELECT id, 
+			account_name, 
+			account_number_last4, 
+			bank_code, 
+			created_at, 
+			provider, 
+			updated_at, 
+			user_id, 
+			verified_at
+		FROM payout_accounts
+		WHERE user_id = $1
+	) AS a`
+	payoutAccount := gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, sql, cliams.ID).Scan(&payoutAccount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "You haven't added a payout account"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payout_account": payoutAccount})
+}
+
+func GetPayouts(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(p)), '[]') FROM (
+		SELECT id, 
+			amount, 
+			attempts, 
+			created_at, 
+			currency, 
+			failure_reason, 
+			next_attempt_at, 
+			paid_at, 
+			payout_account_id, 
+			status, 
+			user_id
+		FROM payouts
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	) AS p`
+	payouts := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, cliams.ID, limit, offset).Scan(&payouts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payouts": payouts, "page": requestQuery.Page})
+}
+
+func GetSavedSearches(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(s) ORDER BY s.created_at DESC), '[]') FROM (
+		SELECT id, created_at, filters, frequency, name, notified_at, user_id
+		FROM saved_searches
+		WHERE user_id = $1
+	) AS s`
+	savedSearches := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, cliams.ID).Scan(&savedSearches); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_searches": savedSearches})
+}
+
+func GetVerificationCases(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(vc) ORDER BY vc.created_at DESC), '[]') FROM (
+		SELECT id, 
+			created_at, 
+			cardinality(documents) AS documents_count, 
+			rejection_reason, 
+			reviewed_at, 
+			status, 
+			type, 
+			user_id
+		FROM verification_cases
+		WHERE user_id = $1
+	) AS vc`
+	verificationCases := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, cliams.ID).Scan(&verificationCases); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verification_cases": verificationCases})
+}
+
 func UpdatePassword(c *gin.Context) {
 	authUser := c.MustGet("user")
 	cliams := authUser.(*services.AccessTokenClaims)
@@ -168,6 +646,48 @@ func UpdatePassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Success"})
 }
 
+// The account is verified with the payout provider before it is saved and only
+// the last four digits of the account number are kept
+func UpdatePayoutAccount(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &UpdatePayoutAccountRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	provider := services.GetPayoutProvider()
+	details, err := provider.VerifyAccount(ctx, requestBody.BankCode, requestBody.AccountNumber)
+	if errors.Is(err, services.ErrPayoutAccountNotVerified) {
+		c.JSON(http.StatusBadRequest, gin.H{"account_number": "We couldn't verify this account with the bank"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+		return
+	}
+
+	payoutAccount := &models.PayoutAccount{
+		AccountName:        details.AccountName,
+		AccountNumberLast4: details.AccountNumber[len(details.AccountNumber)-4:],
+		BankCode:           details.BankCode,
+		Provider:           provider.Name(),
+		ProviderAccountID:  details.ProviderAccountID,
+		UserID:             cliams.ID,
+	}
+	pool := services.GetPostgresConnectionPool()
+	if err = models.UpsertPayoutAccount(ctx, pool, payoutAccount); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payout_account": payoutAccount})
+}
+
 func UpdateProfile(c *gin.Context) {
 	authUser := c.MustGet("user")
 	cliams := authUser.(*services.AccessTokenClaims)
@@ -223,7 +743,7 @@ func UpdateProfile(c *gin.Context) {
 			return
 		}
 
-		err = user.SendEmailVerificationMail(token)
+		err = user.SendEmailVerificationMail(ctx, token)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 			return
@@ -232,3 +752,63 @@ func UpdateProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Success"})
 }
+
+var (
+	errUnsupportedDocument = errors.New("unsupported document type")
+
+	documentExtensions = map[string]string{
+		"application/pdf": ".pdf",
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+	}
+	photoExtensions = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+	}
+)
+
+func deleteFiles(ctx context.Context, storage services.FileStorage, keys []string) {
+	for _, key := range keys {
+		if err := storage.Delete(ctx, key); err != nil {
+			log.Printf("deleteFiles %v: %v\n", key, err)
+		}
+	}
+}
+
+func storeVerificationDocument(ctx context.Context, storage services.FileStorage, verificationCase *models.VerificationCase, index int, document *multipart.FileHeader) (string, error) {
+	key := fmt.Sprintf("verifications/%v/%v/%v", verificationCase.UserID, verificationCase.ID, index)
+	return storeUploadedFile(ctx, storage, key, document, documentExtensions)
+}
+
+// The extension is derived from the sniffed content rather than the uploaded
+// filename so that the stored file can be served back with the right type
+func storeUploadedFile(ctx context.Context, storage services.FileStorage, keyWithoutExtension string, upload *multipart.FileHeader, extensions map[string]string) (string, error) {
+	file, err := upload.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	extension, ok := extensions[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", errUnsupportedDocument
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	key := keyWithoutExtension + extension
+	if err = storage.Put(ctx, key, file); err != nil {
+		deleteFiles(ctx, storage, []string{key})
+		return "", err
+	}
+
+	return key, nil
+}
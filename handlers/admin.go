@@ -0,0 +1,1115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+func ApproveVerificationCase(c *gin.Context) {
+	reviewVerificationCase(c, models.VerificationStatusApproved)
+}
+
+func CreatePromoCode(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreatePromoCodeRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestBody.DiscountType == models.PromoCodeDiscountTypePercentage && requestBody.DiscountValue > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"discount_value": "Discount_value should not be greater than 100 for a percentage"})
+		return
+	}
+
+	if requestBody.ExpiresAt != nil && !requestBody.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"expires_at": "Expires_at should be in the future"})
+		return
+	}
+
+	promoCode := &models.PromoCode{
+		Code:                  strings.ToUpper(requestBody.Code),
+		DiscountType:          requestBody.DiscountType,
+		DiscountValue:         requestBody.DiscountValue,
+		ExpiresAt:             requestBody.ExpiresAt,
+		FirstTripOnly:         requestBody.FirstTripOnly,
+		MaxRedemptions:        requestBody.MaxRedemptions,
+		MaxRedemptionsPerUser: 1,
+		MinTripDays:           1,
+		UserID:                &cliams.ID,
+	}
+	if requestBody.MaxRedemptionsPerUser > 0 {
+		promoCode.MaxRedemptionsPerUser = requestBody.MaxRedemptionsPerUser
+	}
+
+	if requestBody.MinTripDays > 0 {
+		promoCode.MinTripDays = requestBody.MinTripDays
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionCreatePromoCode,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"code": promoCode.Code, "discount_type": promoCode.DiscountType, "discount_value": promoCode.DiscountValue},
+		TargetType: models.AuditTargetPromoCode,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		if err := models.InsertPromoCode(ctx, tx, promoCode); err != nil {
+			return err
+		}
+
+		auditLog.TargetID = promoCode.ID
+		return nil
+	})
+	pgErr := new(pgconn.PgError)
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		c.JSON(http.StatusConflict, gin.H{"code": "Promo code already exists"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"promo_code": promoCode})
+}
+
+// The rule applies to trips priced from then on. One without an address keyword or
+// bounds applies to every vehicle, except for the taxes a more specific rule charges
+func CreateTaxRule(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateTaxRuleRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestBody.PerDaySurcharge == 0 && requestBody.RentalTaxBasisPoints == 0 && requestBody.VATBasisPoints == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "A tax rule needs a rental tax, a VAT or a per day surcharge"})
+		return
+	}
+
+	bounds := []*float64{requestBody.MaxLatitude, requestBody.MaxLongitude, requestBody.MinLatitude, requestBody.MinLongitude}
+	given := 0
+	for _, bound := range bounds {
+		if bound != nil {
+			given++
+		}
+	}
+
+	if given != 0 && given != len(bounds) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Either all of the bounds or none of them should be given"})
+		return
+	}
+
+	if given != 0 && (*requestBody.MinLatitude > *requestBody.MaxLatitude || *requestBody.MinLongitude > *requestBody.MaxLongitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "The minimum bounds should not be greater than the maximum bounds"})
+		return
+	}
+
+	// Keywords are matched against one part of the address at a time
+	if strings.Contains(requestBody.AddressKeyword, ",") {
+		c.JSON(http.StatusBadRequest, gin.H{"address_keyword": "Address_keyword should be a single part of an address, like a city, without commas"})
+		return
+	}
+
+	rule := &models.TaxRule{
+		Jurisdiction:         strings.TrimSpace(requestBody.Jurisdiction),
+		MaxLatitude:          requestBody.MaxLatitude,
+		MaxLongitude:         requestBody.MaxLongitude,
+		MinLatitude:          requestBody.MinLatitude,
+		MinLongitude:         requestBody.MinLongitude,
+		PerDaySurcharge:      requestBody.PerDaySurcharge,
+		RentalTaxBasisPoints: requestBody.RentalTaxBasisPoints,
+		VATBasisPoints:       requestBody.VATBasisPoints,
+	}
+	if keyword := strings.TrimSpace(requestBody.AddressKeyword); keyword != "" {
+		rule.AddressKeyword = &keyword
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionCreateTaxRule,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"jurisdiction": rule.Jurisdiction},
+		TargetType: models.AuditTargetTaxRule,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		if err := models.InsertTaxRule(ctx, tx, rule); err != nil {
+			return err
+		}
+
+		auditLog.TargetID = rule.ID
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"tax_rule": rule})
+}
+
+// Bookings already made keep the taxes they were priced with
+func DeleteTaxRule(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	ruleId := c.Param("id")
+	if _, err := uuid.Parse(ruleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Tax rule with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionDeleteTaxRule,
+		ActorID:    cliams.ID,
+		TargetID:   ruleId,
+		TargetType: models.AuditTargetTaxRule,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		jurisdiction := ""
+		err := tx.QueryRow(ctx, "DELETE FROM tax_rules WHERE id = $1 RETURNING jurisdiction", ruleId).Scan(&jurisdiction)
+		auditLog.Metadata = gin.H{"jurisdiction": jurisdiction}
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Tax rule not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func GetAuditLogs(c *gin.Context) {
+	requestQuery := &SearchAuditLogsQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	conditions := []string{}
+	arguments := []interface{}{}
+	if requestQuery.ActorID != "" {
+		arguments = append(arguments, requestQuery.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%v", len(arguments)))
+	}
+
+	if requestQuery.TargetID != "" {
+		arguments = append(arguments, requestQuery.TargetID)
+		conditions = append(conditions, fmt.Sprintf("target_id = $%v", len(arguments)))
+	}
+
+	if requestQuery.TargetType != "" {
+		arguments = append(arguments, requestQuery.TargetType)
+		conditions = append(conditions, fmt.Sprintf("target_type = $%v", len(arguments)))
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(a)), '[]') FROM (
+		SELECT id, action, actor_id, created_at, metadata, target_id, target_type
+		FROM audit_logs
+		%v
+		ORDER BY created_at DESC
+		LIMIT $%v OFFSET $%v
+	) AS a`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLogs := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&auditLogs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"audit_logs": auditLogs, "page": requestQuery.Page})
+}
+
+// Redemptions are only counted while their booking is going ahead
+func GetPromoCodes(c *gin.Context) {
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(p)), '[]') FROM (
+		SELECT p.id,
+			p.code,
+			p.created_at,
+			p.discount_type,
+			p.discount_value,
+			p.expires_at,
+			p.first_trip_only,
+			p.max_redemptions,
+			p.max_redemptions_per_user,
+			p.min_trip_days,
+			(SELECT COUNT(*) FROM promo_redemptions AS r JOIN bookings AS b ON r.booking_id = b.id
+				WHERE r.promo_code_id = p.id AND b.status = ANY($1)) AS redemptions_count,
+			p.user_id
+		FROM promo_codes AS p
+		ORDER BY p.created_at DESC
+		LIMIT $2 OFFSET $3
+	) AS p`
+	statuses := append([]string{models.BookingStatusCompleted}, models.BlockingBookingStatuses...)
+	promoCodes := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, statuses, limit, offset).Scan(&promoCodes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"page": requestQuery.Page, "promo_codes": promoCodes})
+}
+
+func GetReconciliation(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	discrepancies, err := models.ReconcileLedger(ctx, pool, services.GetPaymentProvider())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"discrepancies": discrepancies})
+}
+
+func GetTaxRules(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(r) ORDER BY r.jurisdiction, r.created_at), '[]')
+	FROM tax_rules AS r`
+	rules := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql).Scan(&rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tax_rules": rules})
+}
+
+func GetVerificationDocument(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	verificationCaseId := c.Param("id")
+	if _, err := uuid.Parse(verificationCaseId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Verification case with the given id is invalid"})
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Document index is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	documents := []string{}
+	pool := services.GetPostgresConnectionPool()
+	err = pool.QueryRow(ctx, "SELECT documents FROM verification_cases WHERE id = $1", verificationCaseId).Scan(&documents)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Verification case not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if index >= len(documents) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Document not found"})
+		return
+	}
+
+	file, err := services.GetFileStorage().Open(ctx, documents[index])
+	if errors.Is(err, services.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Document not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// Identity documents are sensitive so every view is audited as well
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionViewVerificationDocument,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"index": index},
+		TargetID:   verificationCaseId,
+		TargetType: models.AuditTargetVerificationCase,
+	}
+	if err = auditLog.Insert(ctx, pool); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(documents[index]))
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
+
+// Refunds part or all of what the renter was charged, e.g. as a goodwill gesture
+// or after a dispute. Refunds larger than the rental payment go on to the
+// booking's charges
+func RefundBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	requestBody := &RefundRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionRefundBooking,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"amount": requestBody.Amount, "reason": requestBody.Reason},
+		TargetID:   bookingId,
+		TargetType: models.AuditTargetBooking,
+	}
+	var payment *models.Payment
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		var err error
+		payment, err = refundBooking(ctx, tx, bookingId, requestBody.Amount)
+		if err == nil && payment == nil {
+			return pgx.ErrNoRows
+		}
+
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Payment not found"})
+		return
+	}
+
+	if err != nil {
+		response := paymentErrorResponse(err)
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment": payment})
+}
+
+func RejectVerificationCase(c *gin.Context) {
+	reviewVerificationCase(c, models.VerificationStatusRejected)
+}
+
+func RelistVehicle(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionRelistVehicle,
+		ActorID:    cliams.ID,
+		TargetID:   vehicleId,
+		TargetType: models.AuditTargetVehicle,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		sql := "UPDATE vehicles SET unlisted_at = NULL, unlisting_reason = '' WHERE id = $1 RETURNING id"
+		return tx.QueryRow(ctx, sql, vehicleId).Scan(&vehicleId)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	// Another listing took the vehicle's VIN while it was unlisted
+	pgErr := new(pgconn.PgError)
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		c.JSON(http.StatusConflict, gin.H{"message": "A listed vehicle already has this vehicle's vin"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// Decides a disputed claim. The renter pays the amount given, which may be
+// anything from nothing up to the amount claimed
+func ResolveClaim(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	claimId := c.Param("id")
+	if _, err := uuid.Parse(claimId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Claim with the given id is invalid"})
+		return
+	}
+
+	requestBody := &ResolveClaimRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claim, booking, response := updateClaim(ctx, claimId, func(tx pgx.Tx, claim *models.Claim, booking *models.Booking) *models.SQLResponse {
+		if claim.Status != models.ClaimStatusDisputed {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only disputed claims can be resolved"}}
+		}
+
+		if *requestBody.Amount > claim.Amount {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"amount": "Amount should not be more than the amount claimed"}}
+		}
+
+		response := settleClaim(ctx, tx, claim, models.ClaimStatusResolved, *requestBody.Amount, &cliams.ID, requestBody.Reason)
+		if response != nil {
+			return response
+		}
+
+		auditLog := &models.AuditLog{
+			Action:     models.AuditActionResolveClaim,
+			ActorID:    cliams.ID,
+			Metadata:   gin.H{"amount": *requestBody.Amount, "reason": requestBody.Reason},
+			TargetID:   claim.ID,
+			TargetType: models.AuditTargetClaim,
+		}
+		if err := auditLog.Insert(ctx, tx); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	notifyBookingUpdated(booking, booking.HostID, "Your damage claim was resolved")
+	notifyBookingUpdated(booking, booking.UserID, "The damage claim for your trip was resolved")
+	c.JSON(http.StatusOK, gin.H{"claim": claim})
+}
+
+// Charges the renter again for overage fees whose charge failed, e.g. once they've
+// topped up their card. The new charge is recorded even when it fails again
+func RetryOverageCharge(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var charge *models.BookingCharge
+	_, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		failedCharge, attempts, err := models.SelectRetryableBookingCharge(ctx, tx, booking.ID, models.BookingChargeTypeOverage)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "No failed overage charge found"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		// Every attempt needs its own intent, so the attempt is part of the charge's key
+		referenceId := fmt.Sprintf("%v:%v", booking.ID, attempts)
+		charge, err = chargeBooking(ctx, tx, booking, failedCharge.Type, referenceId, failedCharge.Amount, failedCharge.TaxAmount, failedCharge.Description)
+		if err != nil {
+			return paymentErrorResponse(err)
+		}
+
+		if charge == nil {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Payment not found"}}
+		}
+
+		if charge.FailureReason == "" {
+			if err = models.MoveBookingTaxLines(ctx, tx, failedCharge.ID, charge.ID); err != nil {
+				return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+			}
+
+			if response := reviseBookingInvoice(ctx, tx, booking); response != nil {
+				return response
+			}
+		}
+
+		auditLog := &models.AuditLog{
+			Action:     models.AuditActionRetryOverageCharge,
+			ActorID:    cliams.ID,
+			Metadata:   gin.H{"amount": charge.Amount, "charge_id": charge.ID, "failure_reason": charge.FailureReason},
+			TargetID:   booking.ID,
+			TargetType: models.AuditTargetBooking,
+		}
+		if err = auditLog.Insert(ctx, tx); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if charge.FailureReason != "" {
+		c.JSON(http.StatusPaymentRequired, gin.H{"charge": charge, "message": "The renter's payment method was declined again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"charge": charge})
+}
+
+func SearchBookings(c *gin.Context) {
+	requestQuery := &SearchBookingsQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	conditions := []string{}
+	arguments := []interface{}{}
+	if requestQuery.Status != "" {
+		arguments = append(arguments, requestQuery.Status)
+		conditions = append(conditions, fmt.Sprintf("b.status = $%v", len(arguments)))
+	}
+
+	if requestQuery.UserID != "" {
+		arguments = append(arguments, requestQuery.UserID)
+		conditions = append(conditions, fmt.Sprintf("(b.user_id = $%[1]v OR v.user_id = $%[1]v)", len(arguments)))
+	}
+
+	if requestQuery.VehicleID != "" {
+		arguments = append(arguments, requestQuery.VehicleID)
+		conditions = append(conditions, fmt.Sprintf("b.vehicle_id = $%v", len(arguments)))
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(b)), '[]') FROM (
+		SELECT b.id,
+			b.cancellation_policy,
+			b.completed_at,
+			b.created_at,
+			b.credit_amount,
+			b.daily_mileage_limit,
+			b.discount_amount,
+			b.end_at,
+			b.expires_at,
+			b.fuel_fee,
+			v.user_id AS host_id,
+			b.mileage_fee,
+			b.security_deposit,
+			b.start_at,
+			b.status,
+			b.tax_amount,
+			b.total_amount,
+			b.user_id,
+			b.vehicle_id
+		FROM bookings AS b
+		JOIN vehicles AS v ON b.vehicle_id = v.id
+		%v
+		ORDER BY b.created_at DESC
+		LIMIT $%v OFFSET $%v
+	) AS b`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bookings := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings, "page": requestQuery.Page})
+}
+
+func SearchClaims(c *gin.Context) {
+	requestQuery := &SearchClaimsQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	conditions := []string{}
+	arguments := []interface{}{}
+	if requestQuery.Status != "" {
+		arguments = append(arguments, requestQuery.Status)
+		conditions = append(conditions, fmt.Sprintf("cl.status = $%v", len(arguments)))
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(cl)), '[]') FROM (
+		SELECT cl.id,
+			cl.amount,
+			cl.booking_id,
+			cl.created_at,
+			cl.description,
+			cl.dispute_reason,
+			cardinality(cl.photos) AS photos_count,
+			cl.resolution_reason,
+			cl.resolved_at,
+			cl.resolved_by,
+			cl.settled_amount,
+			cl.status,
+			cl.updated_at,
+			cl.user_id
+		FROM claims AS cl
+		%v
+		ORDER BY cl.created_at ASC
+		LIMIT $%v OFFSET $%v
+	) AS cl`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claims := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claims": claims, "page": requestQuery.Page})
+}
+
+func SearchUsers(c *gin.Context) {
+	requestQuery := &SearchUsersQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	conditions := []string{}
+	arguments := []interface{}{}
+	if requestQuery.Query != "" {
+		arguments = append(arguments, "%"+requestQuery.Query+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(email ILIKE $%[1]v OR firstname ILIKE $%[1]v OR lastname ILIKE $%[1]v OR CAST(id AS TEXT) ILIKE $%[1]v)",
+			len(arguments),
+		))
+	}
+
+	if requestQuery.Role != "" {
+		arguments = append(arguments, requestQuery.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%v", len(arguments)))
+	}
+
+	if requestQuery.Status == "active" {
+		conditions = append(conditions, "suspended_at IS NULL")
+	}
+
+	if requestQuery.Status == "suspended" {
+		conditions = append(conditions, "suspended_at IS NOT NULL")
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(u)), '[]') FROM (
+		SELECT id,
+			created_at,
+			email,
+			firstname,
+			(
+				SELECT COUNT(*) FROM cancellations AS c 
+				WHERE c.user_id = users.id AND c.cancelled_by = 'host'
+			) AS host_cancellations_count,
+			lastname,
+			role,
+			suspended_at,
+			suspension_reason
+		FROM users
+		%v
+		ORDER BY created_at DESC
+		LIMIT $%v OFFSET $%v
+	) AS u`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&users); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "page": requestQuery.Page})
+}
+
+func SearchVerificationCases(c *gin.Context) {
+	requestQuery := &SearchVerificationCasesQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	conditions := []string{}
+	arguments := []interface{}{}
+	if requestQuery.Status != "" {
+		arguments = append(arguments, requestQuery.Status)
+		conditions = append(conditions, fmt.Sprintf("vc.status = $%v", len(arguments)))
+	}
+
+	if requestQuery.Type != "" {
+		arguments = append(arguments, requestQuery.Type)
+		conditions = append(conditions, fmt.Sprintf("vc.type = $%v", len(arguments)))
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(vc)), '[]') FROM (
+		SELECT vc.id,
+			vc.created_at,
+			cardinality(vc.documents) AS documents_count,
+			vc.rejection_reason,
+			vc.reviewed_at,
+			vc.reviewer_id,
+			vc.status,
+			vc.type,
+			jsonb_build_object('id', u.id, 'email', u.email, 'firstname', u.firstname, 'lastname', u.lastname) AS user
+		FROM verification_cases AS vc
+		JOIN users AS u ON vc.user_id = u.id
+		%v
+		ORDER BY vc.created_at ASC
+		LIMIT $%v OFFSET $%v
+	) AS vc`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verificationCases := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&verificationCases); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verification_cases": verificationCases, "page": requestQuery.Page})
+}
+
+func SuspendUser(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	userId := c.Param("id")
+	if _, err := uuid.Parse(userId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "User with the given id is invalid"})
+		return
+	}
+
+	if userId == cliams.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You can't suspend your own account"})
+		return
+	}
+
+	requestBody := &ReasonField{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionSuspendUser,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"reason": requestBody.Reason},
+		TargetID:   userId,
+		TargetType: models.AuditTargetUser,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		sql := "UPDATE users SET suspended_at = NOW(), suspension_reason = $1 WHERE id = $2 RETURNING id"
+		return tx.QueryRow(ctx, sql, requestBody.Reason, userId).Scan(&userId)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func UnlistVehicle(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &ReasonField{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionUnlistVehicle,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"reason": requestBody.Reason},
+		TargetID:   vehicleId,
+		TargetType: models.AuditTargetVehicle,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		sql := "UPDATE vehicles SET unlisted_at = NOW(), unlisting_reason = $1 WHERE id = $2 RETURNING id"
+		return tx.QueryRow(ctx, sql, requestBody.Reason, vehicleId).Scan(&vehicleId)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func UnsuspendUser(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	userId := c.Param("id")
+	if _, err := uuid.Parse(userId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "User with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionUnsuspendUser,
+		ActorID:    cliams.ID,
+		TargetID:   userId,
+		TargetType: models.AuditTargetUser,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		sql := "UPDATE users SET suspended_at = NULL, suspension_reason = '' WHERE id = $1 RETURNING id"
+		return tx.QueryRow(ctx, sql, userId).Scan(&userId)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func UpdateUserRole(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	userId := c.Param("id")
+	if _, err := uuid.Parse(userId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "User with the given id is invalid"})
+		return
+	}
+
+	if userId == cliams.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You can't change your own role"})
+		return
+	}
+
+	requestBody := &UpdateUserRoleRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionUpdateUserRole,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{"role": requestBody.Role},
+		TargetID:   userId,
+		TargetType: models.AuditTargetUser,
+	}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		previousRole := ""
+		sql := "SELECT role FROM users WHERE id = $1 FOR UPDATE"
+		if err := tx.QueryRow(ctx, sql, userId).Scan(&previousRole); err != nil {
+			return err
+		}
+
+		auditLog.Metadata["previous_role"] = previousRole
+		_, err := tx.Exec(ctx, "UPDATE users SET role = $1 WHERE id = $2", requestBody.Role, userId)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func reviewVerificationCase(c *gin.Context, status string) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	verificationCaseId := c.Param("id")
+	if _, err := uuid.Parse(verificationCaseId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Verification case with the given id is invalid"})
+		return
+	}
+
+	auditLog := &models.AuditLog{
+		Action:     models.AuditActionApproveVerification,
+		ActorID:    cliams.ID,
+		Metadata:   gin.H{},
+		TargetID:   verificationCaseId,
+		TargetType: models.AuditTargetVerificationCase,
+	}
+	requestBody := &ReasonField{}
+	if status == models.VerificationStatusRejected {
+		if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+			c.JSON(http.StatusBadRequest, messages)
+			return
+		}
+
+		auditLog.Action = models.AuditActionRejectVerification
+		auditLog.Metadata["reason"] = requestBody.Reason
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	verificationCase := &models.VerificationCase{}
+	err := executeAdminAction(ctx, auditLog, func(tx pgx.Tx) error {
+		sql := `
+		UPDATE verification_cases 
+		SET status = $1, rejection_reason = $2, reviewed_at = NOW(), reviewer_id = $3 
+		WHERE id = $4 AND status = 'pending'
+		RETURNING id, created_at, cardinality(documents), rejection_reason, reviewed_at, status, type, user_id`
+		arguments := []interface{}{status, requestBody.Reason, cliams.ID, verificationCaseId}
+		destination := []interface{}{
+			&verificationCase.ID,
+			&verificationCase.CreatedAt,
+			&verificationCase.DocumentsCount,
+			&verificationCase.RejectionReason,
+			&verificationCase.ReviewedAt,
+			&verificationCase.Status,
+			&verificationCase.Type,
+			&verificationCase.UserID,
+		}
+		if err := tx.QueryRow(ctx, sql, arguments...).Scan(destination...); err != nil {
+			return err
+		}
+
+		auditLog.Metadata["type"] = verificationCase.Type
+		auditLog.Metadata["user_id"] = verificationCase.UserID
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Pending verification case not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verification_case": verificationCase})
+}
+
+// Runs the action and records it in the audit log within the same transaction
+// so that no admin action is ever applied without a trail
+func executeAdminAction(ctx context.Context, auditLog *models.AuditLog, action func(tx pgx.Tx) error) error {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = action(tx); err != nil {
+		return err
+	}
+
+	if err = auditLog.Insert(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
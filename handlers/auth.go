@@ -14,6 +14,7 @@ import (
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4"
 )
 
 func Login(c *gin.Context) {
@@ -27,10 +28,12 @@ func Login(c *gin.Context) {
 	defer cancel()
 
 	user := &models.User{}
+	isSuspended := false
+	returnColumns := helpers.GenerateUserReturnColumns([]string{})
 	options := models.SQLOptions{
 		Arguments:         []interface{}{requestBody.Email},
 		AfterTableClauses: `WHERE email = $1`,
-		ReturnColumns:     helpers.GenerateUserReturnColumns([]string{}),
+		ReturnColumns:     append(returnColumns, "suspended_at IS NOT NULL AS is_suspended"),
 		Destination: []interface{}{
 			&user.ID,
 			&user.AverageRating,
@@ -40,12 +43,15 @@ func Login(c *gin.Context) {
 			&user.Image,
 			&user.Is2FAEnabled,
 			&user.IsEmailVerified,
+			&user.IsIdentityVerified,
 			&user.IsPhoneVerified,
 			&user.Lastname,
 			&user.Password,
 			&user.PhoneNo,
 			&user.ReviewsCount,
+			&user.Role,
 			&user.TripsCount,
+			&isSuspended,
 		},
 	}
 	response := models.SelectUserRow(ctx, options)
@@ -70,6 +76,11 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if isSuspended {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Your account has been suspended"})
+		return
+	}
+
 	if user.Is2FAEnabled {
 		token, err := helpers.GenerateRandomToken(24)
 		if err != nil {
@@ -134,10 +145,12 @@ func Me(c *gin.Context) {
 			&user.Image,
 			&user.Is2FAEnabled,
 			&user.IsEmailVerified,
+			&user.IsIdentityVerified,
 			&user.IsPhoneVerified,
 			&user.Lastname,
 			&user.PhoneNo,
 			&user.ReviewsCount,
+			&user.Role,
 			&user.TripsCount,
 		},
 	}
@@ -147,9 +160,17 @@ func Me(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	unreadMessagesCount, err := models.CountUnreadMessages(ctx, services.GetPostgresConnectionPool(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "unread_messages_count": unreadMessagesCount})
 }
 
+// Users who sign up with someone's referral code are both rewarded once the new
+// user completes their first trip
 func Register(c *gin.Context) {
 	requestBody := &RegisterRequestBody{}
 	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
@@ -190,6 +211,24 @@ func Register(c *gin.Context) {
 		ReturnColumns: []string{"id"},
 		Destination:   []interface{}{&user.ID},
 	}
+	if requestBody.ReferralCode != "" {
+		referrerId := ""
+		pool := services.GetPostgresConnectionPool()
+		err = pool.QueryRow(ctx, "SELECT id FROM users WHERE referral_code = upper($1)", requestBody.ReferralCode).Scan(&referrerId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"referral_code": "Referral code is invalid"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		options.Arguments = append(options.Arguments, referrerId)
+		options.InsertColumns = append(options.InsertColumns, "referred_by")
+	}
+
 	if response := models.InsertUserRow(ctx, options); response != nil {
 		c.JSON(response.StatusCode, response.Body)
 		return
@@ -208,7 +247,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	if err = user.SendEmailVerificationMail(token); err != nil {
+	if err = user.SendEmailVerificationMail(ctx, token); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
@@ -302,10 +341,11 @@ func VerifyLogin(c *gin.Context) {
 	user := &models.User{}
 	returnColumns := helpers.GenerateUserReturnColumns([]string{"password"})
 	secret := ""
+	isSuspended := false
 	option := models.SQLOptions{
 		Arguments:         []interface{}{requestBody.Email},
 		AfterTableClauses: `WHERE email = $1`,
-		ReturnColumns:     append(returnColumns, "otp_secret_key"),
+		ReturnColumns:     append(returnColumns, "otp_secret_key", "suspended_at IS NOT NULL AS is_suspended"),
 		Destination: []interface{}{
 			&user.ID,
 			&user.AverageRating,
@@ -315,12 +355,15 @@ func VerifyLogin(c *gin.Context) {
 			&user.Image,
 			&user.Is2FAEnabled,
 			&user.IsEmailVerified,
+			&user.IsIdentityVerified,
 			&user.IsPhoneVerified,
 			&user.Lastname,
 			&user.PhoneNo,
 			&user.ReviewsCount,
+			&user.Role,
 			&user.TripsCount,
 			&secret,
+			&isSuspended,
 		},
 	}
 	if response := models.SelectUserRow(ctx, option); response != nil {
@@ -333,6 +376,11 @@ func VerifyLogin(c *gin.Context) {
 		return
 	}
 
+	if isSuspended {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Your account has been suspended"})
+		return
+	}
+
 	accessToken, err := user.GenerateAccessToken()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
@@ -0,0 +1,835 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+var promoCodeMessages = map[error]string{
+	models.ErrPromoCodeExhausted:     "This promo code has reached its usage limit",
+	models.ErrPromoCodeExpired:       "This promo code has expired",
+	models.ErrPromoCodeFirstTripOnly: "This promo code is only for your first trip",
+	models.ErrPromoCodeTripTooShort:  "Your trip is too short for this promo code",
+}
+
+func AcceptBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCalendar, "Only the host can accept this booking"); response != nil {
+			return response
+		}
+
+		if booking.Status != models.BookingStatusPending {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "Only pending bookings can be accepted"},
+			}
+		}
+
+		if booking.ExpiresAt != nil && booking.ExpiresAt.Before(time.Now()) {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "This booking request has expired"},
+			}
+		}
+
+		return models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusConfirmed)
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	notifyBookingConfirmed(booking)
+	c.JSON(http.StatusOK, gin.H{"booking": booking})
+}
+
+// Either participant can cancel a booking before the trip starts, and co-hosts who
+// manage the calendar can cancel it for the host. The renter is refunded according
+// to the booking's cancellation policy and the rest of their payment is kept for
+// the host. Credits the renter spent on it are returned in full
+func CancelBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cancellation *models.Cancellation
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		actorId, err := selectCancellingUser(ctx, tx, booking, cliams.ID)
+		if err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		if actorId == "" {
+			return &models.SQLResponse{
+				StatusCode: http.StatusNotFound,
+				Body:       gin.H{"message": "Booking not found"},
+			}
+		}
+
+		if booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "Only pending or confirmed bookings can be cancelled"},
+			}
+		}
+
+		cancellation = models.CalculateCancellation(booking, actorId, time.Now())
+		if response := models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusCancelled); response != nil {
+			return response
+		}
+
+		if _, err := settleCancelledPayment(ctx, tx, booking.ID, cancellation.ChargeAmount(booking)); err != nil {
+			return paymentErrorResponse(err)
+		}
+
+		if err := models.InsertCancellation(ctx, tx, cancellation); err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		if err := models.RestoreBookingCredits(ctx, tx, booking); err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	recipientId := booking.HostID
+	if cancellation.CancelledBy == models.CancelledByHost {
+		recipientId = booking.UserID
+	}
+
+	notifyBookingUpdated(booking, recipientId, "Your booking has been cancelled")
+	c.JSON(http.StatusOK, gin.H{"booking": booking, "cancellation": cancellation})
+}
+
+// The host completes the trip once the vehicle has been returned. The host is paid
+// for any discount and credits the renter had, and a referred renter's first
+// completed trip rewards the referral
+func CompleteBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCheckIn, "Only the host can complete this trip"); response != nil {
+			return response
+		}
+
+		if booking.Status != models.BookingStatusInProgress {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "Only trips in progress can be completed"},
+			}
+		}
+
+		if response := models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusCompleted); response != nil {
+			return response
+		}
+
+		if err := models.PostBookingPromotionToLedger(ctx, tx, booking); err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		if err := models.RewardReferral(ctx, tx, booking); err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	notifyBookingUpdated(booking, booking.UserID, "Your trip has been completed")
+	c.JSON(http.StatusOK, gin.H{"booking": booking})
+}
+
+// Renters who meet the requirements of an instant book vehicle have their booking
+// confirmed straight away. Any other booking is a request the host has to answer
+// within config.BookingRequestTTL. The renter pays the total of the quote for the
+// trip, which takes off their promo code and credits and adds the taxes of where
+// the vehicle is
+func CreateBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateBookingRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if messages := checkBookingDates(&requestBody.QuoteBookingRequestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	isVerified, err := models.HasApprovedVerification(ctx, pool, cliams.ID, models.VerificationTypeDriverLicence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !isVerified {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Your driver's licence needs to be verified before you can request a booking"})
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	booking := &models.Booking{
+		EndAt:     requestBody.EndAt,
+		StartAt:   requestBody.StartAt,
+		Status:    models.BookingStatusPending,
+		UserID:    cliams.ID,
+		VehicleID: requestBody.VehicleID,
+	}
+	rentalFee := 0
+	instantBook := false
+	requirements := &models.InstantBookRequirements{}
+	// Locking the vehicle serialises concurrent requests for it so that the overlap check below holds
+	sql := `
+	SELECT user_id, cancellation_policy, daily_mileage_limit, fuel_fee, instant_book, instant_book_min_account_age_days, 
+		instant_book_min_rating, instant_book_min_trips, mileage_fee, rental_fee, security_deposit 
+	FROM vehicles 
+	WHERE id = $1 AND unlisted_at IS NULL 
+	FOR UPDATE`
+	destination := []interface{}{
+		&booking.HostID,
+		&booking.CancellationPolicy,
+		&booking.DailyMileageLimit,
+		&booking.FuelFee,
+		&instantBook,
+		&requirements.MinAccountAgeDays,
+		&requirements.MinRating,
+		&requirements.MinTrips,
+		&booking.MileageFee,
+		&rentalFee,
+		&booking.SecurityDeposit,
+	}
+	err = tx.QueryRow(ctx, sql, booking.VehicleID).Scan(destination...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if booking.HostID == cliams.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You can't book your own vehicle"})
+		return
+	}
+
+	overlaps, err := models.HasOverlappingBooking(ctx, tx, booking.VehicleID, booking.StartAt, booking.EndAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !overlaps {
+		overlaps, err = models.HasOverlappingBlackout(ctx, tx, booking.VehicleID, booking.StartAt, booking.EndAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if overlaps {
+		c.JSON(http.StatusConflict, gin.H{"message": "Vehicle is not available for the selected dates"})
+		return
+	}
+
+	if instantBook {
+		if instantBook, err = requirements.AreMetBy(ctx, tx, cliams.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if instantBook {
+		booking.Status = models.BookingStatusConfirmed
+	} else {
+		expiresAt := time.Now().Add(config.BookingRequestTTL)
+		booking.ExpiresAt = &expiresAt
+	}
+
+	quote, response := quoteBooking(ctx, tx, &requestBody.QuoteBookingRequestBody, cliams.ID, rentalFee, true)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	booking.CreditAmount = quote.CreditAmount
+	booking.DiscountAmount = quote.DiscountAmount
+	booking.TaxAmount = quote.TaxAmount
+	booking.TotalAmount = quote.TotalAmount
+	if err = models.InsertBooking(ctx, tx, booking); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = models.InsertBookingTaxLines(ctx, tx, booking.ID, nil, quote.TaxLines); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if quote.PromoCode != nil {
+		if err = models.InsertPromoRedemption(ctx, tx, booking, quote.PromoCode); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if err = models.SpendBookingCredits(ctx, tx, booking); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	payment, err := authorisePayment(ctx, tx, booking, requestBody.PaymentMethodID)
+	if err != nil {
+		response := paymentErrorResponse(err)
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		cancelPaymentIntent(payment.ProviderIntentID)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	publishBookingUpdated(booking)
+	if booking.Status == models.BookingStatusConfirmed {
+		notifyBookingUpdated(booking, booking.HostID, "New booking")
+		notifyBookingConfirmed(booking)
+	} else {
+		notifyBookingUpdated(booking, booking.HostID, "New booking request")
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"booking": booking, "payment": payment})
+}
+
+func DeclineBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCalendar, "Only the host can decline this booking"); response != nil {
+			return response
+		}
+
+		if booking.Status != models.BookingStatusPending {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "Only pending bookings can be declined"},
+			}
+		}
+
+		if response := models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusDeclined); response != nil {
+			return response
+		}
+
+		if _, err := settleCancelledPayment(ctx, tx, booking.ID, 0); err != nil {
+			return paymentErrorResponse(err)
+		}
+
+		if err := models.RestoreBookingCredits(ctx, tx, booking); err != nil {
+			return &models.SQLResponse{
+				StatusCode: http.StatusInternalServerError,
+				Body:       gin.H{"message": err.Error()},
+			}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	notifyBookingUpdated(booking, booking.UserID, "Your booking has been declined")
+	c.JSON(http.StatusOK, gin.H{"booking": booking})
+}
+
+// Shows what cancelling the booking now would refund, or what was refunded once
+// it has been cancelled
+func GetCancellation(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	booking, response := checkBookingVisible(ctx, pool, bookingId, cliams)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	switch booking.Status {
+	case models.BookingStatusPending, models.BookingStatusConfirmed:
+		actorId, err := selectCancellingUser(ctx, pool, booking, cliams.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		if actorId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Only the host or the renter can cancel this booking"})
+			return
+		}
+
+		cancellation := models.CalculateCancellation(booking, actorId, time.Now())
+		c.JSON(http.StatusOK, gin.H{"cancellation": cancellation})
+	case models.BookingStatusCancelled:
+		cancellation, err := models.SelectCancellation(ctx, pool, booking.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Cancellation not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"cancellation": cancellation})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Only pending or confirmed bookings can be cancelled"})
+	}
+}
+
+func GetBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	booking, response := checkBookingVisible(ctx, pool, bookingId, cliams)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"booking": booking})
+}
+
+func GetBookings(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &GetBookingsQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	arguments := []interface{}{cliams.ID}
+	conditions := []string{"b.user_id = $1"}
+	if requestQuery.Role == config.RoleHost {
+		conditions = []string{`(v.user_id = $1 OR EXISTS (
+			SELECT 1 FROM vehicle_cohosts AS vc WHERE vc.vehicle_id = v.id AND vc.user_id = $1 AND vc.accepted_at IS NOT NULL
+		))`}
+	}
+
+	if requestQuery.Status != "" {
+		arguments = append(arguments, requestQuery.Status)
+		conditions = append(conditions, fmt.Sprintf("b.status = $%v", len(arguments)))
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(b)), '[]') FROM (
+		SELECT b.id,
+			b.cancellation_policy,
+			b.completed_at,
+			b.created_at,
+			b.credit_amount,
+			b.daily_mileage_limit,
+			b.discount_amount,
+			b.end_at,
+			b.expires_at,
+			b.fuel_fee,
+			v.user_id AS host_id,
+			b.mileage_fee,
+			b.security_deposit,
+			b.start_at,
+			b.status,
+			b.tax_amount,
+			b.total_amount,
+			b.updated_at,
+			b.user_id,
+			jsonb_build_object('id', v.id, 'image', v.image, 'make', v.make, 'name', v.name) AS vehicle
+		FROM bookings AS b
+		JOIN vehicles AS v ON b.vehicle_id = v.id
+		%v
+		ORDER BY b.start_at DESC
+		LIMIT $%v OFFSET $%v
+	) AS b`, helpers.BuildWhereClause(conditions), len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bookings := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&bookings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookings": bookings, "page": requestQuery.Page})
+}
+
+// Like publishEvent this is best effort, the booking change has already been committed
+func notifyBookingUpdated(booking *models.Booking, recipientId string, title string, attachments ...services.MailAttachment) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user := &models.User{ID: recipientId}
+	options := models.SQLOptions{
+		Arguments:         []interface{}{user.ID},
+		AfterTableClauses: "WHERE id = $1",
+		Destination:       []interface{}{&user.Email, &user.Firstname, &user.Lastname},
+		ReturnColumns:     []string{"email", "firstname", "lastname"},
+	}
+	if response := models.SelectUserRow(ctx, options); response != nil {
+		log.Printf("notifyBookingUpdated %v: %v\n", recipientId, response.Body)
+		return
+	}
+
+	if err := user.SendBookingUpdatedMail(ctx, booking, title, attachments...); err != nil {
+		log.Printf("notifyBookingUpdated %v: %v\n", recipientId, err)
+	}
+}
+
+// Prices a trip the way booking it would, without holding the vehicle, the promo
+// code or the renter's credits
+func QuoteBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &QuoteBookingRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if messages := checkBookingDates(requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId, rentalFee := "", 0
+	pool := services.GetPostgresConnectionPool()
+	sql := "SELECT user_id, rental_fee FROM vehicles WHERE id = $1 AND unlisted_at IS NULL"
+	err := pool.QueryRow(ctx, sql, requestBody.VehicleID).Scan(&hostId, &rentalFee)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if hostId == cliams.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You can't book your own vehicle"})
+		return
+	}
+
+	quote, response := quoteBooking(ctx, pool, requestBody, cliams.ID, rentalFee, false)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+// The host starts the trip when handing over the vehicle, which is when the renter
+// is charged and the security deposit is held
+func StartBooking(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var deposit *models.SecurityDeposit
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCheckIn, "Only the host can start this trip"); response != nil {
+			return response
+		}
+
+		if booking.Status != models.BookingStatusConfirmed {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "Only confirmed bookings can be started"},
+			}
+		}
+
+		now := time.Now()
+		if now.Before(booking.StartAt.Add(-config.TripStartWindow)) || !now.Before(booking.EndAt) {
+			return &models.SQLResponse{
+				StatusCode: http.StatusBadRequest,
+				Body:       gin.H{"message": "This trip can't be started at this time"},
+			}
+		}
+
+		if response := models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusInProgress); response != nil {
+			return response
+		}
+
+		// The deposit is held before the rental is captured so that a declined hold
+		// leaves the renter's payment untouched
+		_, err := updatePayment(ctx, tx, booking.ID, func(provider services.PaymentProvider, payment *models.Payment) (*services.PaymentIntent, string, error) {
+			if booking.SecurityDeposit > 0 {
+				var err error
+				if deposit, err = authoriseSecurityDeposit(ctx, tx, booking, payment.PaymentMethodID); err != nil {
+					return nil, "", err
+				}
+			}
+
+			intent, err := provider.Capture(ctx, payment.ProviderIntentID, payment.Amount, services.PaymentIdempotencyKey(models.PaymentReasonCapture, payment.ProviderIntentID, 0))
+			if err != nil && deposit != nil {
+				cancelPaymentIntent(deposit.ProviderIntentID)
+			}
+
+			return intent, models.PaymentReasonCapture, err
+		})
+		if err != nil {
+			return paymentErrorResponse(err)
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	notifyBookingUpdated(booking, booking.UserID, "Your trip has started")
+	c.JSON(http.StatusOK, gin.H{"booking": booking, "security_deposit": deposit})
+}
+
+func checkBookingDates(requestBody *QuoteBookingRequestBody) gin.H {
+	if !requestBody.StartAt.After(time.Now()) {
+		return gin.H{"start_at": "Start_at should be in the future"}
+	}
+
+	if !requestBody.EndAt.After(requestBody.StartAt) {
+		return gin.H{"end_at": "End_at should be after start_at"}
+	}
+
+	return nil
+}
+
+// Co-hosts act on the host's side of a booking when the host gave them the permission
+func checkHostPermission(ctx context.Context, querier models.Querier, booking *models.Booking, userId string, permission string, message string) *models.SQLResponse {
+	isManager, err := booking.IsManagedBy(ctx, querier, userId, permission)
+	if err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !isManager {
+		return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": message}}
+	}
+
+	return nil
+}
+
+func publishBookingUpdated(booking *models.Booking) {
+	event := services.Event{Type: services.EventBookingUpdated, Data: booking}
+	publishVehicleEvent([]string{booking.UserID, booking.HostID}, booking.VehicleID, models.CohostPermissionCalendar, event)
+}
+
+// The promo code is applied before taxes and credits after them. With lock, the
+// promo code and the renter's credits stay locked until the transaction of querier
+// ends
+func quoteBooking(ctx context.Context, querier models.Querier, requestBody *QuoteBookingRequestBody, userId string, rentalFee int, lock bool) (*models.BookingQuote, *models.SQLResponse) {
+	quote := models.NewBookingQuote(requestBody.StartAt, requestBody.EndAt, rentalFee)
+	if requestBody.PromoCode != "" {
+		selectPromoCode := models.SelectPromoCode
+		if lock {
+			selectPromoCode = models.SelectPromoCodeForUpdate
+		}
+
+		promoCode, err := selectPromoCode(ctx, querier, requestBody.PromoCode)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"promo_code": "Promo code is invalid"}}
+		}
+
+		if err == nil {
+			err = promoCode.Check(ctx, querier, userId, quote.Days)
+		}
+
+		if message, ok := promoCodeMessages[err]; ok {
+			return nil, &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"promo_code": message}}
+		}
+
+		if err != nil {
+			return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		quote.ApplyPromoCode(promoCode)
+	}
+
+	rules, err := models.SelectVehicleTaxRules(ctx, querier, requestBody.VehicleID)
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	quote.ApplyTaxes(rules)
+	if requestBody.UseCredits {
+		selectCreditBalance := models.SelectCreditBalance
+		if lock {
+			selectCreditBalance = models.SelectCreditBalanceForUpdate
+		}
+
+		balance, err := selectCreditBalance(ctx, querier, userId)
+		if err != nil {
+			return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		quote.ApplyCredits(balance)
+	}
+
+	return quote, nil
+}
+
+// A co-host who manages the calendar cancels for the host, so the cancellation
+// counts against the host. It's empty for anyone who can't cancel the booking
+func selectCancellingUser(ctx context.Context, querier models.Querier, booking *models.Booking, userId string) (string, error) {
+	if booking.UserID == userId {
+		return userId, nil
+	}
+
+	isManager, err := booking.IsManagedBy(ctx, querier, userId, models.CohostPermissionCalendar)
+	if err != nil || !isManager {
+		return "", err
+	}
+
+	return booking.HostID, nil
+}
+
+// Loads and locks the booking before handing it to update which decides whether
+// the current user may change it. Everything update does is committed together
+func updateBooking(ctx context.Context, bookingId string, update func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse) (*models.Booking, *models.SQLResponse) {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := models.SelectBookingForUpdate(ctx, tx, bookingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if response := update(tx, booking); response != nil {
+		return nil, response
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return booking, nil
+}
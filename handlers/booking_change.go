@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+// The host approves a pending change, which moves the booking to the new dates and
+// charges or refunds the difference in price
+func ApproveBookingChange(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId, changeId := c.Param("id"), c.Param("changeId")
+	if response := checkBookingChangeIds(bookingId, changeId); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var charge *models.BookingCharge
+	booking, change, response := updateBookingChange(ctx, bookingId, changeId, cliams.ID, func(tx pgx.Tx, booking *models.Booking, change *models.BookingChange) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCalendar, "Only the host can approve this change"); response != nil {
+			return response
+		}
+
+		if change.Status != models.BookingChangeStatusPending {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only pending changes can be approved"}}
+		}
+
+		if response := checkBookingChange(booking, change.StartAt, change.EndAt); response != nil {
+			return response
+		}
+
+		vehicle, response := selectVehicleForBookingChange(ctx, tx, booking)
+		if response != nil {
+			return response
+		}
+
+		if response := checkVehicleAvailable(ctx, tx, booking, vehicle, change); response != nil {
+			return response
+		}
+
+		if charge, response = applyBookingChange(ctx, tx, booking, change); response != nil {
+			return response
+		}
+
+		if err := change.UpdateStatus(ctx, tx, models.BookingChangeStatusApproved); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	publishBookingUpdated(booking)
+	notifyBookingUpdated(booking, booking.UserID, "Your trip change has been approved")
+	c.JSON(http.StatusOK, gin.H{"booking": booking, "change": change, "charge": charge})
+}
+
+// The renter withdraws a change the host hasn't answered yet
+func CancelBookingChange(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId, changeId := c.Param("id"), c.Param("changeId")
+	if response := checkBookingChangeIds(bookingId, changeId); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, change, response := updateBookingChange(ctx, bookingId, changeId, cliams.ID, func(tx pgx.Tx, booking *models.Booking, change *models.BookingChange) *models.SQLResponse {
+		if booking.UserID != cliams.ID {
+			return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": "Only the renter can cancel this change"}}
+		}
+
+		if change.Status != models.BookingChangeStatusPending {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only pending changes can be cancelled"}}
+		}
+
+		if err := change.UpdateStatus(ctx, tx, models.BookingChangeStatusCancelled); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"change": change})
+}
+
+// The renter asks to move or extend their trip. The vehicle has to be free for the
+// new dates, which are priced at its current rental fee. Renters who meet the
+// requirements of an instant book vehicle have the change approved straight away
+func CreateBookingChange(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateBookingChangeRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if !requestBody.EndAt.After(requestBody.StartAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"end_at": "End_at should be after start_at"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var charge *models.BookingCharge
+	change := &models.BookingChange{
+		BookingID: bookingId,
+		EndAt:     requestBody.EndAt,
+		StartAt:   requestBody.StartAt,
+		Status:    models.BookingChangeStatusPending,
+		UserID:    &cliams.ID,
+	}
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if !booking.IsParticipant(cliams.ID) {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+		}
+
+		if booking.UserID != cliams.ID {
+			return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": "Only the renter can change this trip"}}
+		}
+
+		if response := checkBookingChange(booking, change.StartAt, change.EndAt); response != nil {
+			return response
+		}
+
+		if change.StartAt.Equal(booking.StartAt) && change.EndAt.Equal(booking.EndAt) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "The new dates are the same as the booking's"}}
+		}
+
+		vehicle, response := selectVehicleForBookingChange(ctx, tx, booking)
+		if response != nil {
+			return response
+		}
+
+		if response := checkVehicleAvailable(ctx, tx, booking, vehicle, change); response != nil {
+			return response
+		}
+
+		// Like a deleted rule, a rule added since doesn't change the taxes of a booking
+		rules, err := models.SelectBookingTaxRules(ctx, tx, booking)
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		change.Price(booking, vehicle.RentalFee, rules)
+		err = models.InsertBookingChange(ctx, tx, change)
+		pgErr := new(pgconn.PgError)
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return &models.SQLResponse{StatusCode: http.StatusConflict, Body: gin.H{"message": "This booking already has a pending change"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if !vehicle.InstantBook {
+			return nil
+		}
+
+		eligible, err := vehicle.InstantBookRequirements.AreMetBy(ctx, tx, cliams.ID)
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if !eligible {
+			return nil
+		}
+
+		if charge, response = applyBookingChange(ctx, tx, booking, change); response != nil {
+			return response
+		}
+
+		if err = change.UpdateStatus(ctx, tx, models.BookingChangeStatusApproved); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if change.Status == models.BookingChangeStatusApproved {
+		publishBookingUpdated(booking)
+		notifyBookingUpdated(booking, booking.HostID, "A trip has been changed")
+	} else {
+		notifyBookingUpdated(booking, booking.HostID, "New trip change request")
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"booking": booking, "change": change, "charge": charge})
+}
+
+func DeclineBookingChange(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId, changeId := c.Param("id"), c.Param("changeId")
+	if response := checkBookingChangeIds(bookingId, changeId); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	booking, change, response := updateBookingChange(ctx, bookingId, changeId, cliams.ID, func(tx pgx.Tx, booking *models.Booking, change *models.BookingChange) *models.SQLResponse {
+		if response := checkHostPermission(ctx, tx, booking, cliams.ID, models.CohostPermissionCalendar, "Only the host can decline this change"); response != nil {
+			return response
+		}
+
+		if change.Status != models.BookingChangeStatusPending {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only pending changes can be declined"}}
+		}
+
+		if err := change.UpdateStatus(ctx, tx, models.BookingChangeStatusDeclined); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	notifyBookingUpdated(booking, booking.UserID, "Your trip change has been declined")
+	c.JSON(http.StatusOK, gin.H{"change": change})
+}
+
+func GetBookingChanges(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if _, response := checkBookingVisible(ctx, pool, bookingId, cliams); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(bc) ORDER BY bc.created_at DESC), '[]')
+	FROM booking_changes AS bc
+	WHERE bc.booking_id = $1`
+	changes := []gin.H{}
+	if err := pool.QueryRow(ctx, sql, bookingId).Scan(&changes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// Moves the booking to the new dates and settles the difference in price. More is
+// charged separately on the renter's payment method, with the taxes it adds. Less
+// is refunded from the rental payment, or left uncaptured when the trip hasn't
+// started yet. The invoice is revised for the new dates and price
+func applyBookingChange(ctx context.Context, tx pgx.Tx, booking *models.Booking, change *models.BookingChange) (*models.BookingCharge, *models.SQLResponse) {
+	var charge *models.BookingCharge
+	difference := change.PriceDifference(booking)
+	if difference > 0 {
+		var err error
+		description := fmt.Sprintf("Trip change for booking %v", booking.ID)
+		taxAmount := change.TaxAmount - booking.TaxAmount
+		if taxAmount < 0 {
+			taxAmount = 0
+		}
+
+		if taxAmount > difference {
+			taxAmount = difference
+		}
+
+		charge, err = chargeBooking(ctx, tx, booking, models.BookingChargeTypeModification, change.ID, difference, taxAmount, description)
+		if err != nil {
+			return nil, paymentErrorResponse(err)
+		}
+
+		if charge != nil && charge.FailureReason != "" {
+			return nil, paymentErrorResponse(services.ErrPaymentDeclined)
+		}
+	}
+
+	if difference < 0 {
+		if _, err := refundBooking(ctx, tx, booking.ID, -difference); err != nil {
+			return nil, paymentErrorResponse(err)
+		}
+	}
+
+	if err := models.UpdateBookingDates(ctx, tx, booking, change); err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return charge, reviseBookingInvoice(ctx, tx, booking)
+}
+
+// Trips can be changed until they end, but once started only the end can move
+func checkBookingChange(booking *models.Booking, startAt time.Time, endAt time.Time) *models.SQLResponse {
+	now := time.Now()
+	switch booking.Status {
+	case models.BookingStatusConfirmed:
+		if !startAt.After(now) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"start_at": "Start_at should be in the future"}}
+		}
+	case models.BookingStatusInProgress:
+		if !startAt.Equal(booking.StartAt) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"start_at": "Start_at can't be changed once the trip has started"}}
+		}
+
+		if !endAt.After(now) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"end_at": "End_at should be in the future"}}
+		}
+	default:
+		return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only confirmed trips or trips in progress can be changed"}}
+	}
+
+	return nil
+}
+
+func checkBookingChangeIds(bookingId string, changeId string) *models.SQLResponse {
+	if _, err := uuid.Parse(bookingId); err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Booking with the given id is invalid"}}
+	}
+
+	if _, err := uuid.Parse(changeId); err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Change with the given id is invalid"}}
+	}
+
+	return nil
+}
+
+func checkVehicleAvailable(ctx context.Context, tx pgx.Tx, booking *models.Booking, vehicle *models.Vehicle, change *models.BookingChange) *models.SQLResponse {
+	overlaps, err := models.HasOverlappingBooking(ctx, tx, vehicle.ID, change.StartAt, change.EndAt, booking.ID)
+	if err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !overlaps {
+		overlaps, err = models.HasOverlappingBlackout(ctx, tx, vehicle.ID, change.StartAt, change.EndAt)
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+	}
+
+	if overlaps {
+		return &models.SQLResponse{StatusCode: http.StatusConflict, Body: gin.H{"message": "Vehicle is not available for the selected dates"}}
+	}
+
+	return nil
+}
+
+// Locking the vehicle serialises the availability check with new bookings of it.
+// Trips of an unlisted vehicle can't be changed, like it can't be booked
+func selectVehicleForBookingChange(ctx context.Context, tx pgx.Tx, booking *models.Booking) (*models.Vehicle, *models.SQLResponse) {
+	sql := `
+	SELECT id, instant_book, instant_book_min_account_age_days, instant_book_min_rating, instant_book_min_trips, rental_fee
+	FROM vehicles
+	WHERE id = $1 AND unlisted_at IS NULL
+	FOR UPDATE`
+	vehicle := &models.Vehicle{}
+	destination := []interface{}{
+		&vehicle.ID,
+		&vehicle.InstantBook,
+		&vehicle.InstantBookRequirements.MinAccountAgeDays,
+		&vehicle.InstantBookRequirements.MinRating,
+		&vehicle.InstantBookRequirements.MinTrips,
+		&vehicle.RentalFee,
+	}
+	err := tx.QueryRow(ctx, sql, booking.VehicleID).Scan(destination...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Vehicle not found"}}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return vehicle, nil
+}
+
+// Loads and locks the booking and the change before handing them to update. Only
+// participants of the booking and co-hosts of its vehicle see its changes.
+// Everything update does is committed together
+func updateBookingChange(ctx context.Context, bookingId string, changeId string, userId string, update func(tx pgx.Tx, booking *models.Booking, change *models.BookingChange) *models.SQLResponse) (*models.Booking, *models.BookingChange, *models.SQLResponse) {
+	var change *models.BookingChange
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		isManager, err := booking.IsManagedBy(ctx, tx, userId, "")
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if booking.UserID != userId && !isManager {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+		}
+
+		change, err = models.SelectBookingChangeForUpdate(ctx, tx, booking.ID, changeId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Change not found"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return update(tx, booking, change)
+	})
+	return booking, change, response
+}
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+// Takes the vehicle off the market for the period. Periods the vehicle is already
+// booked for can't be blacked out
+func CreateBlackout(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateBlackoutRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if !requestBody.EndAt.After(requestBody.StartAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"end_at": "End_at should be after start_at"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the vehicle serialises the overlap check with new bookings of it, which
+	// lock it too
+	if _, err = tx.Exec(ctx, "SELECT id FROM vehicles WHERE id = $1 FOR UPDATE", vehicleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	overlaps, err := models.HasOverlappingBooking(ctx, tx, vehicleId, requestBody.StartAt, requestBody.EndAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if overlaps {
+		c.JSON(http.StatusConflict, gin.H{"message": "Vehicle is booked for the selected dates"})
+		return
+	}
+
+	blackout := &models.Blackout{
+		EndAt:     requestBody.EndAt,
+		StartAt:   requestBody.StartAt,
+		Summary:   requestBody.Summary,
+		VehicleID: vehicleId,
+	}
+	if err = models.InsertBlackout(ctx, tx, blackout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"blackout": blackout})
+}
+
+// The calendar is read straight away so that a link that doesn't work is never
+// saved, and its busy periods block the vehicle from then on
+func CreateVehicleCalendar(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateVehicleCalendarRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	calendarURL, err := url.Parse(requestBody.URL)
+	if err == nil && strings.EqualFold(calendarURL.Scheme, "webcal") {
+		calendarURL.Scheme = "https"
+	}
+
+	if err != nil || (calendarURL.Scheme != "http" && calendarURL.Scheme != "https") {
+		c.JSON(http.StatusBadRequest, gin.H{"url": "Url should be an http, https or webcal link"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.CalendarFetchTimeout+5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	// The reason is left out so that the response can't be used to probe what the
+	// server can reach
+	events, err := services.FetchCalendarEvents(ctx, calendarURL.String())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"url": "Calendar could not be read"})
+		return
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	calendar := &models.VehicleCalendar{Name: requestBody.Name, URL: calendarURL.String(), VehicleID: vehicleId}
+	err = models.InsertVehicleCalendar(ctx, tx, calendar)
+	pgErr := new(pgconn.PgError)
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		c.JSON(http.StatusConflict, gin.H{"message": "This calendar has already been added to the vehicle"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = calendar.Sync(ctx, tx, events); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"calendar": calendar})
+}
+
+// Only blackouts the host added can be deleted. Imported ones go away with their
+// calendar
+func DeleteBlackout(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId, blackoutId := c.Param("id"), c.Param("blackoutId")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	if _, err := uuid.Parse(blackoutId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Blackout with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	sql := "DELETE FROM vehicle_blackouts WHERE id = $1 AND vehicle_id = $2 AND calendar_id IS NULL"
+	tag, err := pool.Exec(ctx, sql, blackoutId, vehicleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Blackout not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// The blackouts that were imported from the calendar are deleted with it
+func DeleteVehicleCalendar(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId, calendarId := c.Param("id"), c.Param("calendarId")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	if _, err := uuid.Parse(calendarId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Calendar with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	tag, err := pool.Exec(ctx, "DELETE FROM vehicle_calendars WHERE id = $1 AND vehicle_id = $2", calendarId, vehicleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Calendar not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// Returns the blackouts that haven't ended, both the ones the host added and the
+// ones imported from calendars
+func GetBlackouts(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(vb) ORDER BY vb.start_at), '[]') FROM (
+		SELECT id, calendar_id, created_at, end_at, start_at, summary, vehicle_id
+		FROM vehicle_blackouts
+		WHERE vehicle_id = $1 AND end_at > NOW()
+	) AS vb`
+	blackouts := []gin.H{}
+	if err := pool.QueryRow(ctx, sql, vehicleId).Scan(&blackouts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blackouts": blackouts})
+}
+
+// Serves the vehicle's bookings and blackouts as an iCalendar feed for other
+// platforms. The token in the link is the only thing that protects it
+func GetVehicleCalendarFeed(c *gin.Context) {
+	vehicleId, token := c.Param("id"), c.Query("token")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var calendarToken *string
+	name := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT calendar_token, name FROM vehicles WHERE id = $1", vehicleId).Scan(&calendarToken, &name)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (calendarToken == nil || subtle.ConstantTimeCompare([]byte(*calendarToken), []byte(token)) != 1)) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Calendar not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	events, err := models.SelectVehicleCalendarEvents(ctx, pool, vehicleId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	buffer := &bytes.Buffer{}
+	if err = services.WriteCalendar(buffer, name, events); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buffer.Bytes())
+}
+
+// Returns the calendars the vehicle imports from along with the link of its own
+// feed, which is created the first time it's asked for
+func GetVehicleCalendars(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	token, err := helpers.GenerateRandomToken(config.CalendarTokenLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	sql := `
+	WITH cte_vehicle AS (
+		UPDATE vehicles SET calendar_token = COALESCE(calendar_token, $2) WHERE id = $1 RETURNING calendar_token
+	)
+	SELECT (SELECT calendar_token FROM cte_vehicle), (
+		SELECT COALESCE(json_agg(to_jsonb(vc) ORDER BY vc.created_at), '[]') FROM (
+			SELECT id, created_at, name, sync_error, synced_at, url, vehicle_id
+			FROM vehicle_calendars
+			WHERE vehicle_id = $1
+		) AS vc
+	)`
+	calendars := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, vehicleId, token).Scan(&token, &calendars); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendars": calendars, "feed_url": vehicleCalendarFeedURL(vehicleId, token)})
+}
+
+// Links that were shared with other platforms stop working
+func ResetVehicleCalendarToken(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if response := checkCalendarManager(ctx, pool, vehicleId, cliams.ID); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	token, err := helpers.GenerateRandomToken(config.CalendarTokenLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if _, err = pool.Exec(ctx, "UPDATE vehicles SET calendar_token = $1 WHERE id = $2", token, vehicleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feed_url": vehicleCalendarFeedURL(vehicleId, token)})
+}
+
+// The owner and co-hosts who manage the calendar can change the vehicle's
+// availability. Everyone else is told the vehicle doesn't exist
+func checkCalendarManager(ctx context.Context, querier models.Querier, vehicleId string, userId string) *models.SQLResponse {
+	isManager, err := models.IsVehicleManager(ctx, querier, vehicleId, userId, models.CohostPermissionCalendar)
+	if err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !isManager {
+		return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Vehicle not found"}}
+	}
+
+	return nil
+}
+
+func vehicleCalendarFeedURL(vehicleId string, token string) string {
+	return fmt.Sprintf("%v/vehicles/%v/calendar.ics?token=%v", config.APIOrigin, vehicleId, token)
+}
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+// The renter agrees to pay the full amount claimed, which is captured from the
+// security deposit straight away
+func AcceptClaim(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	claimId := c.Param("id")
+	if _, err := uuid.Parse(claimId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Claim with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claim, booking, response := updateClaim(ctx, claimId, func(tx pgx.Tx, claim *models.Claim, booking *models.Booking) *models.SQLResponse {
+		if booking.UserID != cliams.ID {
+			return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": "Only the renter can accept this claim"}}
+		}
+
+		if claim.Status != models.ClaimStatusPending {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only pending claims can be accepted"}}
+		}
+
+		return settleClaim(ctx, tx, claim, models.ClaimStatusAccepted, claim.Amount, nil, "")
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	notifyBookingUpdated(booking, booking.HostID, "Your damage claim was accepted")
+	c.JSON(http.StatusOK, gin.H{"claim": claim})
+}
+
+// Hosts have config.ClaimWindow after the trip is completed to claim against the
+// security deposit that was held for it
+func CreateClaim(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateClaimRequestBody{}
+	if messages := helpers.ValidateRequestForm(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if len(requestBody.Photos) > config.MaxClaimPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"photos": fmt.Sprintf("Photos should not be greater than %v items", config.MaxClaimPhotos)})
+		return
+	}
+
+	for _, photo := range requestBody.Photos {
+		if photo.Size > config.MaxDocumentSizeInBytes {
+			message := fmt.Sprintf("Each photo should not be larger than %vMB", config.MaxDocumentSizeInBytes>>20)
+			c.JSON(http.StatusBadRequest, gin.H{"photos": message})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claim := &models.Claim{
+		ID:          uuid.NewString(),
+		Amount:      requestBody.Amount,
+		BookingID:   bookingId,
+		Description: requestBody.Description,
+		UserID:      cliams.ID,
+	}
+	storage := services.GetFileStorage()
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		if booking.HostID != cliams.ID {
+			return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": "Only the host can file a claim for this trip"}}
+		}
+
+		if booking.Status != models.BookingStatusCompleted || time.Now().After(booking.CompletedAt.Add(config.ClaimWindow)) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Claims can only be filed shortly after a trip is completed"}}
+		}
+
+		deposit, err := models.SelectSecurityDepositByBookingForUpdate(ctx, tx, booking.ID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && deposit.Status != services.PaymentStatusAuthorised) {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "There is no security deposit held for this trip"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if claim.Amount > deposit.Amount {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"amount": "Amount should not be more than the security deposit"}}
+		}
+
+		for index, photo := range requestBody.Photos {
+			key := fmt.Sprintf("claims/%v/%v/%v", booking.ID, claim.ID, index)
+			key, err = storeUploadedFile(ctx, storage, key, photo, photoExtensions)
+			if errors.Is(err, errUnsupportedDocument) {
+				return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"photos": "Photos should be JPEG or PNG images"}}
+			}
+
+			if err != nil {
+				return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+			}
+
+			claim.Photos = append(claim.Photos, key)
+		}
+
+		err = models.InsertClaim(ctx, tx, claim)
+		pgErr := new(pgconn.PgError)
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return &models.SQLResponse{StatusCode: http.StatusConflict, Body: gin.H{"message": "A claim has already been filed for this trip"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		deleteFiles(ctx, storage, claim.Photos)
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	notifyBookingUpdated(booking, booking.UserID, "A damage claim was filed for your trip")
+	c.JSON(http.StatusCreated, gin.H{"claim": claim})
+}
+
+// Disputed claims are left for an admin to resolve
+func DisputeClaim(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	claimId := c.Param("id")
+	if _, err := uuid.Parse(claimId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Claim with the given id is invalid"})
+		return
+	}
+
+	requestBody := &ReasonField{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claim, booking, response := updateClaim(ctx, claimId, func(tx pgx.Tx, claim *models.Claim, booking *models.Booking) *models.SQLResponse {
+		if booking.UserID != cliams.ID {
+			return &models.SQLResponse{StatusCode: http.StatusForbidden, Body: gin.H{"message": "Only the renter can dispute this claim"}}
+		}
+
+		if claim.Status != models.ClaimStatusPending {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only pending claims can be disputed"}}
+		}
+
+		if err := claim.Dispute(ctx, tx, requestBody.Reason); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	notifyBookingUpdated(booking, booking.HostID, "Your damage claim was disputed")
+	c.JSON(http.StatusOK, gin.H{"claim": claim})
+}
+
+func GetClaim(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	claimId := c.Param("id")
+	if _, err := uuid.Parse(claimId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Claim with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	claim, response := selectVisibleClaim(ctx, claimId, cliams)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"claim": claim})
+}
+
+func GetClaimPhoto(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	claimId := c.Param("id")
+	if _, err := uuid.Parse(claimId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Claim with the given id is invalid"})
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Photo index is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claim, response := selectVisibleClaim(ctx, claimId, cliams)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if index >= len(claim.Photos) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Photo not found"})
+		return
+	}
+
+	file, err := services.GetFileStorage().Open(ctx, claim.Photos[index])
+	if errors.Is(err, services.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Photo not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(claim.Photos[index]))
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
+
+// Claims are visible to both sides of the trip and to staff
+func selectVisibleClaim(ctx context.Context, claimId string, cliams *services.AccessTokenClaims) (*models.Claim, *models.SQLResponse) {
+	pool := services.GetPostgresConnectionPool()
+	claim, err := models.SelectClaim(ctx, pool, claimId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Claim not found"}}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	booking, err := models.SelectBooking(ctx, pool, claim.BookingID)
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	isStaff := cliams.Role == config.RoleAdmin || cliams.Role == config.RoleSupport
+	if !booking.IsParticipant(cliams.ID) && !isStaff {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Claim not found"}}
+	}
+
+	return claim, nil
+}
+
+// Captures settledAmount from the security deposit, releasing the rest, and
+// records the outcome on the claim
+func settleClaim(ctx context.Context, tx pgx.Tx, claim *models.Claim, status string, settledAmount int, resolvedBy *string, reason string) *models.SQLResponse {
+	if _, err := settleSecurityDeposit(ctx, tx, claim.BookingID, settledAmount); err != nil {
+		return paymentErrorResponse(err)
+	}
+
+	if err := claim.Settle(ctx, tx, status, settledAmount, resolvedBy, reason); err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return nil
+}
+
+// Loads and locks the claim along with its booking before handing them to update.
+// Everything update does is committed together
+func updateClaim(ctx context.Context, claimId string, update func(tx pgx.Tx, claim *models.Claim, booking *models.Booking) *models.SQLResponse) (*models.Claim, *models.Booking, *models.SQLResponse) {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+	defer tx.Rollback(ctx)
+
+	claim, err := models.SelectClaimForUpdate(ctx, tx, claimId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Claim not found"}}
+	}
+
+	if err != nil {
+		return nil, nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	booking, err := models.SelectBooking(ctx, tx, claim.BookingID)
+	if err != nil {
+		return nil, nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if response := update(tx, claim, booking); response != nil {
+		return nil, nil, response
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return claim, booking, nil
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// The invited user starts acting for the owner with the permissions they were given
+func AcceptCohostInvitation(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	cohostId := c.Param("id")
+	if _, err := uuid.Parse(cohostId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invitation with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	cohost, err := models.SelectCohost(ctx, pool, cohostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && cohost.UserID != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Invitation not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if cohost.IsAccepted() {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "This invitation has already been accepted"})
+		return
+	}
+
+	if err = cohost.Accept(ctx, pool); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cohost": cohost})
+}
+
+// The owner invites a user by email to co-host the vehicle. Inviting someone who
+// was already invited replaces their permissions
+func CreateCohost(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateCohostRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId, vehicleName := "", ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id, name FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId, &vehicleName)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := &models.User{}
+	options := models.SQLOptions{
+		Arguments:         []interface{}{requestBody.Email},
+		AfterTableClauses: "WHERE email = $1",
+		Destination:       []interface{}{&user.ID, &user.Email, &user.Firstname, &user.Lastname},
+		ReturnColumns:     []string{"id", "email", "firstname", "lastname"},
+	}
+	if response := models.SelectUserRow(ctx, options); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if user.ID == cliams.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "You can't co-host your own vehicle"})
+		return
+	}
+
+	cohost := &models.Cohost{
+		InvitedBy:   cliams.ID,
+		Permissions: requestBody.Permissions,
+		UserID:      user.ID,
+		VehicleID:   vehicleId,
+	}
+	if err = models.UpsertCohost(ctx, pool, cohost); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !cohost.IsAccepted() {
+		if err = user.SendCohostInvitationMail(ctx, cohost, vehicleName); err != nil {
+			log.Printf("CreateCohost %v: %v\n", cohost.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"cohost": cohost})
+}
+
+// The owner revokes a co-host or their invitation, or the co-host leaves
+func DeleteCohost(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId, cohostId := c.Param("id"), c.Param("cohostId")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	if _, err := uuid.Parse(cohostId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Co-host with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	DELETE FROM vehicle_cohosts AS vc
+	USING vehicles AS v
+	WHERE vc.id = $1 AND vc.vehicle_id = $2 AND v.id = vc.vehicle_id AND (v.user_id = $3 OR vc.user_id = $3)`
+	pool := services.GetPostgresConnectionPool()
+	tag, err := pool.Exec(ctx, sql, cohostId, vehicleId, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Co-host not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// Returns the vehicles the user was invited to co-host, including the ones they
+// already accepted
+func GetCohostInvitations(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(vc) ORDER BY vc.created_at DESC), '[]') FROM (
+		SELECT vc.id,
+			vc.accepted_at,
+			vc.created_at,
+			jsonb_build_object('id', u.id, 'firstname', u.firstname, 'image', u.image) AS invited_by,
+			vc.permissions,
+			jsonb_build_object('id', v.id, 'image', v.image, 'make', v.make, 'name', v.name) AS vehicle
+		FROM vehicle_cohosts AS vc
+		JOIN users AS u ON vc.invited_by = u.id
+		JOIN vehicles AS v ON vc.vehicle_id = v.id
+		WHERE vc.user_id = $1
+	) AS vc`
+	invitations := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, cliams.ID).Scan(&invitations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// Only the owner sees who co-hosts the vehicle, whether or not they have accepted
+func GetCohosts(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(vc) ORDER BY vc.created_at), '[]') FROM (
+		SELECT vc.id,
+			vc.accepted_at,
+			vc.created_at,
+			vc.permissions,
+			jsonb_build_object('id', u.id, 'email', u.email, 'firstname', u.firstname, 'image', u.image, 'lastname', u.lastname) AS user
+		FROM vehicle_cohosts AS vc
+		JOIN users AS u ON vc.user_id = u.id
+		WHERE vc.vehicle_id = $1
+	) AS vc`
+	cohosts := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, vehicleId).Scan(&cohosts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cohosts": cohosts})
+}
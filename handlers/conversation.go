@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+func CreateConversation(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateConversationRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestBody.BookingID == "" && requestBody.VehicleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Either booking_id or vehicle_id is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	conversation := &models.Conversation{}
+	if requestBody.BookingID != "" {
+		booking, err := models.SelectBooking(ctx, pool, requestBody.BookingID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Booking not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		isManager, err := booking.IsManagedBy(ctx, pool, cliams.ID, models.CohostPermissionMessages)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		if booking.UserID != cliams.ID && !isManager {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Booking not found"})
+			return
+		}
+
+		conversation.BookingID = &booking.ID
+		conversation.HostID = booking.HostID
+		conversation.RenterID = booking.UserID
+		conversation.VehicleID = booking.VehicleID
+	} else {
+		sql := "SELECT user_id FROM vehicles WHERE id = $1 AND unlisted_at IS NULL"
+		err := pool.QueryRow(ctx, sql, requestBody.VehicleID).Scan(&conversation.HostID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		if conversation.HostID == cliams.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "You can't start a conversation about your own vehicle"})
+			return
+		}
+
+		conversation.RenterID = cliams.ID
+		conversation.VehicleID = requestBody.VehicleID
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err = models.FindOrInsertConversation(ctx, tx, conversation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	message := &models.Message{Body: requestBody.Body, ConversationID: conversation.ID, SenderID: cliams.ID}
+	if err = models.InsertMessage(ctx, tx, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	conversation.LastMessageAt = message.CreatedAt
+	event := services.Event{Type: services.EventMessageCreated, Data: message}
+	publishVehicleEvent([]string{conversation.HostID, conversation.RenterID}, conversation.VehicleID, models.CohostPermissionMessages, event)
+	c.JSON(http.StatusCreated, gin.H{"conversation": conversation, "message": message})
+}
+
+// Co-hosts who handle messages see the conversations of the vehicles they co-host
+// with the renter as the other participant
+func GetConversations(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(c)), '[]') FROM (
+		SELECT c.id,
+			c.booking_id,
+			c.created_at,
+			c.host_id,
+			(
+				SELECT to_jsonb(m) FROM (
+					SELECT id, body, created_at, read_at, sender_id
+					FROM messages
+					WHERE conversation_id = c.id
+					ORDER BY created_at DESC, id DESC
+					LIMIT 1
+				) AS m
+			) AS last_message,
+			c.last_message_at,
+			jsonb_build_object('id', u.id, 'firstname', u.firstname, 'image', u.image) AS participant,
+			c.renter_id,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE conversation_id = c.id AND read_at IS NULL AND CASE WHEN c.renter_id = $1 THEN sender_id <> $1 ELSE sender_id = c.renter_id END
+			) AS unread_count,
+			c.vehicle_id
+		FROM conversations AS c
+		JOIN users AS u ON u.id = CASE WHEN c.renter_id = $1 THEN c.host_id ELSE c.renter_id END
+		WHERE c.host_id = $1 OR c.renter_id = $1 OR EXISTS (
+			SELECT 1 FROM vehicle_cohosts AS vc
+			WHERE vc.vehicle_id = c.vehicle_id AND vc.user_id = $1 AND vc.accepted_at IS NOT NULL AND $4 = ANY(vc.permissions)
+		)
+		ORDER BY c.last_message_at DESC
+		LIMIT $2 OFFSET $3
+	) AS c`
+	conversations := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, cliams.ID, limit, offset, models.CohostPermissionMessages).Scan(&conversations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations, "page": requestQuery.Page})
+}
+
+// Messages are returned newest first. Passing the returned next_cursor as before
+// fetches the page of older messages
+func GetMessages(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &GetMessagesQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conversation, response := selectConversationForParticipant(ctx, c.Param("id"), cliams.ID)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if requestQuery.Limit == 0 {
+		requestQuery.Limit = 50
+	}
+
+	arguments := []interface{}{conversation.ID}
+	cursorCondition := ""
+	if requestQuery.Before != "" {
+		arguments = append(arguments, requestQuery.Before)
+		cursorCondition = "AND (created_at, id) < (SELECT created_at, id FROM messages WHERE id = $2 AND conversation_id = $1)"
+	}
+
+	// One extra row tells us whether there is an older page without a separate count
+	arguments = append(arguments, requestQuery.Limit+1)
+	sql := fmt.Sprintf(`
+	SELECT id, body, conversation_id, created_at, read_at, sender_id
+	FROM messages
+	WHERE conversation_id = $1 %v
+	ORDER BY created_at DESC, id DESC
+	LIMIT $%v`, cursorCondition, len(arguments))
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, arguments...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message := models.Message{}
+		err = rows.Scan(&message.ID, &message.Body, &message.ConversationID, &message.CreatedAt, &message.ReadAt, &message.SenderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	var nextCursor interface{}
+	if len(messages) > requestQuery.Limit {
+		messages = messages[:requestQuery.Limit]
+		nextCursor = messages[len(messages)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "next_cursor": nextCursor})
+}
+
+func MarkConversationRead(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conversation, response := selectConversationForParticipant(ctx, c.Param("id"), cliams.ID)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	// The renter reads what the host's side sent and the host's side reads what the renter sent
+	sql := "UPDATE messages SET read_at = NOW() WHERE conversation_id = $1 AND (sender_id = $2) = $3 AND read_at IS NULL"
+	pool := services.GetPostgresConnectionPool()
+	if _, err := pool.Exec(ctx, sql, conversation.ID, conversation.RenterID, conversation.RenterID != cliams.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	event := services.Event{
+		Type: services.EventConversationRead,
+		Data: gin.H{"conversation_id": conversation.ID, "reader_id": cliams.ID, "read_at": time.Now()},
+	}
+	publishVehicleEvent([]string{conversation.HostID, conversation.RenterID}, conversation.VehicleID, models.CohostPermissionMessages, event)
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func SendMessage(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &BodyField{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conversation, response := selectConversationForParticipant(ctx, c.Param("id"), cliams.ID)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	message := &models.Message{Body: requestBody.Body, ConversationID: conversation.ID, SenderID: cliams.ID}
+	pool := services.GetPostgresConnectionPool()
+	if err := models.InsertMessage(ctx, pool, message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	event := services.Event{Type: services.EventMessageCreated, Data: message}
+	publishVehicleEvent([]string{conversation.HostID, conversation.RenterID}, conversation.VehicleID, models.CohostPermissionMessages, event)
+	c.JSON(http.StatusCreated, gin.H{"message": message})
+}
+
+// Conversations are reported as not found to anyone but their two participants
+// and co-hosts of the vehicle who handle messages
+func selectConversationForParticipant(ctx context.Context, conversationId string, userId string) (*models.Conversation, *models.SQLResponse) {
+	if _, err := uuid.Parse(conversationId); err != nil {
+		return nil, &models.SQLResponse{
+			StatusCode: http.StatusBadRequest,
+			Body:       gin.H{"message": "Conversation with the given id is invalid"},
+		}
+	}
+
+	pool := services.GetPostgresConnectionPool()
+	conversation, err := models.SelectConversation(ctx, pool, conversationId)
+	isParticipant := err == nil && conversation.IsParticipant(userId)
+	if err == nil && !isParticipant {
+		isParticipant, err = models.IsVehicleManager(ctx, pool, conversation.VehicleID, userId, models.CohostPermissionMessages)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !isParticipant) {
+		return nil, &models.SQLResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       gin.H{"message": "Conversation not found"},
+		}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       gin.H{"message": err.Error()},
+		}
+	}
+
+	return conversation, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Streams the user's events as server-sent events until the client disconnects
+// or the access token expires, at which point the client should reconnect
+func StreamEvents(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	ctx := c.Request.Context()
+	subscription := services.SubscribeToEvents(ctx, cliams.ID)
+	defer subscription.Close()
+
+	// Waiting for the confirmation means no event published after this point is missed
+	if _, err := subscription.Receive(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	expiresIn := config.AccessTokenTTLInSeconds * time.Second
+	if cliams.ExpiresAt != nil && time.Until(cliams.ExpiresAt.Time) < expiresIn {
+		expiresIn = time.Until(cliams.ExpiresAt.Time)
+	}
+
+	expiry := time.NewTimer(expiresIn)
+	defer expiry.Stop()
+
+	heartbeat := time.NewTicker(config.EventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	channel := subscription.Channel()
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Flushing straight away sends the headers, so clients know the stream is open
+	// before the first event or heartbeat
+	c.Status(http.StatusOK)
+	if _, err := io.WriteString(c.Writer, ": connected\n\n"); err != nil {
+		return
+	}
+
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-expiry.C:
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case message, ok := <-channel:
+			if !ok {
+				return false
+			}
+
+			event := services.Event{}
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("StreamEvents %v: %v\n", cliams.ID, err)
+				return true
+			}
+
+			c.SSEvent(event.Type, event.Data)
+			return true
+		}
+	})
+}
+
+// Publishes the event to userIds and to the co-hosts of the vehicle who have the
+// permission
+func publishVehicleEvent(userIds []string, vehicleId string, permission string, event services.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cohostIds, err := models.SelectCohostUserIDs(ctx, services.GetPostgresConnectionPool(), vehicleId, permission)
+	if err != nil {
+		log.Printf("publishVehicleEvent %v %v: %v\n", event.Type, vehicleId, err)
+	}
+
+	publishEvent(append(userIds, cohostIds...), event)
+}
+
+// Events are best effort, so a failure to publish never fails the request that caused it
+func publishEvent(userIds []string, event services.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for _, userId := range userIds {
+		if err := services.PublishEvent(ctx, userId, event); err != nil {
+			log.Printf("publishEvent %v %v: %v\n", event.Type, userId, err)
+		}
+	}
+}
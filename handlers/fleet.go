@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+// Features are separated by semicolons within their column
+var fleetCSVColumns = []string{
+	"external_id", "vin", "name", "make", "model", "year", "category", "doors", "seats", "fuel_type",
+	"ev_range", "transmission", "features", "rental_fee", "address", "latitude", "longitude", "description",
+}
+
+type fleetCSVRow struct {
+	messages    map[string]string
+	number      int
+	requestBody *CreateVehicleRequestBody
+}
+
+// Returns the host's vehicles in the columns ImportVehicles takes, so a fleet can
+// be exported, edited and imported again. Vehicles that ImportVehicles would
+// reject, e.g. ones listed before VINs and attributes were required, are left
+// out and counted in the X-Skipped-Vehicles header
+func ExportVehicles(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	sql := `
+	SELECT address, category, description, doors, ev_range, external_id, features, fuel_type,
+		location, make, model, name, rental_fee, seats, transmission, vin, year
+	FROM vehicles
+	WHERE user_id = $1
+	ORDER BY created_at`
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	if err = writer.Write(fleetCSVColumns); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	skipped := 0
+	for rows.Next() {
+		vehicle := &models.Vehicle{Location: &models.Location{}}
+		destination := []interface{}{
+			&vehicle.Address,
+			&vehicle.Category,
+			&vehicle.Description,
+			&vehicle.Doors,
+			&vehicle.EVRange,
+			&vehicle.ExternalID,
+			&vehicle.Features,
+			&vehicle.FuelType,
+			vehicle.Location,
+			&vehicle.Make,
+			&vehicle.Model,
+			&vehicle.Name,
+			&vehicle.RentalFee,
+			&vehicle.Seats,
+			&vehicle.Transmission,
+			&vehicle.VIN,
+			&vehicle.Year,
+		}
+		if err = rows.Scan(destination...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		record := fleetCSVRecord(vehicle)
+		values := map[string]string{}
+		for i, column := range fleetCSVColumns {
+			values[column] = record[i]
+		}
+
+		if _, messages := parseFleetCSVRecord(values); messages != nil {
+			skipped++
+			continue
+		}
+
+		if err = writer.Write(record); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	writer.Flush()
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="vehicles.csv"`)
+	c.Header("X-Skipped-Vehicles", strconv.Itoa(skipped))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
+
+// Creates or updates the host's vehicles from a CSV file with the columns of
+// ExportVehicles. Every row is checked like a vehicle created on its own and is
+// matched to the host's vehicle with the same external id, or else the same VIN.
+// Either every row is saved or, when any of them fails, none is. A dry run
+// reports what would happen without saving anything
+func ImportVehicles(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &ImportVehiclesRequestBody{}
+	if messages := helpers.ValidateRequestForm(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if requestBody.File.Size > config.MaxDocumentSizeInBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"file": fmt.Sprintf("File should not be larger than %vMB", config.MaxDocumentSizeInBytes>>20)})
+		return
+	}
+
+	file, err := requestBody.File.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	rows, err := readFleetCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"file": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	rowErrors := []gin.H{}
+	results := []gin.H{}
+	for _, row := range rows {
+		if row.messages != nil {
+			rowErrors = append(rowErrors, gin.H{"errors": row.messages, "row": row.number})
+			continue
+		}
+
+		vehicle, messages, err := newVehicle(ctx, row.requestBody, cliams.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		action := ""
+		if messages == nil {
+			action, err = saveImportedVehicle(ctx, tx, vehicle)
+			if messages = checkVehicleConflict(err); messages == nil && err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+				return
+			}
+		}
+
+		if messages != nil {
+			rowErrors = append(rowErrors, gin.H{"errors": messages, "row": row.number})
+			continue
+		}
+
+		result := gin.H{"action": action, "row": row.number, "vin": vehicle.VIN}
+		if !requestBody.DryRun {
+			result["id"] = vehicle.ID
+		}
+
+		results = append(results, result)
+	}
+
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": rowErrors})
+		return
+	}
+
+	if !requestBody.DryRun {
+		if err = tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dry_run": requestBody.DryRun, "vehicles": results})
+}
+
+func fleetCSVRecord(vehicle *models.Vehicle) []string {
+	text := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+
+		return *value
+	}
+	number := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+
+		return strconv.Itoa(*value)
+	}
+
+	return []string{
+		text(vehicle.ExternalID),
+		text(vehicle.VIN),
+		vehicle.Name,
+		vehicle.Make,
+		text(vehicle.Model),
+		number(vehicle.Year),
+		text(vehicle.Category),
+		number(vehicle.Doors),
+		number(vehicle.Seats),
+		text(vehicle.FuelType),
+		number(vehicle.EVRange),
+		text(vehicle.Transmission),
+		strings.Join(vehicle.Features, ";"),
+		strconv.Itoa(vehicle.RentalFee),
+		vehicle.Address,
+		strconv.FormatFloat(vehicle.Location.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(vehicle.Location.Longitude, 'f', -1, 64),
+		vehicle.Description,
+	}
+}
+
+// Turns the values of a row into the body of a vehicle creation request and
+// checks it with the same rules. Values that aren't numbers where numbers are
+// expected are reported before the rules
+func parseFleetCSVRecord(values map[string]string) (*CreateVehicleRequestBody, map[string]string) {
+	requestBody := &CreateVehicleRequestBody{
+		Address:     values["address"],
+		Description: values["description"],
+		ExternalID:  values["external_id"],
+		Make:        values["make"],
+		Name:        values["name"],
+		VIN:         strings.ToUpper(values["vin"]),
+	}
+	requestBody.Category = values["category"]
+	requestBody.FuelType = values["fuel_type"]
+	requestBody.Model = values["model"]
+	requestBody.Transmission = values["transmission"]
+	if values["features"] != "" {
+		for _, feature := range strings.Split(values["features"], ";") {
+			requestBody.Features = append(requestBody.Features, strings.TrimSpace(feature))
+		}
+	}
+
+	messages := map[string]string{}
+	integers := map[string]*int{
+		"doors":      &requestBody.Doors,
+		"rental_fee": &requestBody.RentalFee,
+		"seats":      &requestBody.Seats,
+		"year":       &requestBody.Year,
+	}
+	if values["ev_range"] != "" {
+		requestBody.EVRange = new(int)
+		integers["ev_range"] = requestBody.EVRange
+	}
+
+	for column, destination := range integers {
+		if values[column] == "" {
+			continue
+		}
+
+		value, err := strconv.Atoi(values[column])
+		if err != nil {
+			messages[column] = fmt.Sprintf("%v should be a whole number", strings.Title(column))
+			continue
+		}
+
+		*destination = value
+	}
+
+	decimals := map[string]**float64{"latitude": &requestBody.Latitude, "longitude": &requestBody.Longitude}
+	for column, destination := range decimals {
+		if values[column] == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(values[column], 64)
+		if err != nil {
+			messages[column] = fmt.Sprintf("%v should be a number", strings.Title(column))
+			continue
+		}
+
+		*destination = &value
+	}
+
+	for field, message := range helpers.ValidateStruct(requestBody) {
+		if _, ok := messages[field]; !ok {
+			messages[field] = message
+		}
+	}
+
+	if len(messages) > 0 {
+		return nil, messages
+	}
+
+	return requestBody, nil
+}
+
+// Reads the rows of a fleet CSV file, whose header decides the order of the
+// columns. A VIN or external id can only be on one row of the file
+func readFleetCSV(file io.Reader) ([]fleetCSVRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("File should have a header and at least one row")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]bool{}
+	for _, column := range fleetCSVColumns {
+		columns[column] = false
+	}
+
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		seen, ok := columns[column]
+		if !ok {
+			return nil, fmt.Errorf("File has an unknown column %v", column)
+		}
+
+		if seen {
+			return nil, fmt.Errorf("File has the column %v more than once", column)
+		}
+
+		columns[column] = true
+		header[i] = column
+	}
+
+	rows := []fleetCSVRow{}
+	vins, externalIds := map[string]int{}, map[string]int{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) == config.MaxFleetImportRows {
+			return nil, fmt.Errorf("File should not have more than %v rows", config.MaxFleetImportRows)
+		}
+
+		values := map[string]string{}
+		for i, column := range header {
+			values[column] = strings.TrimSpace(record[i])
+		}
+
+		row := fleetCSVRow{}
+		row.number, _ = reader.FieldPos(0)
+		row.requestBody, row.messages = parseFleetCSVRecord(values)
+		vin, externalId := strings.ToUpper(values["vin"]), values["external_id"]
+		if number, ok := vins[vin]; ok && row.messages == nil {
+			row.messages = map[string]string{"vin": fmt.Sprintf("Vin is already on row %v", number)}
+		}
+
+		if number, ok := externalIds[externalId]; ok && row.messages == nil {
+			row.messages = map[string]string{"external_id": fmt.Sprintf("External_id is already on row %v", number)}
+		}
+
+		if vin != "" {
+			vins[vin] = row.number
+		}
+
+		if externalId != "" {
+			externalIds[externalId] = row.number
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("File should have a header and at least one row")
+	}
+
+	return rows, nil
+}
+
+// Updates the host's vehicle with the same external id, or else the same VIN, and
+// creates one when there is none. Each vehicle is saved within a savepoint so
+// that a conflict doesn't abort the rest of the import
+func saveImportedVehicle(ctx context.Context, tx pgx.Tx, vehicle *models.Vehicle) (string, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer savepoint.Rollback(ctx)
+
+	sql := `
+	SELECT id FROM vehicles
+	WHERE user_id = $1 AND (external_id = $2 OR vin = $3)
+	ORDER BY external_id = $2 DESC NULLS LAST, unlisted_at IS NULL DESC, created_at DESC
+	LIMIT 1
+	FOR UPDATE`
+	err = savepoint.QueryRow(ctx, sql, vehicle.UserID, vehicle.ExternalID, vehicle.VIN).Scan(&vehicle.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err = models.InsertVehicle(ctx, savepoint, vehicle); err != nil {
+			return "", err
+		}
+
+		return "created", savepoint.Commit(ctx)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = models.UpdateVehicleListing(ctx, savepoint, vehicle); err != nil {
+		return "", err
+	}
+
+	return "updated", savepoint.Commit(ctx)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v4"
+)
+
+// The party that didn't record the inspection acknowledges it. Once both parties
+// have acknowledged the check-out, its mileage and fuel fees are charged along
+// with their taxes
+func AcknowledgeInspection(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	inspectionType := c.Param("type")
+	if inspectionType != models.InspectionTypeCheckIn && inspectionType != models.InspectionTypeCheckOut {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Inspection not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var charge *models.BookingCharge
+	var inspection *models.Inspection
+	booking, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		isHost, response := checkInspectingSide(ctx, tx, booking, cliams.ID)
+		if response != nil {
+			return response
+		}
+
+		var err error
+		inspection, err = models.SelectInspectionForUpdate(ctx, tx, booking.ID, inspectionType)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Inspection not found"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if inspection.IsAcknowledged() {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "This inspection has already been acknowledged"}}
+		}
+
+		if err = inspection.Acknowledge(ctx, tx, isHost); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		overageFee := inspection.MileageFee + inspection.FuelFee
+		if inspection.Type != models.InspectionTypeCheckOut || !inspection.IsAcknowledged() || overageFee == 0 {
+			return nil
+		}
+
+		rules, err := models.SelectBookingTaxRules(ctx, tx, booking)
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		// The fees are taxed at the rental's rates, without the surcharges for each day
+		taxLines := models.CalculateTaxLines(rules, overageFee, 0)
+		taxAmount := models.SumTaxLines(taxLines)
+		description := fmt.Sprintf("Mileage and fuel fees for booking %v", booking.ID)
+		charge, err = chargeBooking(ctx, tx, booking, models.BookingChargeTypeOverage, booking.ID, overageFee+taxAmount, taxAmount, description)
+		if err != nil {
+			return paymentErrorResponse(err)
+		}
+
+		if charge == nil {
+			return nil
+		}
+
+		if err = models.InsertBookingTaxLines(ctx, tx, booking.ID, &charge.ID, taxLines); err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		if charge.FailureReason != "" {
+			return nil
+		}
+
+		return reviseBookingInvoice(ctx, tx, booking)
+	})
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	if charge != nil && charge.FailureReason != "" {
+		log.Printf("AcknowledgeInspection %v: overage charge failed: %v\n", booking.ID, charge.FailureReason)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"charge": charge, "inspection": inspection})
+}
+
+func CheckInBooking(c *gin.Context) {
+	createInspection(c, models.InspectionTypeCheckIn)
+}
+
+func CheckOutBooking(c *gin.Context) {
+	createInspection(c, models.InspectionTypeCheckOut)
+}
+
+func GetInspectionPhoto(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	index := -1
+	for i, side := range models.InspectionSides {
+		if side == c.Param("side") {
+			index = i
+		}
+	}
+
+	if index == -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Side should be one of front, back, left or right"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if _, response := checkBookingVisible(ctx, pool, bookingId, cliams); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	inspection, err := models.SelectInspection(ctx, pool, bookingId, c.Param("type"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Inspection not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	file, err := services.GetFileStorage().Open(ctx, inspection.Photos[index])
+	if errors.Is(err, services.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Photo not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(inspection.Photos[index]))
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
+
+// Returns the check-in and check-out of the trip, either of which is null until
+// it has been recorded
+func GetInspections(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if _, response := checkBookingVisible(ctx, pool, bookingId, cliams); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	inspections := gin.H{}
+	for _, inspectionType := range []string{models.InspectionTypeCheckIn, models.InspectionTypeCheckOut} {
+		inspection, err := models.SelectInspection(ctx, pool, bookingId, inspectionType)
+		if errors.Is(err, pgx.ErrNoRows) {
+			inspections[inspectionType] = nil
+			continue
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+			return
+		}
+
+		inspections[inspectionType] = inspection
+	}
+
+	c.JSON(http.StatusOK, inspections)
+}
+
+// Bookings are visible to their participants, to staff and to every co-host of the vehicle
+func checkBookingVisible(ctx context.Context, querier models.Querier, bookingId string, cliams *services.AccessTokenClaims) (*models.Booking, *models.SQLResponse) {
+	booking, err := models.SelectBooking(ctx, querier, bookingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if booking.UserID == cliams.ID || cliams.Role == config.RoleAdmin || cliams.Role == config.RoleSupport {
+		return booking, nil
+	}
+
+	isManager, err := booking.IsManagedBy(ctx, querier, cliams.ID, "")
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !isManager {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+	}
+
+	return booking, nil
+}
+
+// The renter records and acknowledges inspections for themselves, while the host
+// and co-hosts who handle check-in do it for the host's side
+func checkInspectingSide(ctx context.Context, querier models.Querier, booking *models.Booking, userId string) (bool, *models.SQLResponse) {
+	if booking.UserID == userId {
+		return false, nil
+	}
+
+	isManager, err := booking.IsManagedBy(ctx, querier, userId, models.CohostPermissionCheckIn)
+	if err != nil {
+		return false, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !isManager {
+		return false, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+	}
+
+	return true, nil
+}
+
+// Either party records the inspection while the trip is in progress, which also
+// counts as their acknowledgement of it. A check-out is compared with the check-in
+// straight away so both parties see the fees before acknowledging
+func createInspection(c *gin.Context, inspectionType string) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	requestBody := &CreateInspectionRequestBody{}
+	if messages := helpers.ValidateRequestForm(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	for _, photo := range requestBody.Photos() {
+		if photo.Size > config.MaxDocumentSizeInBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Each photo should not be larger than 5MB"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	inspection := &models.Inspection{
+		ID:        uuid.NewString(),
+		BookingID: bookingId,
+		FuelLevel: *requestBody.FuelLevel,
+		Odometer:  *requestBody.Odometer,
+		Type:      inspectionType,
+		UserID:    &cliams.ID,
+	}
+	storage := services.GetFileStorage()
+	_, response := updateBooking(ctx, bookingId, func(tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+		isHost, response := checkInspectingSide(ctx, tx, booking, cliams.ID)
+		if response != nil {
+			return response
+		}
+
+		if booking.Status != models.BookingStatusInProgress {
+			return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Inspections can only be recorded while the trip is in progress"}}
+		}
+
+		if isHost {
+			inspection.HostAcknowledgedAt = &now
+		} else {
+			inspection.RenterAcknowledgedAt = &now
+		}
+
+		if inspectionType == models.InspectionTypeCheckOut {
+			checkIn, err := models.SelectInspection(ctx, tx, booking.ID, models.InspectionTypeCheckIn)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "The trip needs a check-in before it can be checked out"}}
+			}
+
+			if err != nil {
+				return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+			}
+
+			if inspection.Odometer < checkIn.Odometer {
+				return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"odometer": "Odometer should not be less than it was at check-in"}}
+			}
+
+			inspection.CalculateOverage(checkIn, booking)
+		}
+
+		for index, photo := range requestBody.Photos() {
+			key := fmt.Sprintf("inspections/%v/%v/%v", booking.ID, inspection.ID, models.InspectionSides[index])
+			key, err := storeUploadedFile(ctx, storage, key, photo, photoExtensions)
+			if errors.Is(err, errUnsupportedDocument) {
+				return &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{models.InspectionSides[index]: "Photos should be JPEG or PNG images"}}
+			}
+
+			if err != nil {
+				return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+			}
+
+			inspection.Photos = append(inspection.Photos, key)
+		}
+
+		err := models.InsertInspection(ctx, tx, inspection)
+		pgErr := new(pgconn.PgError)
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return &models.SQLResponse{StatusCode: http.StatusConflict, Body: gin.H{"message": "This inspection has already been recorded"}}
+		}
+
+		if err != nil {
+			return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+		}
+
+		return nil
+	})
+	if response != nil {
+		deleteFiles(ctx, storage, inspection.Photos)
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"inspection": inspection})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Returns the booking's invoice as a PDF. Bookings that were confirmed before
+// invoices were issued get theirs the first time it's asked for
+func GetBookingInvoice(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	bookingId := c.Param("id")
+	if _, err := uuid.Parse(bookingId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Booking with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if _, response := checkBookingVisible(ctx, pool, bookingId, cliams); response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	invoice, response := selectBookingInvoice(ctx, bookingId)
+	if response != nil {
+		c.JSON(response.StatusCode, response.Body)
+		return
+	}
+
+	file, err := openBookingInvoice(ctx, invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	headers := map[string]string{"Content-Disposition": fmt.Sprintf(`attachment; filename="%v.pdf"`, invoice.Number)}
+	c.DataFromReader(http.StatusOK, -1, "application/pdf", file, headers)
+}
+
+// Mails the renter of a booking that was just confirmed with its invoice attached.
+// Like notifyBookingUpdated this is best effort, so the mail is still sent when
+// the invoice couldn't be issued
+func notifyBookingConfirmed(booking *models.Booking) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	attachments := []services.MailAttachment{}
+	attachment, err := readBookingInvoice(ctx, booking.ID)
+	if err != nil {
+		log.Printf("notifyBookingConfirmed %v: %v\n", booking.ID, err)
+	} else {
+		attachments = append(attachments, *attachment)
+	}
+
+	notifyBookingUpdated(booking, booking.UserID, "Your booking has been confirmed", attachments...)
+}
+
+func readBookingInvoice(ctx context.Context, bookingId string) (*services.MailAttachment, error) {
+	invoice, response := selectBookingInvoice(ctx, bookingId)
+	if response != nil {
+		return nil, fmt.Errorf("%v", response.Body)
+	}
+
+	file, err := openBookingInvoice(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &services.MailAttachment{Content: content, Filename: invoice.Number + ".pdf", Type: "application/pdf"}, nil
+}
+
+// The PDF is written after the invoice is saved, so it's written here when it
+// isn't in file storage yet
+func openBookingInvoice(ctx context.Context, invoice *models.Invoice) (io.ReadCloser, error) {
+	storage := services.GetFileStorage()
+	file, err := storage.Open(ctx, invoice.FileKey)
+	if !errors.Is(err, services.ErrFileNotFound) {
+		return file, err
+	}
+
+	pool := services.GetPostgresConnectionPool()
+	booking, err := models.SelectBooking(ctx, pool, invoice.BookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = invoice.WriteFile(ctx, pool, booking); err != nil {
+		return nil, err
+	}
+
+	return storage.Open(ctx, invoice.FileKey)
+}
+
+// Issues the booking a new revision of its invoice after what the renter paid for
+// it has changed. A booking without an invoice is left alone, it gets one that's
+// up to date when it's first asked for
+func reviseBookingInvoice(ctx context.Context, tx pgx.Tx, booking *models.Booking) *models.SQLResponse {
+	_, err := models.SelectInvoice(ctx, tx, booking.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+
+	if err == nil {
+		_, err = models.IssueInvoice(ctx, tx, booking)
+	}
+
+	if err != nil {
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return nil
+}
+
+// Returns the booking's invoice, issuing it first when the booking has been
+// confirmed but doesn't have one yet. The booking stays locked while it's issued
+// so that it's only ever given one number
+func selectBookingInvoice(ctx context.Context, bookingId string) (*models.Invoice, *models.SQLResponse) {
+	pool := services.GetPostgresConnectionPool()
+	invoice, err := models.SelectInvoice(ctx, pool, bookingId)
+	if err == nil {
+		return invoice, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := models.SelectBookingForUpdate(ctx, tx, bookingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusNotFound, Body: gin.H{"message": "Booking not found"}}
+	}
+
+	if err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	// Another request may have issued it while this one waited for the lock
+	invoice, err = models.SelectInvoice(ctx, tx, bookingId)
+	if err == nil {
+		return invoice, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if !booking.IsInvoiceable() {
+		return nil, &models.SQLResponse{StatusCode: http.StatusBadRequest, Body: gin.H{"message": "Only confirmed bookings have an invoice"}}
+	}
+
+	if invoice, err = models.IssueInvoice(ctx, tx, booking); err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+
+	return invoice, nil
+}
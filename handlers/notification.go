@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/Ekenzy-101/Pentahire-API/models"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func ForgotPassword(c *gin.Context) {
@@ -54,7 +56,7 @@ func ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	err = user.SendPasswordResetMail(token)
+	err = user.SendPasswordResetMail(ctx, token)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
@@ -63,6 +65,147 @@ func ForgotPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Mail has been sent successfully"})
 }
 
+func GetNotificationPreferences(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	preferences, err := models.SelectNotificationPreferences(ctx, pool, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+func GetNotifications(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestQuery := &GetNotificationsQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	arguments := []interface{}{cliams.ID}
+	conditions := []string{"user_id = $1"}
+	if requestQuery.Unread {
+		conditions = append(conditions, "read_at IS NULL")
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(n)), '[]') FROM (
+		SELECT id, body, created_at, data, read_at, title, type
+		FROM notifications
+		%v
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	) AS n`, helpers.BuildWhereClause(conditions))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifications := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&notifications); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	unreadCount := 0
+	sql = "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL"
+	if err := pool.QueryRow(ctx, sql, cliams.ID).Scan(&unreadCount); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "page": requestQuery.Page, "unread_count": unreadCount})
+}
+
+func MarkAllNotificationsRead(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sql := "UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL"
+	pool := services.GetPostgresConnectionPool()
+	if _, err := pool.Exec(ctx, sql, cliams.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	notificationId := c.Param("id")
+	if _, err := uuid.Parse(notificationId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Notification with the given id is invalid"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Keeps the first read_at so marking a notification twice is harmless
+	sql := "UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2"
+	pool := services.GetPostgresConnectionPool()
+	commandTag, err := pool.Exec(ctx, sql, notificationId, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func UpdateNotificationPreference(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &UpdateNotificationPreferenceRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	preference := models.NotificationPreference{
+		Email: *requestBody.Email,
+		InApp: *requestBody.InApp,
+		SMS:   *requestBody.SMS,
+		Type:  c.Param("type"),
+	}
+	if !models.IsNotificationType(preference.Type) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Notification type not found"})
+		return
+	}
+
+	if !preference.Email && models.IsEmailRequired(preference.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"email": "Email can't be turned off for this notification type"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	if err := models.UpsertNotificationPreference(ctx, pool, cliams.ID, preference); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preference": preference})
+}
+
 func VerifyEmail(c *gin.Context) {
 	requestBody := &EmailField{}
 	messages := helpers.ValidateRequestBody(c, requestBody)
@@ -116,7 +259,7 @@ func VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	err = user.SendEmailVerificationMail(token)
+	err = user.SendEmailVerificationMail(ctx, token)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+// Providers retry webhooks that don't get a 2xx response, so anything that can
+// succeed on a retry is answered with a 5xx and everything else with a 2xx or 4xx
+func HandlePaymentWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	provider := services.GetPaymentProvider()
+	event, err := provider.ParseWebhook(payload, c.GetHeader("Payment-Signature"))
+	if errors.Is(err, services.ErrInvalidWebhookSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Webhook signature is invalid"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	isNew, err := models.InsertPaymentWebhookEvent(ctx, tx, provider.Name(), event, payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !isNew {
+		c.JSON(http.StatusOK, gin.H{"message": "Event has already been processed"})
+		return
+	}
+
+	payment, err := models.SelectPaymentByIntentForUpdate(ctx, tx, provider.Name(), event.Intent.ID)
+	if err == nil {
+		err = payment.Apply(ctx, tx, &event.Intent, models.PaymentReasonWebhook+":"+event.Type)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = applySecurityDepositWebhook(ctx, tx, provider.Name(), event)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Intents that don't belong to a booking, e.g. ones whose booking was rolled back
+		log.Printf("HandlePaymentWebhook %v: no payment for intent %v\n", event.ID, event.Intent.ID)
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// Creates and authorises the intent for a booking that is being requested. The
+// intent is cancelled again if it can't be authorised. Credits can take the total
+// below the tax, in which case the rest of the tax is paid with the promotion
+func authorisePayment(ctx context.Context, tx pgx.Tx, booking *models.Booking, paymentMethodId string) (*models.Payment, error) {
+	provider := services.GetPaymentProvider()
+	intent, err := provider.CreateIntent(ctx, booking.TotalAmount, config.Currency, "booking:"+booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &models.Payment{
+		Amount:           intent.Amount,
+		BookingID:        booking.ID,
+		Currency:         intent.Currency,
+		PaymentMethodID:  paymentMethodId,
+		Provider:         provider.Name(),
+		ProviderIntentID: intent.ID,
+		Status:           intent.Status,
+		TaxAmount:        min(booking.TaxAmount, intent.Amount),
+	}
+	if err = models.InsertPayment(ctx, tx, payment); err != nil {
+		return nil, err
+	}
+
+	authorisedIntent, err := provider.Authorise(ctx, intent.ID, paymentMethodId)
+	if err != nil {
+		cancelPaymentIntent(intent.ID)
+		return nil, err
+	}
+
+	return payment, payment.Apply(ctx, tx, authorisedIntent, models.PaymentReasonAuthorise)
+}
+
+func applySecurityDepositWebhook(ctx context.Context, tx pgx.Tx, provider string, event *services.PaymentWebhookEvent) error {
+	deposit, err := models.SelectSecurityDepositByIntentForUpdate(ctx, tx, provider, event.Intent.ID)
+	if err != nil {
+		return err
+	}
+
+	return deposit.Apply(ctx, tx, &event.Intent)
+}
+
+// Holds the booking's security deposit on the payment method the renter booked
+// with. Nothing is captured unless a damage claim is settled against it
+func authoriseSecurityDeposit(ctx context.Context, tx pgx.Tx, booking *models.Booking, paymentMethodId string) (*models.SecurityDeposit, error) {
+	provider := services.GetPaymentProvider()
+	intent, err := provider.CreateIntent(ctx, booking.SecurityDeposit, config.Currency, "security_deposit:"+booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorisedIntent, err := provider.Authorise(ctx, intent.ID, paymentMethodId)
+	if err != nil {
+		cancelPaymentIntent(intent.ID)
+		return nil, err
+	}
+
+	deposit := &models.SecurityDeposit{
+		Amount:           authorisedIntent.Amount,
+		BookingID:        booking.ID,
+		Currency:         authorisedIntent.Currency,
+		Provider:         provider.Name(),
+		ProviderIntentID: authorisedIntent.ID,
+		Status:           authorisedIntent.Status,
+	}
+	if err = models.InsertSecurityDeposit(ctx, tx, deposit); err != nil {
+		cancelPaymentIntent(intent.ID)
+		return nil, err
+	}
+
+	return deposit, nil
+}
+
+// Charges the renter again on the payment method they booked with, e.g. for the
+// mileage and fuel fees worked out at check-out. A charge the provider refuses is
+// still recorded, with its failure reason, so that it can be followed up. The
+// provider sees chargeType and referenceId as the key of the charge. amount
+// includes taxAmount
+func chargeBooking(ctx context.Context, tx pgx.Tx, booking *models.Booking, chargeType string, referenceId string, amount int, taxAmount int, description string) (*models.BookingCharge, error) {
+	payment, err := models.SelectPaymentByBookingForUpdate(ctx, tx, booking.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := services.GetPaymentProvider()
+	intent, err := provider.CreateIntent(ctx, amount, config.Currency, chargeType+":"+referenceId)
+	if err != nil {
+		return nil, err
+	}
+
+	charge := &models.BookingCharge{
+		Amount:           amount,
+		BookingID:        booking.ID,
+		Currency:         intent.Currency,
+		Description:      description,
+		Provider:         provider.Name(),
+		ProviderIntentID: intent.ID,
+		Status:           intent.Status,
+		TaxAmount:        taxAmount,
+		Type:             chargeType,
+	}
+	authorisedIntent, err := provider.Authorise(ctx, intent.ID, payment.PaymentMethodID)
+	if err == nil {
+		charge.Status = authorisedIntent.Status
+		intent, err = provider.Capture(ctx, intent.ID, amount, services.PaymentIdempotencyKey(models.PaymentReasonCapture, intent.ID, 0))
+	}
+
+	if err == nil {
+		charge.Status = intent.Status
+	} else {
+		charge.FailureReason = err.Error()
+		cancelPaymentIntent(charge.ProviderIntentID)
+	}
+
+	return charge, models.InsertBookingCharge(ctx, tx, charge)
+}
+
+// Best effort, an intent left behind is never captured and expires at the provider
+func cancelPaymentIntent(intentId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := services.GetPaymentProvider().Cancel(ctx, intentId, services.PaymentIdempotencyKey(models.PaymentReasonCancel, intentId, 0)); err != nil {
+		log.Printf("cancelPaymentIntent %v: %v\n", intentId, err)
+	}
+}
+
+func paymentErrorResponse(err error) *models.SQLResponse {
+	switch {
+	case errors.Is(err, services.ErrPaymentDeclined):
+		return &models.SQLResponse{StatusCode: http.StatusPaymentRequired, Body: gin.H{"message": "Your payment method was declined"}}
+	case errors.Is(err, services.ErrInvalidPaymentState):
+		return &models.SQLResponse{StatusCode: http.StatusConflict, Body: gin.H{"message": "Payment can't be changed in its current state"}}
+	case errors.Is(err, services.ErrPaymentIntentNotFound):
+		return &models.SQLResponse{StatusCode: http.StatusBadGateway, Body: gin.H{"message": "Payment is unknown to the payment provider"}}
+	default:
+		return &models.SQLResponse{StatusCode: http.StatusInternalServerError, Body: gin.H{"message": err.Error()}}
+	}
+}
+
+// Settles the payment of a booking that is cancelled. The renter ends up paying
+// chargeAmount, which is taken from the rental payment first and then from the
+// booking's charges, e.g. for approved trip changes. Each is captured from an
+// authorisation or kept from a capture for its own share, with the rest refunded
+func settleCancelledPayment(ctx context.Context, tx pgx.Tx, bookingId string, chargeAmount int) (*models.Payment, error) {
+	charges, err := models.SelectRefundableBookingChargesForUpdate(ctx, tx, bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := updatePayment(ctx, tx, bookingId, func(provider services.PaymentProvider, payment *models.Payment) (*services.PaymentIntent, string, error) {
+		switch payment.Status {
+		case services.PaymentStatusPending, services.PaymentStatusAuthorised:
+			captureAmount := min(chargeAmount, payment.Amount)
+			chargeAmount -= captureAmount
+			if captureAmount == 0 {
+				intent, err := provider.Cancel(ctx, payment.ProviderIntentID, services.PaymentIdempotencyKey(models.PaymentReasonCancel, payment.ProviderIntentID, 0))
+				return intent, models.PaymentReasonCancel, err
+			}
+
+			intent, err := provider.Capture(ctx, payment.ProviderIntentID, captureAmount, services.PaymentIdempotencyKey(models.PaymentReasonCapture, payment.ProviderIntentID, 0))
+			return intent, models.PaymentReasonCapture, err
+		case services.PaymentStatusCaptured, services.PaymentStatusPartiallyRefunded:
+			keptAmount := min(chargeAmount, payment.AmountCaptured-payment.AmountRefunded)
+			chargeAmount -= keptAmount
+			refundAmount := payment.AmountCaptured - payment.AmountRefunded - keptAmount
+			if refundAmount <= 0 {
+				return nil, "", nil
+			}
+
+			intent, err := provider.Refund(ctx, payment.ProviderIntentID, refundAmount, services.PaymentIdempotencyKey(models.PaymentReasonRefund, payment.ProviderIntentID, payment.AmountRefunded))
+			return intent, models.PaymentReasonRefund, err
+		default:
+			return nil, "", nil
+		}
+	})
+	if err != nil {
+		return payment, err
+	}
+
+	for _, charge := range charges {
+		keptAmount := min(chargeAmount, charge.Refundable())
+		chargeAmount -= keptAmount
+		if err = refundBookingCharge(ctx, tx, charge, charge.Refundable()-keptAmount); err != nil {
+			return payment, err
+		}
+	}
+
+	return payment, nil
+}
+
+// Gives amount back to the renter of a booking. A captured payment is refunded,
+// with whatever it doesn't hold refunded from the booking's charges, and an
+// authorised one is lowered so that less is captured later
+func refundBooking(ctx context.Context, tx pgx.Tx, bookingId string, amount int) (*models.Payment, error) {
+	charges, err := models.SelectRefundableBookingChargesForUpdate(ctx, tx, bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	chargesRefundable := 0
+	for _, charge := range charges {
+		chargesRefundable += charge.Refundable()
+	}
+
+	payment, err := updatePayment(ctx, tx, bookingId, func(provider services.PaymentProvider, payment *models.Payment) (*services.PaymentIntent, string, error) {
+		switch payment.Status {
+		case services.PaymentStatusAuthorised:
+			if amount >= payment.Amount {
+				return nil, "", services.ErrInvalidPaymentState
+			}
+
+			err := payment.ReduceAmount(ctx, tx, amount)
+			amount = 0
+			return nil, "", err
+		case services.PaymentStatusCaptured, services.PaymentStatusPartiallyRefunded:
+			paymentRefundable := payment.AmountCaptured - payment.AmountRefunded
+			if amount > paymentRefundable+chargesRefundable {
+				return nil, "", services.ErrInvalidPaymentState
+			}
+
+			refundAmount := min(amount, paymentRefundable)
+			amount -= refundAmount
+			if refundAmount == 0 {
+				return nil, "", nil
+			}
+
+			intent, err := provider.Refund(ctx, payment.ProviderIntentID, refundAmount, services.PaymentIdempotencyKey(models.PaymentReasonRefund, payment.ProviderIntentID, payment.AmountRefunded))
+			return intent, models.PaymentReasonRefund, err
+		default:
+			return nil, "", services.ErrInvalidPaymentState
+		}
+	})
+	if err != nil || payment == nil {
+		return payment, err
+	}
+
+	for _, charge := range charges {
+		refundAmount := min(amount, charge.Refundable())
+		amount -= refundAmount
+		if err = refundBookingCharge(ctx, tx, charge, refundAmount); err != nil {
+			return payment, err
+		}
+	}
+
+	return payment, nil
+}
+
+func refundBookingCharge(ctx context.Context, tx pgx.Tx, charge *models.BookingCharge, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	provider := services.GetPaymentProvider()
+	intent, err := provider.Refund(ctx, charge.ProviderIntentID, amount, services.PaymentIdempotencyKey(models.PaymentReasonRefund, charge.ProviderIntentID, charge.AmountRefunded))
+	if err != nil {
+		return err
+	}
+
+	return charge.ApplyRefund(ctx, tx, intent)
+}
+
+// Captures chargeAmount from the booking's security deposit and releases the rest,
+// or releases all of it when chargeAmount is 0
+func settleSecurityDeposit(ctx context.Context, tx pgx.Tx, bookingId string, chargeAmount int) (*models.SecurityDeposit, error) {
+	deposit, err := models.SelectSecurityDepositByBookingForUpdate(ctx, tx, bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if deposit.Status != services.PaymentStatusAuthorised {
+		return deposit, services.ErrInvalidPaymentState
+	}
+
+	provider := services.GetPaymentProvider()
+	var intent *services.PaymentIntent
+	if chargeAmount == 0 {
+		intent, err = provider.Cancel(ctx, deposit.ProviderIntentID, services.PaymentIdempotencyKey(models.PaymentReasonCancel, deposit.ProviderIntentID, 0))
+	} else {
+		intent, err = provider.Capture(ctx, deposit.ProviderIntentID, chargeAmount, services.PaymentIdempotencyKey(models.PaymentReasonCapture, deposit.ProviderIntentID, 0))
+	}
+
+	if err != nil {
+		return deposit, err
+	}
+
+	return deposit, deposit.Apply(ctx, tx, intent)
+}
+
+// Runs a provider operation against the booking's payment and stores the state the
+// provider returns. Bookings made before payments existed have no payment and are
+// left alone. An operation that returns no intent leaves the payment unchanged.
+//
+// The provider is called while the payment row is locked so concurrent requests
+// can't move the same money twice. If the transaction rolls back after the call,
+// the provider is ahead of the payment until its webhook arrives, a retry reuses
+// the idempotency key and gets the same result, and anything still out of step is
+// reported by ReconcileLedger (GET /admin/reconciliation or cmd/reconcile)
+func updatePayment(ctx context.Context, tx pgx.Tx, bookingId string, operation func(provider services.PaymentProvider, payment *models.Payment) (*services.PaymentIntent, string, error)) (*models.Payment, error) {
+	payment, err := models.SelectPaymentByBookingForUpdate(ctx, tx, bookingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	intent, reason, err := operation(services.GetPaymentProvider(), payment)
+	if err != nil || intent == nil {
+		return payment, err
+	}
+
+	return payment, payment.Apply(ctx, tx, intent, reason)
+}
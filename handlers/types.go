@@ -1,5 +1,29 @@
 package handlers
 
+import (
+	"mime/multipart"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+)
+
+type BodyField struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+// The attributes renters filter on. The year is left to the request bodies
+// because it can come from the VIN when a vehicle is created
+type VehicleAttributeFields struct {
+	Category     string   `json:"category" binding:"required,oneof=compact convertible luxury minivan pickup sedan suv van"`
+	Doors        int      `json:"doors" binding:"required,gte=2,lte=6"`
+	EVRange      *int     `json:"ev_range" binding:"omitempty,gt=0"`
+	Features     []string `json:"features" binding:"max=10,dive,oneof=air_conditioning all_wheel_drive android_auto apple_carplay bluetooth child_seat gps heated_seats sunroof usb_charger"`
+	FuelType     string   `json:"fuel_type" binding:"required,oneof=diesel electric hybrid petrol"`
+	Model        string   `json:"model" binding:"required,max=50"`
+	Seats        int      `json:"seats" binding:"required,gte=1,lte=15"`
+	Transmission string   `json:"transmission" binding:"required,oneof=automatic manual"`
+}
+
 type CodeField struct {
 	Code string `json:"code" binding:"required,len=6"`
 }
@@ -21,15 +45,155 @@ type TokenField struct {
 	Token string `json:"token" binding:"required"`
 }
 
+type CreateBookingChangeRequestBody struct {
+	EndAt   time.Time `json:"end_at" binding:"required"`
+	StartAt time.Time `json:"start_at" binding:"required"`
+}
+
+type CreateBookingRequestBody struct {
+	QuoteBookingRequestBody
+	PaymentMethodID string `json:"payment_method_id" binding:"required,max=255"`
+}
+
+type CreateClaimRequestBody struct {
+	Amount      int                     `form:"amount" json:"amount" binding:"required,gt=0"`
+	Description string                  `form:"description" json:"description" binding:"required,max=2000"`
+	Photos      []*multipart.FileHeader `form:"photos" json:"photos" binding:"required,min=1"`
+}
+
+// A percentage discount is a whole percent. Promo codes are stored in upper case
+type CreatePromoCodeRequestBody struct {
+	Code                  string     `json:"code" binding:"required,alphanum,min=3,max=30"`
+	DiscountType          string     `json:"discount_type" binding:"required,oneof=fixed percentage"`
+	DiscountValue         int        `json:"discount_value" binding:"required,gt=0"`
+	ExpiresAt             *time.Time `json:"expires_at"`
+	FirstTripOnly         bool       `json:"first_trip_only"`
+	MaxRedemptions        *int       `json:"max_redemptions" binding:"omitempty,gt=0"`
+	MaxRedemptionsPerUser int        `json:"max_redemptions_per_user" binding:"omitempty,gt=0"`
+	MinTripDays           int        `json:"min_trip_days" binding:"omitempty,gt=0"`
+}
+
+// Rates are in basis points. The bounds are either all given or none of them
+type CreateTaxRuleRequestBody struct {
+	AddressKeyword       string   `json:"address_keyword" binding:"max=100"`
+	Jurisdiction         string   `json:"jurisdiction" binding:"required,max=100"`
+	MaxLatitude          *float64 `json:"max_latitude" binding:"omitempty,gte=-90,lte=90"`
+	MaxLongitude         *float64 `json:"max_longitude" binding:"omitempty,gte=-180,lte=180"`
+	MinLatitude          *float64 `json:"min_latitude" binding:"omitempty,gte=-90,lte=90"`
+	MinLongitude         *float64 `json:"min_longitude" binding:"omitempty,gte=-180,lte=180"`
+	PerDaySurcharge      int      `json:"per_day_surcharge" binding:"gte=0"`
+	RentalTaxBasisPoints int      `json:"rental_tax_basis_points" binding:"gte=0,lte=10000"`
+	VATBasisPoints       int      `json:"vat_basis_points" binding:"gte=0,lte=10000"`
+}
+
+type CreateInspectionRequestBody struct {
+	Back      *multipart.FileHeader `form:"back" json:"back" binding:"required"`
+	FuelLevel *int                  `form:"fuel_level" json:"fuel_level" binding:"required,gte=0,lte=100"`
+	Front     *multipart.FileHeader `form:"front" json:"front" binding:"required"`
+	Left      *multipart.FileHeader `form:"left" json:"left" binding:"required"`
+	Odometer  *int                  `form:"odometer" json:"odometer" binding:"required,gte=0"`
+	Right     *multipart.FileHeader `form:"right" json:"right" binding:"required"`
+}
+
+// Photos are returned in the order of models.InspectionSides
+func (requestBody *CreateInspectionRequestBody) Photos() []*multipart.FileHeader {
+	return []*multipart.FileHeader{requestBody.Front, requestBody.Back, requestBody.Left, requestBody.Right}
+}
+
+type CreateBlackoutRequestBody struct {
+	EndAt   time.Time `json:"end_at" binding:"required"`
+	StartAt time.Time `json:"start_at" binding:"required"`
+	Summary string    `json:"summary" binding:"max=255"`
+}
+
+type CreateCohostRequestBody struct {
+	Email       string   `json:"email" binding:"required,email,max=255"`
+	Permissions []string `json:"permissions" binding:"required,min=1,max=4,unique,dive,oneof=calendar check_in earnings messages"`
+}
+
+type CreateConversationRequestBody struct {
+	BodyField
+	BookingID string `json:"booking_id" binding:"omitempty,uuid"`
+	VehicleID string `json:"vehicle_id" binding:"omitempty,uuid"`
+}
+
+type CreateSavedSearchRequestBody struct {
+	Filters   models.VehicleSearchFilters `json:"filters"`
+	Frequency string                      `json:"frequency" binding:"required,oneof=daily instant"`
+	Name      string                      `json:"name" binding:"required,max=100"`
+}
+
+type CreateVerificationCaseRequestBody struct {
+	Documents []*multipart.FileHeader `form:"documents" json:"documents" binding:"required,min=1,max=3"`
+	Type      string                  `form:"type" json:"type" binding:"required,oneof=driver_licence identity"`
+}
+
+// Calendars shared with a webcal:// link are fetched over https
+type CreateVehicleCalendarRequestBody struct {
+	Name string `json:"name" binding:"max=100"`
+	URL  string `json:"url" binding:"required,url,max=2000"`
+}
+
+// Make and Year are decoded from the VIN when they aren't given. The rental fee
+// is in the minor unit of the currency
+type CreateVehicleRequestBody struct {
+	VehicleAttributeFields
+	Address     string   `json:"address" binding:"required,max=255"`
+	Description string   `json:"description" binding:"max=2000"`
+	ExternalID  string   `json:"external_id" binding:"max=100"`
+	Latitude    *float64 `json:"latitude" binding:"required,gte=-90,lte=90"`
+	Longitude   *float64 `json:"longitude" binding:"required,gte=-180,lte=180"`
+	Make        string   `json:"make" binding:"max=50"`
+	Name        string   `json:"name" binding:"required,max=100"`
+	RentalFee   int      `json:"rental_fee" binding:"required,gt=0"`
+	VIN         string   `json:"vin" binding:"required,len=17,alphanum"`
+	Year        int      `json:"year" binding:"omitempty,gte=1950"`
+}
+
+type GetBookingsQuery struct {
+	PaginationQuery
+	Role   string `form:"role" json:"role" binding:"omitempty,oneof=host renter"`
+	Status string `form:"status" json:"status" binding:"max=20"`
+}
+
+type GetNotificationsQuery struct {
+	PaginationQuery
+	Unread bool `form:"unread" json:"unread"`
+}
+
+// Year defaults to the current one
+type GetTaxSummaryQuery struct {
+	Year int `form:"year" json:"year" binding:"omitempty,gte=2000,lte=9999"`
+}
+
+type GetMessagesQuery struct {
+	Before string `form:"before" json:"before" binding:"omitempty,uuid"`
+	Limit  int    `form:"limit" json:"limit" binding:"omitempty,gte=1,lte=100"`
+}
+
+type ImportVehiclesRequestBody struct {
+	DryRun bool                  `form:"dry_run" json:"dry_run"`
+	File   *multipart.FileHeader `form:"file" json:"file" binding:"required"`
+}
+
 type LoginRequestBody struct {
 	EmailField
 	PasswordField
 }
 
+type QuoteBookingRequestBody struct {
+	EndAt      time.Time `json:"end_at" binding:"required"`
+	PromoCode  string    `json:"promo_code" binding:"max=30"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+	UseCredits bool      `json:"use_credits"`
+	VehicleID  string    `json:"vehicle_id" binding:"required,uuid"`
+}
+
 type RegisterRequestBody struct {
 	NameFields
 	TokenField
 	LoginRequestBody
+	ReferralCode string `json:"referral_code" binding:"max=30"`
 }
 
 type ResetPasswordRequestBody struct {
@@ -37,17 +201,137 @@ type ResetPasswordRequestBody struct {
 	PasswordField
 }
 
+type UpdateNotificationPreferenceRequestBody struct {
+	Email *bool `json:"email" binding:"required"`
+	InApp *bool `json:"in_app" binding:"required"`
+	SMS   *bool `json:"sms" binding:"required"`
+}
+
 type UpdateProfileRequestBody struct {
 	NameFields
 	EmailField
 }
 
+type UpdatePayoutAccountRequestBody struct {
+	AccountNumber string `json:"account_number" binding:"required,len=10,numeric"`
+	BankCode      string `json:"bank_code" binding:"required,max=10,numeric"`
+}
+
 type UpdatePasswordRequestBody struct {
 	OldPassword string `json:"old_password" binding:"required,min=8,max=128,password"`
 	NewPassword string `json:"new_password" binding:"required,min=8,max=128,password"`
 }
 
+type UpdateCancellationPolicyRequestBody struct {
+	Policy string `json:"policy" binding:"required,oneof=flexible moderate strict"`
+}
+
+// A requirement of 0 isn't checked
+type UpdateInstantBookRequestBody struct {
+	Enabled           *bool   `json:"enabled" binding:"required"`
+	MinAccountAgeDays int     `json:"min_account_age_days" binding:"gte=0,lte=3650"`
+	MinRating         float64 `json:"min_rating" binding:"gte=0,lte=5"`
+	MinTrips          int     `json:"min_trips" binding:"gte=0"`
+}
+
+// A vehicle without a daily mileage limit has unlimited mileage
+type UpdatePricingRulesRequestBody struct {
+	DailyMileageLimit *int `json:"daily_mileage_limit" binding:"omitempty,gt=0"`
+	FuelFee           *int `json:"fuel_fee" binding:"required,gte=0"`
+	MileageFee        *int `json:"mileage_fee" binding:"required,gte=0"`
+}
+
+// EVRange is required for electric vehicles and not allowed for any other
+type UpdateVehicleAttributesRequestBody struct {
+	VehicleAttributeFields
+	Year int `json:"year" binding:"required,gte=1950"`
+}
+
+type UpdateSecurityDepositRequestBody struct {
+	Amount *int `json:"amount" binding:"required,gte=0"`
+}
+
 type VerifyLoginRequestBody struct {
 	EmailField
 	CodeField
 }
+
+type PaginationQuery struct {
+	Limit int `form:"limit" json:"limit" binding:"omitempty,gte=1,lte=100"`
+	Page  int `form:"page" json:"page" binding:"omitempty,gte=1"`
+}
+
+func (query *PaginationQuery) LimitAndOffset() (int, int) {
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+
+	return query.Limit, (query.Page - 1) * query.Limit
+}
+
+type RefundRequestBody struct {
+	Amount int `json:"amount" binding:"required,gt=0"`
+	ReasonField
+}
+
+// Amount is what the renter ends up paying and may be anything from nothing up to
+// the amount claimed
+type ResolveClaimRequestBody struct {
+	Amount *int `json:"amount" binding:"required,gte=0"`
+	ReasonField
+}
+
+type ReasonField struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type SearchAuditLogsQuery struct {
+	PaginationQuery
+	ActorID    string `form:"actor_id" json:"actor_id" binding:"omitempty,uuid"`
+	TargetID   string `form:"target_id" json:"target_id" binding:"omitempty,uuid"`
+	TargetType string `form:"target_type" json:"target_type" binding:"omitempty,oneof=booking claim promo_code tax_rule user vehicle verification_case"`
+}
+
+type SearchBookingsQuery struct {
+	PaginationQuery
+	Status    string `form:"status" json:"status" binding:"max=20"`
+	UserID    string `form:"user_id" json:"user_id" binding:"omitempty,uuid"`
+	VehicleID string `form:"vehicle_id" json:"vehicle_id" binding:"omitempty,uuid"`
+}
+
+type SearchClaimsQuery struct {
+	PaginationQuery
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=accepted disputed pending resolved"`
+}
+
+type AutocompleteVehiclesQuery struct {
+	Query string `form:"q" json:"q" binding:"required,max=50"`
+}
+
+// Availability is only checked when both start_at and end_at are given, distance
+// when both latitude and longitude are
+type SearchVehiclesQuery struct {
+	PaginationQuery
+	models.VehicleSearchFilters
+}
+
+type SearchUsersQuery struct {
+	PaginationQuery
+	Query  string `form:"q" json:"q" binding:"max=255"`
+	Role   string `form:"role" json:"role" binding:"omitempty,oneof=renter host admin support"`
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=active suspended"`
+}
+
+type SearchVerificationCasesQuery struct {
+	PaginationQuery
+	Status string `form:"status" json:"status" binding:"omitempty,oneof=pending approved rejected"`
+	Type   string `form:"type" json:"type" binding:"omitempty,oneof=driver_licence identity"`
+}
+
+type UpdateUserRoleRequestBody struct {
+	Role string `json:"role" binding:"required,oneof=renter host admin support"`
+}
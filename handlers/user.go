@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
 	"github.com/Ekenzy-101/Pentahire-API/models"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,8 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// The public profile of any user, whether or not they host. Their listed
+// vehicles are paginated
 func GetUser(c *gin.Context) {
 	userId := c.Param("id")
 	_, err := uuid.Parse(userId)
@@ -21,56 +24,77 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	sql := `
-	WITH cte_vehicles AS (
-  	SELECT id, 
-			average_rating, 
-			image,
-			make,
-			name, 
-			rental_fee,
-			trips_count 
-		FROM vehicles
-		WHERE user_id = $1
-	)
 	SELECT u.id, 
 		u.average_rating, 
+		array_remove(ARRAY[
+			CASE WHEN u.email_verified_at IS NOT NULL THEN 'email_verified' END,
+			CASE WHEN u.phone_verified_at IS NOT NULL THEN 'phone_verified' END,
+			CASE WHEN EXISTS (
+				SELECT 1 FROM verification_cases
+				WHERE user_id = u.id AND type = 'identity' AND status = 'approved'
+			) THEN 'identity_verified' END,
+			CASE WHEN EXISTS (
+				SELECT 1 FROM verification_cases
+				WHERE user_id = u.id AND type = 'driver_licence' AND status = 'approved'
+			) THEN 'driver_licence_verified' END
+		], NULL) AS badges,
 		u.created_at,
 		u.firstname,
+		(SELECT COUNT(*) FROM bookings WHERE user_id = u.id AND status = $2) AS guest_trips_count,
+		(SELECT COUNT(*) FROM vehicles WHERE user_id = u.id AND unlisted_at IS NULL) AS host_listings_count,
+		u.response_rate_30_days,
+		u.response_rate_90_days,
+		u.response_time_30_days,
+		u.response_time_90_days,
+		(
+			SELECT COUNT(*) FROM bookings AS b
+			JOIN vehicles AS v ON b.vehicle_id = v.id
+			WHERE v.user_id = u.id AND b.status = $2
+		) AS host_trips_count,
 		u.image,
-		CASE 
-			WHEN email_verified_at IS NULL THEN CAST ('false' AS BOOLEAN)
-			ELSE CAST('true' AS BOOLEAN)
-		END AS is_email_verified,
-		CASE 
-			WHEN phone_verified_at IS NULL THEN CAST ('false' AS BOOLEAN)
-			ELSE CAST('true' AS BOOLEAN)
-		END AS is_phone_verified,
+		u.email_verified_at IS NOT NULL AS is_email_verified,
+		EXISTS (
+			SELECT 1 FROM verification_cases
+			WHERE user_id = u.id AND type = 'identity' AND status = 'approved'
+		) AS is_identity_verified,
+		u.phone_verified_at IS NOT NULL AS is_phone_verified,
 		u.reviews_count,
-		u.trips_count,
-		json_agg(to_jsonb(v)) AS vehicles
-	FROM users AS u, cte_vehicles AS v
-	WHERE u.id = $1
-	GROUP BY u.id
-	`
-	user := models.User{}
+		u.trips_count
+	FROM users AS u
+	WHERE u.id = $1`
+	user := models.User{GuestStats: &models.GuestStats{}, HostStats: &models.HostStats{}}
 	destination := []interface{}{
 		&user.ID,
 		&user.AverageRating,
+		&user.Badges,
 		&user.CreatedAt,
 		&user.Firstname,
+		&user.GuestStats.TripsCount,
+		&user.HostStats.ListingsCount,
+		&user.HostStats.ResponseRate30Days,
+		&user.HostStats.ResponseRate90Days,
+		&user.HostStats.ResponseTime30Days,
+		&user.HostStats.ResponseTime90Days,
+		&user.HostStats.TripsCount,
 		&user.Image,
 		&user.IsEmailVerified,
+		&user.IsIdentityVerified,
 		&user.IsPhoneVerified,
 		&user.ReviewsCount,
 		&user.TripsCount,
-		&user.Vehicles,
 	}
 	pool := services.GetPostgresConnectionPool()
-	err = pool.QueryRow(ctx, sql, userId).Scan(destination...)
+	err = pool.QueryRow(ctx, sql, userId, models.BookingStatusCompleted).Scan(destination...)
 	if errors.Is(err, pgx.ErrNoRows) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
 		return
@@ -81,5 +105,28 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	limit, offset := requestQuery.LimitAndOffset()
+	sql = `
+	SELECT COALESCE(json_agg(to_jsonb(v)), '[]') FROM (
+		SELECT id, 
+			average_rating, 
+			image,
+			make,
+			name, 
+			rental_fee,
+			trips_count 
+		FROM vehicles
+		WHERE user_id = $1 AND unlisted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	) AS v`
+	vehicles := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, userId, limit, offset).Scan(&vehicles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Reviews aren't stored yet, so the list stays empty until they are
+	reviews := []gin.H{}
+	c.JSON(http.StatusOK, gin.H{"page": requestQuery.Page, "reviews": reviews, "user": user, "vehicles": vehicles})
 }
@@ -3,16 +3,119 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
 	"github.com/Ekenzy-101/Pentahire-API/models"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v4"
 )
 
+// Suggests the makes and models of listed vehicles as the renter types, those
+// starting with what was typed first and then those that look like it. The
+// conditions match the expressions of the trigram indexes on vehicles, keep them
+// in step
+func AutocompleteVehicles(c *gin.Context) {
+	requestQuery := &AutocompleteVehiclesQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefix := likePatternEscaper.Replace(strings.ToLower(strings.TrimSpace(requestQuery.Query))) + "%"
+	sql := `
+	SELECT COALESCE(json_agg(to_jsonb(s)), '[]') FROM (
+		SELECT make, model, COUNT(*) AS vehicles_count
+		FROM vehicles
+		WHERE unlisted_at IS NULL AND (
+			lower(make || ' ' || coalesce(model, '')) LIKE $1 OR 
+			lower(model) LIKE $1 OR 
+			lower($2) <% lower(make || ' ' || coalesce(model, ''))
+		)
+		GROUP BY make, model
+		ORDER BY bool_or(lower(make || ' ' || coalesce(model, '')) LIKE $1 OR lower(model) LIKE $1) DESC, 
+			max(word_similarity(lower($2), lower(make || ' ' || coalesce(model, '')))) DESC, 
+			vehicles_count DESC
+		LIMIT 10
+	) AS s`
+	suggestions := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, prefix, requestQuery.Query).Scan(&suggestions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// Make and year are pre-filled from the VIN when the host leaves them out, which
+// is also where the manufacturer comes from
+func CreateVehicle(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	requestBody := &CreateVehicleRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vehicle, messages, err := newVehicle(ctx, requestBody, cliams.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	err = models.InsertVehicle(ctx, services.GetPostgresConnectionPool(), vehicle)
+	if messages := checkVehicleConflict(err); messages != nil {
+		c.JSON(http.StatusConflict, messages)
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"vehicle": vehicle})
+}
+
+// Lets the listing form pre-fill what it can before the host submits it
+func DecodeVIN(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	decoded, err := services.GetVINDecoder().Decode(ctx, c.Param("vin"))
+	if errors.Is(err, services.ErrInvalidVIN) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vin is invalid"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vin": decoded})
+}
+
 func GetVehicle(c *gin.Context) {
 	vehicleId := c.Param("id")
 	_, err := uuid.Parse(vehicleId)
@@ -26,39 +129,86 @@ func GetVehicle(c *gin.Context) {
 
 	sql := `
 	WITH cte_users AS (
-  	SELECT id, firstname, created_at, image, trips_count FROM users
+  	SELECT id, 
+			firstname, 
+			created_at, 
+			image, 
+			response_rate_30_days, 
+			response_rate_90_days, 
+			response_time_30_days, 
+			response_time_90_days, 
+			trips_count 
+		FROM users
 	)
 	SELECT v.id, 
 		v.address,
 		v.average_rating, 
+		v.cancellation_policy,
+		v.category,
 		v.created_at,
+		v.daily_mileage_limit,
+		v.description,
+		v.doors,
+		v.ev_range,
+		v.features,
+		v.fuel_fee,
+		v.fuel_type,
 		v.is_rented,
 		v.image,
+		v.instant_book,
+		v.instant_book_min_account_age_days,
+		v.instant_book_min_rating,
+		v.instant_book_min_trips,
 		v.location,
 		v.make,
+		v.mileage_fee,
+		v.model,
 		v.name,
 		v.rental_fee,
 		v.reviews_count,
+		v.seats,
+		v.security_deposit,
+		v.transmission,
 		to_jsonb(u) AS user, 
-		v.trips_count
+		v.trips_count,
+		v.year
 	FROM vehicles AS v 
 	JOIN cte_users AS u ON v.user_id = u.id 
-	WHERE v.id = $1`
+	WHERE v.id = $1 AND v.unlisted_at IS NULL`
 	vehicle := &models.Vehicle{Location: &models.Location{}}
 	destination := []interface{}{
 		&vehicle.ID,
 		&vehicle.Address,
 		&vehicle.AverageRating,
+		&vehicle.CancellationPolicy,
+		&vehicle.Category,
 		&vehicle.CreatedAt,
+		&vehicle.DailyMileageLimit,
+		&vehicle.Description,
+		&vehicle.Doors,
+		&vehicle.EVRange,
+		&vehicle.Features,
+		&vehicle.FuelFee,
+		&vehicle.FuelType,
 		&vehicle.IsRented,
 		&vehicle.Image,
+		&vehicle.InstantBook,
+		&vehicle.InstantBookRequirements.MinAccountAgeDays,
+		&vehicle.InstantBookRequirements.MinRating,
+		&vehicle.InstantBookRequirements.MinTrips,
 		vehicle.Location,
 		&vehicle.Make,
+		&vehicle.MileageFee,
+		&vehicle.Model,
 		&vehicle.Name,
 		&vehicle.RentalFee,
 		&vehicle.ReviewsCount,
+		&vehicle.Seats,
+		&vehicle.SecurityDeposit,
+		&vehicle.Transmission,
 		&vehicle.User,
 		&vehicle.TripsCount,
+		&vehicle.Year,
 	}
 	pool := services.GetPostgresConnectionPool()
 	err = pool.QueryRow(ctx, sql, vehicleId).Scan(destination...)
@@ -74,3 +224,516 @@ func GetVehicle(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"vehicle": vehicle})
 }
+
+// Returns what the owner earned from the vehicle's bookings along with the ledger
+// entries behind it. Co-hosts who can view earnings see the same as the owner
+func GetVehicleEarnings(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestQuery := &PaginationQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool := services.GetPostgresConnectionPool()
+	isManager, err := models.IsVehicleManager(ctx, pool, vehicleId, cliams.ID, models.CohostPermissionEarnings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if !isManager {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	sql := `
+	WITH cte_entries AS (
+		SELECT e.id,
+			-e.amount AS amount,
+			b.id AS booking_id,
+			t.created_at,
+			t.description,
+			t.type
+		FROM ledger_entries AS e
+		JOIN ledger_accounts AS a ON e.account_id = a.id
+		JOIN ledger_transactions AS t ON e.transaction_id = t.id
+		LEFT JOIN payments AS p ON t.reference_type = $2 AND t.reference_id = p.id
+		LEFT JOIN security_deposits AS d ON t.reference_type = $3 AND t.reference_id = d.id
+		LEFT JOIN booking_charges AS bc ON t.reference_type = $4 AND t.reference_id = bc.id
+		JOIN bookings AS b ON b.id = COALESCE(
+			p.booking_id, d.booking_id, bc.booking_id, CASE WHEN t.reference_type = $5 THEN t.reference_id END
+		)
+		JOIN vehicles AS v ON b.vehicle_id = v.id
+		WHERE a.type = $6 AND a.user_id = v.user_id AND v.id = $1
+	)
+	SELECT COALESCE(SUM(amount), 0), (
+		SELECT COALESCE(json_agg(to_jsonb(e)), '[]') FROM (
+			SELECT * FROM cte_entries ORDER BY created_at DESC LIMIT $7 OFFSET $8
+		) AS e
+	)
+	FROM cte_entries`
+	arguments := []interface{}{
+		vehicleId,
+		models.LedgerReferencePayment,
+		models.LedgerReferenceSecurityDeposit,
+		models.LedgerReferenceBookingCharge,
+		models.LedgerReferenceBooking,
+		models.LedgerAccountHost,
+		limit,
+		offset,
+	}
+	earnings := 0
+	entries := []gin.H{}
+	if err = pool.QueryRow(ctx, sql, arguments...).Scan(&earnings, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"currency": config.Currency, "earnings": earnings, "entries": entries, "page": requestQuery.Page})
+}
+
+// Only listed vehicles are returned. Text searches are ordered by relevance, which
+// falls off with distance when searching around a point. Otherwise the nearest
+// come first when searching around a point and the newest when not. A number of
+// seats in the text, like "7 seater", is taken as the minimum seats
+func SearchVehicles(c *gin.Context) {
+	requestQuery := &SearchVehiclesQuery{}
+	if messages := helpers.ValidateRequestQuery(c, requestQuery); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if messages := checkVehicleSearchFilters(&requestQuery.VehicleSearchFilters); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	search := requestQuery.Build()
+	arguments, conditions := search.Arguments, search.Conditions
+	distance, orderBy := "NULL::DOUBLE PRECISION", "v.created_at DESC"
+	if search.Distance != "" {
+		distance, orderBy = search.Distance, "distance_in_km"
+	}
+
+	// A vehicle config.SearchDistanceDecayInKm away ranks as if it were half as
+	// relevant as one at the search point
+	if search.Relevance != "" && search.Distance != "" {
+		orderBy = fmt.Sprintf("%v / (1 + %v / %v) DESC", search.Relevance, distance, config.SearchDistanceDecayInKm)
+	} else if search.Relevance != "" {
+		orderBy = search.Relevance + " DESC"
+	}
+
+	limit, offset := requestQuery.LimitAndOffset()
+	arguments = append(arguments, limit, offset)
+	sql := fmt.Sprintf(`
+	SELECT COALESCE(json_agg(to_jsonb(v)), '[]') FROM (
+		SELECT v.id,
+			v.address,
+			v.average_rating,
+			v.category,
+			%v AS distance_in_km,
+			v.doors,
+			v.ev_range,
+			v.features,
+			v.fuel_type,
+			v.image,
+			v.instant_book,
+			jsonb_build_object('latitude', v.location[0], 'longitude', v.location[1]) AS location,
+			v.make,
+			v.model,
+			v.name,
+			v.rental_fee,
+			v.reviews_count,
+			v.seats,
+			v.transmission,
+			v.trips_count,
+			v.year
+		FROM vehicles AS v
+		%v
+		ORDER BY %v
+		LIMIT $%v OFFSET $%v
+	) AS v`, distance, helpers.BuildWhereClause(conditions), orderBy, len(arguments)-1, len(arguments))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vehicles := []gin.H{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, sql, arguments...).Scan(&vehicles); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"page": requestQuery.Page, "vehicles": vehicles})
+}
+
+// Like the security deposit, only applies to bookings made afterwards
+func UpdateCancellationPolicy(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &UpdateCancellationPolicyRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	sql := "UPDATE vehicles SET cancellation_policy = $1 WHERE id = $2"
+	if _, err = pool.Exec(ctx, sql, requestBody.Policy, vehicleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cancellation_policy": requestBody.Policy})
+}
+
+// Bookings that were already made aren't affected
+func UpdateInstantBook(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &UpdateInstantBookRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	requirements := models.InstantBookRequirements{
+		MinAccountAgeDays: requestBody.MinAccountAgeDays,
+		MinRating:         requestBody.MinRating,
+		MinTrips:          requestBody.MinTrips,
+	}
+	sql := `
+	UPDATE vehicles SET 
+		instant_book = $1, 
+		instant_book_min_account_age_days = $2, 
+		instant_book_min_rating = $3, 
+		instant_book_min_trips = $4 
+	WHERE id = $5`
+	arguments := []interface{}{*requestBody.Enabled, requirements.MinAccountAgeDays, requirements.MinRating, requirements.MinTrips, vehicleId}
+	if _, err = pool.Exec(ctx, sql, arguments...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instant_book": *requestBody.Enabled, "instant_book_requirements": requirements})
+}
+
+// Like the security deposit, only applies to bookings made afterwards
+func UpdatePricingRules(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &UpdatePricingRulesRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	sql := "UPDATE vehicles SET daily_mileage_limit = $1, fuel_fee = $2, mileage_fee = $3 WHERE id = $4"
+	arguments := []interface{}{requestBody.DailyMileageLimit, *requestBody.FuelFee, *requestBody.MileageFee, vehicleId}
+	if _, err = pool.Exec(ctx, sql, arguments...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"daily_mileage_limit": requestBody.DailyMileageLimit,
+		"fuel_fee":            *requestBody.FuelFee,
+		"mileage_fee":         *requestBody.MileageFee,
+	})
+}
+
+// Only applies to bookings made afterwards, existing bookings keep the deposit
+// they were made with
+func UpdateSecurityDeposit(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &UpdateSecurityDepositRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	sql := "UPDATE vehicles SET security_deposit = $1 WHERE id = $2"
+	if _, err = pool.Exec(ctx, sql, *requestBody.Amount, vehicleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"security_deposit": *requestBody.Amount})
+}
+
+func UpdateVehicleAttributes(c *gin.Context) {
+	cliams := c.MustGet("user").(*services.AccessTokenClaims)
+	vehicleId := c.Param("id")
+	if _, err := uuid.Parse(vehicleId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Vehicle with the given id is invalid"})
+		return
+	}
+
+	requestBody := &UpdateVehicleAttributesRequestBody{}
+	if messages := helpers.ValidateRequestBody(c, requestBody); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	if messages := checkVehicleAttributes(&requestBody.VehicleAttributeFields, requestBody.Year); messages != nil {
+		c.JSON(http.StatusBadRequest, messages)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hostId := ""
+	pool := services.GetPostgresConnectionPool()
+	err := pool.QueryRow(ctx, "SELECT user_id FROM vehicles WHERE id = $1", vehicleId).Scan(&hostId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hostId != cliams.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Vehicle not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if requestBody.Features == nil {
+		requestBody.Features = []string{}
+	}
+
+	sql := `
+	UPDATE vehicles SET 
+		category = $1, 
+		doors = $2, 
+		ev_range = $3, 
+		features = $4, 
+		fuel_type = $5, 
+		model = $6, 
+		seats = $7, 
+		transmission = $8, 
+		year = $9 
+	WHERE id = $10`
+	arguments := []interface{}{
+		requestBody.Category,
+		requestBody.Doors,
+		requestBody.EVRange,
+		requestBody.Features,
+		requestBody.FuelType,
+		requestBody.Model,
+		requestBody.Seats,
+		requestBody.Transmission,
+		requestBody.Year,
+		vehicleId,
+	}
+	if _, err = pool.Exec(ctx, sql, arguments...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attributes": requestBody})
+}
+
+var (
+	likePatternEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+)
+
+// A VIN can only be on one listed vehicle and an external id on one of the host's
+// vehicles. Messages are only returned for those conflicts
+func checkVehicleConflict(err error) gin.H {
+	pgErr := new(pgconn.PgError)
+	if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.UniqueViolation {
+		return nil
+	}
+
+	if pgErr.ConstraintName == "vehicles_user_id_external_id_key" {
+		return gin.H{"external_id": "Another of your vehicles has this external_id"}
+	}
+
+	return gin.H{"vin": "A listed vehicle already has this vin"}
+}
+
+func checkVehicleSearchFilters(filters *models.VehicleSearchFilters) gin.H {
+	if filters.HasDates() && !filters.EndAt.After(filters.StartAt) {
+		return gin.H{"end_at": "End_at should be after start_at"}
+	}
+
+	if (filters.Latitude == nil) != (filters.Longitude == nil) {
+		return gin.H{"message": "Latitude and longitude should be given together"}
+	}
+
+	return nil
+}
+
+// The checks that binding tags can't express
+func checkVehicleAttributes(requestBody *VehicleAttributeFields, year int) gin.H {
+	if year > time.Now().Year()+1 {
+		return gin.H{"year": fmt.Sprintf("Year should not be greater than %v", time.Now().Year()+1)}
+	}
+
+	if requestBody.FuelType == models.FuelTypeElectric && requestBody.EVRange == nil {
+		return gin.H{"ev_range": "Ev_range is required for electric vehicles"}
+	}
+
+	if requestBody.FuelType != models.FuelTypeElectric && requestBody.EVRange != nil {
+		return gin.H{"ev_range": "Ev_range is only for electric vehicles"}
+	}
+
+	return nil
+}
+
+// Builds the vehicle a creation request describes, or the messages for one that
+// fails the checks its binding tags can't express
+func newVehicle(ctx context.Context, requestBody *CreateVehicleRequestBody, userId string) (*models.Vehicle, gin.H, error) {
+	decoded, err := services.GetVINDecoder().Decode(ctx, requestBody.VIN)
+	if errors.Is(err, services.ErrInvalidVIN) {
+		return nil, gin.H{"vin": "Vin is invalid"}, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if requestBody.Make == "" {
+		requestBody.Make = decoded.Make
+	}
+
+	if requestBody.Year == 0 {
+		requestBody.Year = decoded.ModelYear
+	}
+
+	if requestBody.Make == "" {
+		return nil, gin.H{"make": "Make is required as it couldn't be worked out from the vin"}, nil
+	}
+
+	if requestBody.Year == 0 {
+		return nil, gin.H{"year": "Year is required as it couldn't be worked out from the vin"}, nil
+	}
+
+	if messages := checkVehicleAttributes(&requestBody.VehicleAttributeFields, requestBody.Year); messages != nil {
+		return nil, messages, nil
+	}
+
+	if requestBody.Features == nil {
+		requestBody.Features = []string{}
+	}
+
+	vehicle := &models.Vehicle{
+		Address:      requestBody.Address,
+		Category:     &requestBody.Category,
+		Description:  requestBody.Description,
+		Doors:        &requestBody.Doors,
+		EVRange:      requestBody.EVRange,
+		Features:     requestBody.Features,
+		FuelType:     &requestBody.FuelType,
+		Location:     &models.Location{Latitude: *requestBody.Latitude, Longitude: *requestBody.Longitude},
+		Make:         requestBody.Make,
+		Manufacturer: decoded.Manufacturer,
+		Model:        &requestBody.Model,
+		Name:         requestBody.Name,
+		RentalFee:    requestBody.RentalFee,
+		Seats:        &requestBody.Seats,
+		Transmission: &requestBody.Transmission,
+		UserID:       userId,
+		VIN:          &decoded.VIN,
+		Year:         &requestBody.Year,
+	}
+	if requestBody.ExternalID != "" {
+		vehicle.ExternalID = &requestBody.ExternalID
+	}
+
+	return vehicle, nil, nil
+}
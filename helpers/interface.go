@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -21,3 +22,49 @@ func ValidateRequestBody(c *gin.Context, obj interface{}) interface{} {
 
 	return nil
 }
+
+// Obj should be a pointer to a value. Binds multipart and url encoded forms
+func ValidateRequestForm(c *gin.Context, obj interface{}) interface{} {
+	err := c.ShouldBind(obj)
+	validationErrors := validator.ValidationErrors{}
+	if errors.As(err, &validationErrors) {
+		return GenerateErrorMessages(validationErrors)
+	}
+
+	if err != nil {
+		return gin.H{"message": err.Error()}
+	}
+
+	return nil
+}
+
+// Obj should be a pointer to a value
+func ValidateRequestQuery(c *gin.Context, obj interface{}) interface{} {
+	err := c.ShouldBindQuery(obj)
+	validationErrors := validator.ValidationErrors{}
+	if errors.As(err, &validationErrors) {
+		return GenerateErrorMessages(validationErrors)
+	}
+
+	if err != nil {
+		return gin.H{"message": err.Error()}
+	}
+
+	return nil
+}
+
+// Obj should be a pointer to a value that wasn't bound from the request, e.g. a
+// row of an uploaded file
+func ValidateStruct(obj interface{}) map[string]string {
+	err := binding.Validator.ValidateStruct(obj)
+	validationErrors := validator.ValidationErrors{}
+	if errors.As(err, &validationErrors) {
+		return GenerateErrorMessages(validationErrors)
+	}
+
+	if err != nil {
+		return map[string]string{"message": err.Error()}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -19,6 +20,8 @@ func GenerateErrorMessages(errors validator.ValidationErrors) map[string]string
 			messages[field] = fmt.Sprintf("%v is not a valid email address", strings.Title(field))
 		case "gt":
 			messages[field] = fmt.Sprintf("%v should be greater than %v", strings.Title(field), err.Param())
+		case "gte":
+			messages[field] = fmt.Sprintf("%v should not be less than %v", strings.Title(field), err.Param())
 		case "len":
 			value := fmt.Sprintf("%v should be %v characters", strings.Title(field), err.Param())
 			if field == "code" {
@@ -29,9 +32,9 @@ func GenerateErrorMessages(errors validator.ValidationErrors) map[string]string
 		case "lte":
 			messages[field] = fmt.Sprintf("%v should not be greater than %v", strings.Title(field), err.Param())
 		case "max":
-			messages[field] = fmt.Sprintf("%v should not be greater than %v characters", strings.Title(field), err.Param())
+			messages[field] = fmt.Sprintf("%v should not be greater than %v %v", strings.Title(field), err.Param(), lengthUnit(err))
 		case "min":
-			messages[field] = fmt.Sprintf("%v should not be less than %v characters", strings.Title(field), err.Param())
+			messages[field] = fmt.Sprintf("%v should not be less than %v %v", strings.Title(field), err.Param(), lengthUnit(err))
 		case "oneof":
 			messages[field] = fmt.Sprintf("%v should be in these category %v", strings.Title(field), err.Param())
 		case "password":
@@ -51,3 +54,11 @@ func GenerateErrorMessages(errors validator.ValidationErrors) map[string]string
 
 	return messages
 }
+
+func lengthUnit(err validator.FieldError) string {
+	if err.Kind() == reflect.Slice {
+		return "items"
+	}
+
+	return "characters"
+}
@@ -69,6 +69,10 @@ func GenerateUserReturnColumns(excludedColumns []string) []string {
   			WHEN email_verified_at IS NULL THEN CAST ('false' AS BOOLEAN)
   			ELSE CAST('true' AS BOOLEAN)
 			END AS is_email_verified`,
+		`EXISTS (
+				SELECT 1 FROM verification_cases 
+				WHERE user_id = users.id AND type = 'identity' AND status = 'approved'
+			) AS is_identity_verified`,
 		`CASE 
   			WHEN phone_verified_at IS NULL THEN CAST ('false' AS BOOLEAN)
   			ELSE CAST('true' AS BOOLEAN)
@@ -77,6 +81,7 @@ func GenerateUserReturnColumns(excludedColumns []string) []string {
 		"password",
 		"phone_no",
 		"reviews_count",
+		"role",
 		"trips_count",
 	}
 
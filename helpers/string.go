@@ -3,6 +3,7 @@ package helpers
 import (
 	"crypto/rand"
 	"math/big"
+	"strings"
 )
 
 const (
@@ -29,3 +30,12 @@ func generateRandomFromSeed(length int, seed string) (string, error) {
 	}
 	return string(byteSlice), nil
 }
+
+// Joins the conditions with AND, returning an empty string when there are none
+func BuildWhereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
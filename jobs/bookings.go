@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4"
+)
+
+// Booking requests the host didn't answer before they expired free up the vehicle
+// and release the renter's authorisation and credits
+func ExpireBookingRequests(ctx context.Context) error {
+	sql := "SELECT id FROM bookings WHERE status = $1 AND expires_at <= NOW()"
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, models.BookingStatusPending)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bookingIds := []string{}
+	for rows.Next() {
+		bookingId := ""
+		if err = rows.Scan(&bookingId); err != nil {
+			return err
+		}
+
+		bookingIds = append(bookingIds, bookingId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, bookingId := range bookingIds {
+		booking, err := expireBookingRequest(ctx, bookingId)
+		if err != nil {
+			log.Printf("ExpireBookingRequests %v: %v\n", bookingId, err)
+			continue
+		}
+
+		if booking != nil {
+			notifyBookingExpired(ctx, booking)
+		}
+	}
+
+	return nil
+}
+
+// The booking is locked like it is when the host answers, so a request that is
+// accepted in the meantime is left alone
+func expireBookingRequest(ctx context.Context, bookingId string) (*models.Booking, error) {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	booking, err := models.SelectBookingForUpdate(ctx, tx, bookingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if booking.Status != models.BookingStatusPending {
+		return nil, nil
+	}
+
+	if response := models.UpdateBookingStatus(ctx, tx, booking, models.BookingStatusExpired); response != nil {
+		return nil, fmt.Errorf("%v", response.Body)
+	}
+
+	if err = models.RestoreBookingCredits(ctx, tx, booking); err != nil {
+		return nil, err
+	}
+
+	payment, err := models.SelectPaymentByBookingForUpdate(ctx, tx, bookingId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return booking, tx.Commit(ctx)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Status == services.PaymentStatusPending || payment.Status == services.PaymentStatusAuthorised {
+		intent, err := services.GetPaymentProvider().Cancel(ctx, payment.ProviderIntentID, services.PaymentIdempotencyKey(models.PaymentReasonCancel, payment.ProviderIntentID, 0))
+		if err != nil {
+			return nil, err
+		}
+
+		if err = payment.Apply(ctx, tx, intent, models.PaymentReasonCancel); err != nil {
+			return nil, err
+		}
+	}
+
+	return booking, tx.Commit(ctx)
+}
+
+func notifyBookingExpired(ctx context.Context, booking *models.Booking) {
+	event := services.Event{Type: services.EventBookingUpdated, Data: booking}
+	for _, userId := range []string{booking.UserID, booking.HostID} {
+		if err := services.PublishEvent(ctx, userId, event); err != nil {
+			log.Printf("notifyBookingExpired %v: %v\n", booking.ID, err)
+		}
+	}
+
+	user := &models.User{ID: booking.UserID}
+	options := models.SQLOptions{
+		Arguments:         []interface{}{user.ID},
+		AfterTableClauses: "WHERE id = $1",
+		Destination:       []interface{}{&user.Email, &user.Firstname, &user.Lastname},
+		ReturnColumns:     []string{"email", "firstname", "lastname"},
+	}
+	if response := models.SelectUserRow(ctx, options); response != nil {
+		log.Printf("notifyBookingExpired %v: %v\n", booking.ID, response.Body)
+		return
+	}
+
+	if err := user.SendBookingUpdatedMail(ctx, booking, "Your booking request has expired"); err != nil {
+		log.Printf("notifyBookingExpired %v: %v\n", booking.ID, err)
+	}
+}
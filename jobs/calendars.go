@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// Imports the busy periods of every external calendar as blackouts of its vehicle.
+// A calendar that can't be read keeps the blackouts of its last sync and the
+// error is recorded for the host to see
+func SyncVehicleCalendars(ctx context.Context) error {
+	pool := services.GetPostgresConnectionPool()
+	calendars, err := models.SelectVehicleCalendars(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	for _, calendar := range calendars {
+		if err = syncVehicleCalendar(ctx, calendar); err != nil {
+			log.Printf("SyncVehicleCalendars %v: %v\n", calendar.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func syncVehicleCalendar(ctx context.Context, calendar *models.VehicleCalendar) error {
+	pool := services.GetPostgresConnectionPool()
+	events, err := services.FetchCalendarEvents(ctx, calendar.URL)
+	if err != nil {
+		return calendar.RecordSyncError(ctx, pool, err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = calendar.Sync(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4"
+)
+
+// Claims the renter hasn't answered within config.ClaimResponseWindow are handed
+// to admins as if they had been disputed
+func EscalateUnansweredClaims(ctx context.Context) error {
+	sql := "UPDATE claims SET status = $1, updated_at = NOW() WHERE status = $2 AND created_at <= $3"
+	pool := services.GetPostgresConnectionPool()
+	createdBefore := time.Now().Add(-config.ClaimResponseWindow)
+	_, err := pool.Exec(ctx, sql, models.ClaimStatusDisputed, models.ClaimStatusPending, createdBefore)
+	return err
+}
+
+// Security deposits of trips that were completed more than config.ClaimWindow ago
+// without a claim are released back to the renter
+func ReleaseSecurityDeposits(ctx context.Context) error {
+	if err := EscalateUnansweredClaims(ctx); err != nil {
+		return err
+	}
+
+	sql := `
+	SELECT d.booking_id
+	FROM security_deposits AS d
+	JOIN bookings AS b ON d.booking_id = b.id
+	WHERE d.status = $1 AND b.completed_at <= $2 AND NOT EXISTS (SELECT 1 FROM claims WHERE booking_id = b.id)`
+	pool := services.GetPostgresConnectionPool()
+	completedBefore := time.Now().Add(-config.ClaimWindow)
+	rows, err := pool.Query(ctx, sql, services.PaymentStatusAuthorised, completedBefore)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bookingIds := []string{}
+	for rows.Next() {
+		bookingId := ""
+		if err = rows.Scan(&bookingId); err != nil {
+			return err
+		}
+
+		bookingIds = append(bookingIds, bookingId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, bookingId := range bookingIds {
+		if err = releaseSecurityDeposit(ctx, bookingId); err != nil {
+			log.Printf("ReleaseSecurityDeposits %v: %v\n", bookingId, err)
+		}
+	}
+
+	return nil
+}
+
+// The booking is locked like it is when a claim is filed, so a claim can't slip
+// in between the check and the release
+func releaseSecurityDeposit(ctx context.Context, bookingId string) error {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = models.SelectBookingForUpdate(ctx, tx, bookingId); err != nil {
+		return err
+	}
+
+	claimId := ""
+	err = tx.QueryRow(ctx, "SELECT id FROM claims WHERE booking_id = $1", bookingId).Scan(&claimId)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	deposit, err := models.SelectSecurityDepositByBookingForUpdate(ctx, tx, bookingId)
+	if err != nil {
+		return err
+	}
+
+	if deposit.Status != services.PaymentStatusAuthorised {
+		return nil
+	}
+
+	intent, err := services.GetPaymentProvider().Cancel(ctx, deposit.ProviderIntentID, services.PaymentIdempotencyKey(models.PaymentReasonCancel, deposit.ProviderIntentID, 0))
+	if err != nil {
+		return err
+	}
+
+	if err = deposit.Apply(ctx, tx, intent); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// Works out every host's response rate and median response time over the last 30
+// and 90 days. A booking request counts once the host has answered it or it has
+// expired, and requests the renter withdrew don't count. A renter message that
+// opens a conversation or follows a host message counts once the host or a
+// co-host has replied or config.MessageResponseWindow has passed, and only replies
+// within the window count as answered. Hosts who no longer have anything to count
+// are reset
+func UpdateHostResponseMetrics(ctx context.Context) error {
+	sql := `
+	WITH cte_messages AS (
+		SELECT m.conversation_id,
+			m.created_at,
+			m.sender_id,
+			c.host_id,
+			c.renter_id,
+			lag(m.sender_id) OVER (PARTITION BY m.conversation_id ORDER BY m.created_at) AS previous_sender_id
+		FROM messages AS m
+		JOIN conversations AS c ON m.conversation_id = c.id
+		WHERE m.created_at >= NOW() - INTERVAL '91 days'
+	), cte_requests AS (
+		SELECT v.user_id AS host_id, b.created_at AS received_at, b.responded_at
+		FROM bookings AS b
+		JOIN vehicles AS v ON b.vehicle_id = v.id
+		WHERE b.expires_at IS NOT NULL
+			AND b.created_at >= NOW() - INTERVAL '90 days'
+			AND (b.responded_at IS NOT NULL OR b.status = $1)
+		UNION ALL
+		SELECT host_id, received_at, responded_at FROM (
+			SELECT m.host_id, m.created_at AS received_at, (
+				SELECT MIN(r.created_at) FROM messages AS r
+				WHERE r.conversation_id = m.conversation_id AND r.sender_id <> m.renter_id AND r.created_at > m.created_at
+			) AS responded_at
+			FROM cte_messages AS m
+			WHERE m.sender_id = m.renter_id
+				AND m.previous_sender_id IS DISTINCT FROM m.renter_id
+				AND m.created_at >= NOW() - INTERVAL '90 days'
+		) AS m
+		WHERE responded_at IS NOT NULL OR received_at <= NOW() - $2 * INTERVAL '1 second'
+	), cte_metrics AS (
+		SELECT host_id,
+			(COUNT(*) FILTER (WHERE received_at >= NOW() - INTERVAL '30 days' AND responded_at <= received_at + $2 * INTERVAL '1 second'))::NUMERIC
+				/ NULLIF(COUNT(*) FILTER (WHERE received_at >= NOW() - INTERVAL '30 days'), 0) AS response_rate_30_days,
+			(COUNT(*) FILTER (WHERE responded_at <= received_at + $2 * INTERVAL '1 second'))::NUMERIC / COUNT(*) AS response_rate_90_days,
+			round(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM responded_at - received_at))
+				FILTER (WHERE received_at >= NOW() - INTERVAL '30 days' AND responded_at IS NOT NULL) / 60) AS response_time_30_days,
+			round(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM responded_at - received_at))
+				FILTER (WHERE responded_at IS NOT NULL) / 60) AS response_time_90_days
+		FROM cte_requests
+		GROUP BY host_id
+	)
+	UPDATE users AS u SET
+		response_metrics_updated_at = NOW(),
+		response_rate_30_days = m.response_rate_30_days,
+		response_rate_90_days = m.response_rate_90_days,
+		response_time_30_days = m.response_time_30_days,
+		response_time_90_days = m.response_time_90_days
+	FROM (
+		SELECT id AS host_id FROM users WHERE response_metrics_updated_at IS NOT NULL
+		UNION
+		SELECT host_id FROM cte_metrics
+	) AS h
+	LEFT JOIN cte_metrics AS m ON h.host_id = m.host_id
+	WHERE u.id = h.host_id`
+	pool := services.GetPostgresConnectionPool()
+	_, err := pool.Exec(ctx, sql, models.BookingStatusExpired, config.MessageResponseWindow.Seconds())
+	return err
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+type Job struct {
+	Interval time.Duration
+	Name     string
+	Run      func(ctx context.Context) error
+}
+
+var registeredJobs = []Job{
+	{Interval: time.Minute, Name: "expire_booking_requests", Run: ExpireBookingRequests},
+	{Interval: 15 * time.Minute, Name: "match_saved_searches", Run: MatchSavedSearches},
+	{Interval: time.Minute, Name: "notify_unread_messages", Run: NotifyUnreadMessages},
+	{Interval: time.Hour, Name: "release_security_deposits", Run: ReleaseSecurityDeposits},
+	{Interval: time.Hour, Name: "update_host_response_metrics", Run: UpdateHostResponseMetrics},
+	{Interval: time.Hour, Name: "run_payouts", Run: RunPayouts},
+	{Interval: 15 * time.Minute, Name: "sync_vehicle_calendars", Run: SyncVehicleCalendars},
+}
+
+// Runs every registered job on its own ticker until ctx is done
+func Start(ctx context.Context) {
+	for _, job := range registeredJobs {
+		go schedule(ctx, job)
+	}
+}
+
+func schedule(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runOnce(ctx, job)
+		}
+	}
+}
+
+// Every API instance schedules the same jobs, so a redis lock that lives for
+// one interval makes sure only one of them runs each tick
+func runOnce(ctx context.Context, job Job) {
+	redisClient := services.GetRedisClient()
+	acquired, err := redisClient.SetNX(ctx, config.RedisJobLockPrefix+job.Name, 1, job.Interval).Result()
+	if err != nil {
+		log.Printf("Job %v: %v\n", job.Name, err)
+		return
+	}
+
+	if !acquired {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+
+	if err = job.Run(ctx); err != nil {
+		log.Printf("Job %v: %v\n", job.Name, err)
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// Emails each participant who has messages that stayed unread for longer than
+// config.UnreadMessagesDelay, along with the co-hosts who handle messages for the
+// vehicle when the renter sent them. Messages are marked as notified before the
+// mail is sent so that a recipient is never emailed twice about the same message
+func NotifyUnreadMessages(ctx context.Context) error {
+	sql := `
+	WITH cte_messages AS (
+		UPDATE messages AS m SET notified_at = NOW()
+		FROM conversations AS c
+		WHERE m.conversation_id = c.id 
+			AND m.read_at IS NULL 
+			AND m.notified_at IS NULL 
+			AND m.created_at <= NOW() - $1 * INTERVAL '1 second'
+		RETURNING m.sender_id, c.host_id, c.renter_id, c.vehicle_id
+	), cte_recipients AS (
+		SELECT CASE WHEN sender_id = renter_id THEN host_id ELSE renter_id END AS recipient_id
+		FROM cte_messages
+		UNION ALL
+		SELECT vc.user_id
+		FROM cte_messages AS m
+		JOIN vehicle_cohosts AS vc ON vc.vehicle_id = m.vehicle_id
+		WHERE m.sender_id = m.renter_id AND vc.accepted_at IS NOT NULL AND $2 = ANY(vc.permissions)
+	)
+	SELECT u.id, u.email, u.firstname, u.lastname, COUNT(*)
+	FROM cte_recipients AS r
+	JOIN users AS u ON r.recipient_id = u.id
+	GROUP BY u.id`
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, config.UnreadMessagesDelay.Seconds(), models.CohostPermissionMessages)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type recipient struct {
+		user  *models.User
+		count int
+	}
+	recipients := []recipient{}
+	for rows.Next() {
+		user := &models.User{}
+		count := 0
+		if err = rows.Scan(&user.ID, &user.Email, &user.Firstname, &user.Lastname, &count); err != nil {
+			return err
+		}
+
+		recipients = append(recipients, recipient{user: user, count: count})
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err = recipient.user.SendUnreadMessagesMail(ctx, recipient.count); err != nil {
+			log.Printf("NotifyUnreadMessages %v: %v\n", recipient.user.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4"
+)
+
+// Moves every host's settled earnings into a payout and then sends the payouts
+// that are due. A payout that fails is retried after config.PayoutRetryDelay times
+// its attempts until config.PayoutMaxAttempts, and the host is told every time
+func RunPayouts(ctx context.Context) error {
+	if err := SweepHostEarnings(ctx); err != nil {
+		return err
+	}
+
+	return SendDuePayouts(ctx)
+}
+
+func SendDuePayouts(ctx context.Context) error {
+	sql := "SELECT id FROM payouts WHERE status = $1 AND next_attempt_at <= NOW() ORDER BY next_attempt_at"
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, models.PayoutStatusPending)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	payoutIds := []string{}
+	for rows.Next() {
+		payoutId := ""
+		if err = rows.Scan(&payoutId); err != nil {
+			return err
+		}
+
+		payoutIds = append(payoutIds, payoutId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, payoutId := range payoutIds {
+		if err = sendPayout(ctx, payoutId); err != nil {
+			log.Printf("SendDuePayouts %v: %v\n", payoutId, err)
+		}
+	}
+
+	return nil
+}
+
+func SweepHostEarnings(ctx context.Context) error {
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, "SELECT user_id FROM payout_accounts")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	hostIds := []string{}
+	for rows.Next() {
+		hostId := ""
+		if err = rows.Scan(&hostId); err != nil {
+			return err
+		}
+
+		hostIds = append(hostIds, hostId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	completedBefore := time.Now().Add(-config.PayoutHoldPeriod)
+	for _, hostId := range hostIds {
+		if err = sweepHostEarnings(ctx, hostId, completedBefore); err != nil {
+			log.Printf("SweepHostEarnings %v: %v\n", hostId, err)
+		}
+	}
+
+	return nil
+}
+
+func notifyPayoutFailed(ctx context.Context, payout *models.Payout) {
+	user := &models.User{ID: payout.UserID}
+	options := models.SQLOptions{
+		Arguments:         []interface{}{user.ID},
+		AfterTableClauses: "WHERE id = $1",
+		Destination:       []interface{}{&user.Email, &user.Firstname, &user.Lastname},
+		ReturnColumns:     []string{"email", "firstname", "lastname"},
+	}
+	if response := models.SelectUserRow(ctx, options); response != nil {
+		log.Printf("notifyPayoutFailed %v: %v\n", payout.ID, response.Body)
+		return
+	}
+
+	if err := user.SendPayoutFailedMail(ctx, payout); err != nil {
+		log.Printf("notifyPayoutFailed %v: %v\n", payout.ID, err)
+	}
+}
+
+// The payout id is the idempotency key, so a transfer that went through but
+// wasn't recorded is not sent twice when it is retried
+func sendPayout(ctx context.Context, payoutId string) error {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	payout := &models.Payout{ID: payoutId}
+	providerAccountId := ""
+	sql := `
+	SELECT p.amount, p.attempts, p.currency, p.status, p.user_id, a.provider_account_id
+	FROM payouts AS p
+	JOIN payout_accounts AS a ON p.payout_account_id = a.id
+	WHERE p.id = $1
+	FOR UPDATE OF p SKIP LOCKED`
+	destination := []interface{}{&payout.Amount, &payout.Attempts, &payout.Currency, &payout.Status, &payout.UserID, &providerAccountId}
+	err = tx.QueryRow(ctx, sql, payoutId).Scan(destination...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another instance is sending it
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if payout.Status != models.PayoutStatusPending {
+		return nil
+	}
+
+	provider := services.GetPayoutProvider()
+	transferId, transferErr := provider.CreateTransfer(ctx, providerAccountId, payout.Amount, payout.Currency, payout.ID)
+	payout.Attempts++
+	if transferErr == nil {
+		sql = "UPDATE payouts SET attempts = $1, failure_reason = '', paid_at = NOW(), provider_transfer_id = $2, status = $3 WHERE id = $4"
+		if _, err = tx.Exec(ctx, sql, payout.Attempts, transferId, models.PayoutStatusPaid, payout.ID); err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	}
+
+	if payout.Attempts >= config.PayoutMaxAttempts {
+		payout.Status = models.PayoutStatusFailed
+		if err = models.ReversePayout(ctx, tx, payout); err != nil {
+			return err
+		}
+	}
+
+	payout.FailureReason = transferErr.Error()
+	payout.NextAttemptAt = time.Now().Add(time.Duration(payout.Attempts) * config.PayoutRetryDelay)
+	sql = "UPDATE payouts SET attempts = $1, failure_reason = $2, next_attempt_at = $3, status = $4 WHERE id = $5"
+	arguments := []interface{}{payout.Attempts, payout.FailureReason, payout.NextAttemptAt, payout.Status, payout.ID}
+	if _, err = tx.Exec(ctx, sql, arguments...); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	notifyPayoutFailed(ctx, payout)
+	return nil
+}
+
+// Locking the payout account keeps two runs from sweeping the same earnings
+func sweepHostEarnings(ctx context.Context, hostId string, completedBefore time.Time) error {
+	pool := services.GetPostgresConnectionPool()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	payout := &models.Payout{Currency: config.Currency, UserID: hostId}
+	sql := "SELECT id FROM payout_accounts WHERE user_id = $1 FOR UPDATE"
+	if err = tx.QueryRow(ctx, sql, hostId).Scan(&payout.PayoutAccountID); err != nil {
+		return err
+	}
+
+	// Earnings returned by a failed payout wait for the host to replace their
+	// account, which queues that payout again, rather than failing once more
+	hasFailedPayouts := false
+	sql = "SELECT EXISTS (SELECT 1 FROM payouts WHERE user_id = $1 AND status = $2)"
+	if err = tx.QueryRow(ctx, sql, hostId, models.PayoutStatusFailed).Scan(&hasFailedPayouts); err != nil {
+		return err
+	}
+
+	if hasFailedPayouts {
+		return nil
+	}
+
+	payout.Amount, err = models.SelectSettledHostEarnings(ctx, tx, hostId, completedBefore)
+	if err != nil {
+		return err
+	}
+
+	if payout.Amount <= 0 {
+		return nil
+	}
+
+	if err = models.InsertPayout(ctx, tx, payout); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
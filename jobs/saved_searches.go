@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Matches the listings that changed since the last run against every saved
+// search and then tells users about their new matches. The database's clock is
+// used because it is the one that sets when listings changed
+func MatchSavedSearches(ctx context.Context) error {
+	until := time.Time{}
+	pool := services.GetPostgresConnectionPool()
+	if err := pool.QueryRow(ctx, "SELECT NOW()").Scan(&until); err != nil {
+		return err
+	}
+
+	savedSearches, err := models.SelectSavedSearchesToMatch(ctx, pool, until)
+	if err != nil {
+		return err
+	}
+
+	for _, savedSearch := range savedSearches {
+		if savedSearch.IsStale() {
+			continue
+		}
+
+		if err = savedSearch.Match(ctx, pool, until); err != nil {
+			log.Printf("MatchSavedSearches %v: %v\n", savedSearch.ID, err)
+		}
+	}
+
+	return SendSavedSearchMatches(ctx)
+}
+
+// Sends each user one mail with the matches of their instant saved searches and
+// of their daily ones that haven't been sent in the last day. Matches are marked
+// as notified before the mail is sent so that a listing is never sent twice
+func SendSavedSearchMatches(ctx context.Context) error {
+	sql := `
+	WITH cte_saved_searches AS (
+		UPDATE saved_searches AS s SET notified_at = NOW()
+		WHERE (s.frequency = $1 OR s.notified_at IS NULL OR s.notified_at <= NOW() - INTERVAL '1 day')
+			AND EXISTS (SELECT 1 FROM saved_search_matches AS m WHERE m.saved_search_id = s.id AND m.notified_at IS NULL)
+		RETURNING s.id, s.name, s.user_id
+	), cte_matches AS (
+		UPDATE saved_search_matches AS m SET notified_at = NOW()
+		FROM cte_saved_searches AS s
+		WHERE m.saved_search_id = s.id AND m.notified_at IS NULL
+		RETURNING s.name, s.user_id, m.vehicle_id
+	)
+	SELECT u.id, u.email, u.firstname, u.lastname, json_agg(jsonb_build_object(
+		'saved_search', m.name,
+		'vehicle', jsonb_build_object('id', v.id, 'image', v.image, 'name', v.name, 'rental_fee', v.rental_fee)
+	))
+	FROM cte_matches AS m
+	JOIN users AS u ON m.user_id = u.id
+	JOIN vehicles AS v ON m.vehicle_id = v.id
+	WHERE v.unlisted_at IS NULL
+	GROUP BY u.id`
+	pool := services.GetPostgresConnectionPool()
+	rows, err := pool.Query(ctx, sql, models.SavedSearchFrequencyInstant)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type recipient struct {
+		user    *models.User
+		matches []gin.H
+	}
+	recipients := []recipient{}
+	for rows.Next() {
+		user := &models.User{}
+		matches := []gin.H{}
+		if err = rows.Scan(&user.ID, &user.Email, &user.Firstname, &user.Lastname, &matches); err != nil {
+			return err
+		}
+
+		recipients = append(recipients, recipient{user: user, matches: matches})
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err = recipient.user.SendSavedSearchMail(ctx, recipient.matches); err != nil {
+			log.Printf("SendSavedSearchMatches %v: %v\n", recipient.user.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Ekenzy-101/Pentahire-API/config"
 	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
 	"github.com/Ekenzy-101/Pentahire-API/routes"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 )
@@ -18,6 +19,12 @@ func main() {
 	defer pool.Close()
 
 	services.CreateRedisClient(ctx)
+	services.CreateFileStorage()
+	services.CreatePaymentProvider()
+	services.CreatePayoutProvider()
+	services.CreateVINDecoder()
+	jobs.Start(ctx)
+
 	router := routes.SetupRouter()
 	host := "127.0.0.1"
 	if config.IsProduction {
@@ -0,0 +1,50 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS role TEXT DEFAULT 'renter' NOT NULL CHECK (role IN ('renter', 'host', 'admin', 'support')),
+  ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS suspension_reason TEXT DEFAULT '' NOT NULL;
+
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS unlisted_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS unlisting_reason TEXT DEFAULT '' NOT NULL;
+
+CREATE TABLE IF NOT EXISTS bookings (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  end_at TIMESTAMPTZ NOT NULL,
+  start_at TIMESTAMPTZ NOT NULL,
+  status TEXT DEFAULT 'pending' NOT NULL,
+  total_amount INT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  CHECK (end_at > start_at)
+);
+
+CREATE INDEX IF NOT EXISTS bookings_user_id_idx ON bookings (user_id);
+CREATE INDEX IF NOT EXISTS bookings_vehicle_id_idx ON bookings (vehicle_id);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  action TEXT NOT NULL,
+  actor_id uuid NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  metadata JSONB DEFAULT '{}' NOT NULL,
+  target_id uuid NOT NULL,
+  target_type TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_target_idx ON audit_logs (target_type, target_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS bookings;
+
+ALTER TABLE vehicles
+  DROP COLUMN IF EXISTS unlisting_reason,
+  DROP COLUMN IF EXISTS unlisted_at;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS suspension_reason,
+  DROP COLUMN IF EXISTS suspended_at,
+  DROP COLUMN IF EXISTS role;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS verification_cases (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  documents TEXT[] DEFAULT '{}' NOT NULL,
+  rejection_reason TEXT DEFAULT '' NOT NULL,
+  reviewed_at TIMESTAMPTZ,
+  reviewer_id uuid,
+  status TEXT DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+  type TEXT NOT NULL CHECK (type IN ('driver_licence', 'identity')),
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS verification_cases_pending_idx ON verification_cases (user_id, type) WHERE status = 'pending';
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS verification_cases;
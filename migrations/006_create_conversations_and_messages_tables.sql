@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS conversations (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  host_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  last_message_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  renter_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  CHECK (host_id <> renter_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS conversations_booking_id_idx ON conversations (booking_id) WHERE booking_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS conversations_vehicle_id_renter_id_idx ON conversations (vehicle_id, renter_id) WHERE booking_id IS NULL;
+CREATE INDEX IF NOT EXISTS conversations_host_id_idx ON conversations (host_id);
+CREATE INDEX IF NOT EXISTS conversations_renter_id_idx ON conversations (renter_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  body TEXT NOT NULL,
+  conversation_id uuid NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  notified_at TIMESTAMPTZ,
+  read_at TIMESTAMPTZ,
+  sender_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS messages_conversation_id_created_at_idx ON messages (conversation_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON messages (conversation_id) WHERE read_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  body TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  data JSONB DEFAULT '{}' NOT NULL,
+  read_at TIMESTAMPTZ,
+  title TEXT NOT NULL,
+  type TEXT NOT NULL,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_created_at_idx ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- A missing row means the user is on the defaults for that type
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  email BOOLEAN NOT NULL,
+  in_app BOOLEAN NOT NULL,
+  sms BOOLEAN NOT NULL,
+  type TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, type)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,46 @@
+-- Amounts are in the minor unit of the currency
+CREATE TABLE IF NOT EXISTS payments (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  amount_captured INT DEFAULT 0 NOT NULL,
+  amount_refunded INT DEFAULT 0 NOT NULL,
+  booking_id uuid NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  currency TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  provider_intent_id TEXT NOT NULL,
+  status TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  UNIQUE (provider, provider_intent_id),
+  CHECK (amount_captured BETWEEN 0 AND amount),
+  CHECK (amount_refunded BETWEEN 0 AND amount_captured)
+);
+
+CREATE TABLE IF NOT EXISTS payment_transitions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount_captured INT NOT NULL,
+  amount_refunded INT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  from_status TEXT,
+  payment_id uuid NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  to_status TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS payment_transitions_payment_id_idx ON payment_transitions (payment_id, created_at);
+
+-- Provider event ids are stored so that a redelivered webhook is only processed once
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+  id TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  payload JSONB NOT NULL,
+  provider TEXT NOT NULL,
+  type TEXT NOT NULL,
+  PRIMARY KEY (provider, id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS payment_webhook_events;
+DROP TABLE IF EXISTS payment_transitions;
+DROP TABLE IF EXISTS payments;
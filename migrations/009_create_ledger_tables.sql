@@ -0,0 +1,79 @@
+-- Host and renter accounts belong to a user, the others are the platform's own
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('deposits', 'host', 'payment_provider', 'platform_revenue', 'renter', 'tax')),
+  user_id uuid REFERENCES users (id) ON DELETE CASCADE,
+  CHECK ((type IN ('host', 'renter')) = (user_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_accounts_type_user_id_idx ON ledger_accounts (type, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_accounts_type_idx ON ledger_accounts (type) WHERE user_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  description TEXT DEFAULT '' NOT NULL,
+  reference_id uuid NOT NULL,
+  reference_type TEXT NOT NULL,
+  type TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS ledger_transactions_reference_idx ON ledger_transactions (reference_type, reference_id);
+
+-- Amounts are in the minor unit of the currency. Debits are positive and credits negative
+CREATE TABLE IF NOT EXISTS ledger_entries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  account_id uuid NOT NULL REFERENCES ledger_accounts (id) ON DELETE CASCADE,
+  amount BIGINT NOT NULL CHECK (amount <> 0),
+  transaction_id uuid NOT NULL REFERENCES ledger_transactions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_account_id_idx ON ledger_entries (account_id);
+CREATE INDEX IF NOT EXISTS ledger_entries_transaction_id_idx ON ledger_entries (transaction_id);
+
+-- Checked when the inserting transaction commits, once all of its entries exist
+CREATE OR REPLACE FUNCTION check_ledger_transaction_balanced() RETURNS TRIGGER AS $$
+DECLARE
+  entries_count INT;
+  total BIGINT;
+BEGIN
+  SELECT COUNT(*), COALESCE(SUM(amount), 0) INTO entries_count, total
+  FROM ledger_entries
+  WHERE transaction_id = NEW.transaction_id;
+
+  IF entries_count < 2 OR total <> 0 THEN
+    RAISE EXCEPTION 'ledger transaction % is unbalanced', NEW.transaction_id;
+  END IF;
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+  AFTER INSERT ON ledger_entries
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION check_ledger_transaction_balanced();
+
+-- Mistakes are corrected with a reversing transaction, never by editing history
+CREATE OR REPLACE FUNCTION prevent_ledger_update() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'ledger rows can not be updated';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_immutable
+  BEFORE UPDATE ON ledger_entries
+  FOR EACH ROW EXECUTE FUNCTION prevent_ledger_update();
+
+CREATE TRIGGER ledger_transactions_immutable
+  BEFORE UPDATE ON ledger_transactions
+  FOR EACH ROW EXECUTE FUNCTION prevent_ledger_update();
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
+DROP TABLE IF EXISTS ledger_accounts;
+DROP FUNCTION IF EXISTS prevent_ledger_update;
+DROP FUNCTION IF EXISTS check_ledger_transaction_balanced;
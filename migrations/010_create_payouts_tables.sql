@@ -0,0 +1,40 @@
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS payout_accounts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  account_name TEXT NOT NULL,
+  account_number_last4 TEXT NOT NULL,
+  bank_code TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  provider TEXT NOT NULL,
+  provider_account_id TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  user_id uuid NOT NULL UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+  verified_at TIMESTAMPTZ DEFAULT NOW() NOT NULL
+);
+
+-- Amounts are in the minor unit of the currency
+CREATE TABLE IF NOT EXISTS payouts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  attempts INT DEFAULT 0 NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  currency TEXT NOT NULL,
+  failure_reason TEXT DEFAULT '' NOT NULL,
+  next_attempt_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  paid_at TIMESTAMPTZ,
+  payout_account_id uuid NOT NULL REFERENCES payout_accounts (id) ON DELETE CASCADE,
+  provider_transfer_id TEXT,
+  status TEXT DEFAULT 'pending' NOT NULL CHECK (status IN ('failed', 'paid', 'pending')),
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS payouts_user_id_idx ON payouts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS payouts_pending_idx ON payouts (next_attempt_at) WHERE status = 'pending';
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS payouts;
+DROP TABLE IF EXISTS payout_accounts;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS completed_at;
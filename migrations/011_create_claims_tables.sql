@@ -0,0 +1,52 @@
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS security_deposit INT DEFAULT 0 NOT NULL CHECK (security_deposit >= 0);
+
+-- Copied from the vehicle when the booking is made so that later changes don't apply to it
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS security_deposit INT DEFAULT 0 NOT NULL;
+
+-- Kept so that the deposit can be held on the renter's card when the trip starts
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS payment_method_id TEXT DEFAULT '' NOT NULL;
+
+-- Amounts are in the minor unit of the currency
+CREATE TABLE IF NOT EXISTS security_deposits (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  amount_captured INT DEFAULT 0 NOT NULL,
+  booking_id uuid NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  currency TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  provider_intent_id TEXT NOT NULL,
+  status TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  UNIQUE (provider, provider_intent_id),
+  CHECK (amount_captured BETWEEN 0 AND amount)
+);
+
+CREATE TABLE IF NOT EXISTS claims (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  booking_id uuid NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  description TEXT NOT NULL,
+  dispute_reason TEXT DEFAULT '' NOT NULL,
+  photos TEXT[] NOT NULL,
+  resolution_reason TEXT DEFAULT '' NOT NULL,
+  resolved_at TIMESTAMPTZ,
+  resolved_by uuid REFERENCES users (id) ON DELETE SET NULL,
+  settled_amount INT,
+  status TEXT DEFAULT 'pending' NOT NULL CHECK (status IN ('accepted', 'disputed', 'pending', 'resolved')),
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  CHECK (settled_amount BETWEEN 0 AND amount)
+);
+
+CREATE INDEX IF NOT EXISTS claims_status_idx ON claims (status, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS claims;
+DROP TABLE IF EXISTS security_deposits;
+
+ALTER TABLE payments DROP COLUMN IF EXISTS payment_method_id;
+ALTER TABLE bookings DROP COLUMN IF EXISTS security_deposit;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS security_deposit;
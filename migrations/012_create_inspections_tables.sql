@@ -0,0 +1,87 @@
+-- A vehicle without a daily mileage limit has unlimited mileage. Fees are in the
+-- minor unit of the currency, per kilometre over the limit and per percent of fuel
+-- or charge that is missing at check-out
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS daily_mileage_limit INT CHECK (daily_mileage_limit > 0),
+  ADD COLUMN IF NOT EXISTS fuel_fee INT DEFAULT 0 NOT NULL CHECK (fuel_fee >= 0),
+  ADD COLUMN IF NOT EXISTS mileage_fee INT DEFAULT 0 NOT NULL CHECK (mileage_fee >= 0);
+
+-- Copied from the vehicle when the booking is made so that later changes don't apply to it
+ALTER TABLE bookings
+  ADD COLUMN IF NOT EXISTS daily_mileage_limit INT,
+  ADD COLUMN IF NOT EXISTS fuel_fee INT DEFAULT 0 NOT NULL,
+  ADD COLUMN IF NOT EXISTS mileage_fee INT DEFAULT 0 NOT NULL;
+
+-- Photos are stored in the order front, back, left, right. Distance and the fees
+-- are only worked out for the check-out, against the check-in
+CREATE TABLE IF NOT EXISTS inspections (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  distance INT DEFAULT 0 NOT NULL,
+  fuel_difference INT DEFAULT 0 NOT NULL,
+  fuel_fee INT DEFAULT 0 NOT NULL,
+  fuel_level INT NOT NULL CHECK (fuel_level BETWEEN 0 AND 100),
+  host_acknowledged_at TIMESTAMPTZ,
+  mileage_fee INT DEFAULT 0 NOT NULL,
+  odometer INT NOT NULL CHECK (odometer >= 0),
+  photos TEXT[] NOT NULL,
+  renter_acknowledged_at TIMESTAMPTZ,
+  type TEXT NOT NULL CHECK (type IN ('check_in', 'check_out')),
+  user_id uuid REFERENCES users (id) ON DELETE SET NULL,
+  UNIQUE (booking_id, type)
+);
+
+-- Inspections are evidence, so the only change allowed is a party acknowledging one
+CREATE OR REPLACE FUNCTION prevent_inspection_update() RETURNS TRIGGER AS $$
+BEGIN
+  IF (OLD.host_acknowledged_at IS NOT NULL AND NEW.host_acknowledged_at IS DISTINCT FROM OLD.host_acknowledged_at)
+    OR (OLD.renter_acknowledged_at IS NOT NULL AND NEW.renter_acknowledged_at IS DISTINCT FROM OLD.renter_acknowledged_at)
+    OR (to_jsonb(NEW) - 'host_acknowledged_at' - 'renter_acknowledged_at') <> (to_jsonb(OLD) - 'host_acknowledged_at' - 'renter_acknowledged_at')
+  THEN
+    RAISE EXCEPTION 'inspections are immutable';
+  END IF;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER inspections_immutable
+  BEFORE UPDATE ON inspections
+  FOR EACH ROW EXECUTE FUNCTION prevent_inspection_update();
+
+-- Charges made after the trip on top of the rental, e.g. for mileage and fuel.
+-- Amounts are in the minor unit of the currency
+CREATE TABLE IF NOT EXISTS booking_charges (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  booking_id uuid NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  currency TEXT NOT NULL,
+  description TEXT NOT NULL,
+  failure_reason TEXT DEFAULT '' NOT NULL,
+  provider TEXT NOT NULL,
+  provider_intent_id TEXT NOT NULL,
+  status TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('overage')),
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  UNIQUE (booking_id, type),
+  UNIQUE (provider, provider_intent_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS booking_charges;
+DROP TRIGGER IF EXISTS inspections_immutable ON inspections;
+DROP FUNCTION IF EXISTS prevent_inspection_update();
+DROP TABLE IF EXISTS inspections;
+
+ALTER TABLE bookings
+  DROP COLUMN IF EXISTS daily_mileage_limit,
+  DROP COLUMN IF EXISTS fuel_fee,
+  DROP COLUMN IF EXISTS mileage_fee;
+
+ALTER TABLE vehicles
+  DROP COLUMN IF EXISTS daily_mileage_limit,
+  DROP COLUMN IF EXISTS fuel_fee,
+  DROP COLUMN IF EXISTS mileage_fee;
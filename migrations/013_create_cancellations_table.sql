@@ -0,0 +1,28 @@
+ALTER TABLE vehicles 
+  ADD COLUMN IF NOT EXISTS cancellation_policy TEXT DEFAULT 'flexible' NOT NULL 
+  CHECK (cancellation_policy IN ('flexible', 'moderate', 'strict'));
+
+-- Copied from the vehicle when the booking is made so that later changes don't apply to it
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancellation_policy TEXT DEFAULT 'flexible' NOT NULL;
+
+-- What each party got out of a cancelled booking. Host cancellations are kept so
+-- that hosts who cancel often can be penalised. Amounts are in the minor unit of
+-- the currency
+CREATE TABLE IF NOT EXISTS cancellations (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+  cancelled_by TEXT NOT NULL CHECK (cancelled_by IN ('host', 'renter')),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  host_compensation INT NOT NULL CHECK (host_compensation >= 0),
+  policy TEXT NOT NULL,
+  refund_amount INT NOT NULL CHECK (refund_amount >= 0),
+  user_id uuid REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS cancellations_host_idx ON cancellations (user_id, created_at) WHERE cancelled_by = 'host';
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS cancellations;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancellation_policy;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS cancellation_policy;
@@ -0,0 +1,23 @@
+-- Renters who meet every requirement of an instant book vehicle have their booking
+-- confirmed straight away. A requirement of 0 isn't checked
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS instant_book BOOLEAN DEFAULT FALSE NOT NULL,
+  ADD COLUMN IF NOT EXISTS instant_book_min_account_age_days INT DEFAULT 0 NOT NULL CHECK (instant_book_min_account_age_days >= 0),
+  ADD COLUMN IF NOT EXISTS instant_book_min_rating NUMERIC(2,1) DEFAULT 0.0 NOT NULL CHECK (instant_book_min_rating BETWEEN 0 AND 5),
+  ADD COLUMN IF NOT EXISTS instant_book_min_trips INT DEFAULT 0 NOT NULL CHECK (instant_book_min_trips >= 0);
+
+-- Booking requests the host hasn't answered by expires_at are expired
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS bookings_expires_at_idx ON bookings (expires_at) WHERE status = 'pending';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS bookings_expires_at_idx;
+ALTER TABLE bookings DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE vehicles
+  DROP COLUMN IF EXISTS instant_book,
+  DROP COLUMN IF EXISTS instant_book_min_account_age_days,
+  DROP COLUMN IF EXISTS instant_book_min_rating,
+  DROP COLUMN IF EXISTS instant_book_min_trips;
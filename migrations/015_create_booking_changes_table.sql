@@ -0,0 +1,38 @@
+-- A renter's request to move or extend their trip. total_amount is the booking's
+-- new total, priced when the request is made. Amounts are in the minor unit of
+-- the currency
+CREATE TABLE IF NOT EXISTS booking_changes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  end_at TIMESTAMPTZ NOT NULL,
+  responded_at TIMESTAMPTZ,
+  start_at TIMESTAMPTZ NOT NULL,
+  status TEXT DEFAULT 'pending' NOT NULL CHECK (status IN ('approved', 'cancelled', 'declined', 'pending')),
+  total_amount INT NOT NULL CHECK (total_amount > 0),
+  updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  user_id uuid REFERENCES users (id) ON DELETE SET NULL,
+  CHECK (end_at > start_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS booking_changes_pending_idx ON booking_changes (booking_id) WHERE status = 'pending';
+
+-- A booking can be changed more than once, so only overage charges stay one per booking
+ALTER TABLE booking_charges 
+  DROP CONSTRAINT IF EXISTS booking_charges_booking_id_type_key,
+  DROP CONSTRAINT IF EXISTS booking_charges_type_check,
+  ADD CONSTRAINT booking_charges_type_check CHECK (type IN ('modification', 'overage'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS booking_charges_overage_idx ON booking_charges (booking_id) WHERE type = 'overage';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS booking_charges_overage_idx;
+DELETE FROM booking_charges WHERE type = 'modification';
+
+ALTER TABLE booking_charges 
+  DROP CONSTRAINT IF EXISTS booking_charges_type_check,
+  ADD CONSTRAINT booking_charges_type_check CHECK (type IN ('overage')),
+  ADD CONSTRAINT booking_charges_booking_id_type_key UNIQUE (booking_id, type);
+
+DROP TABLE IF EXISTS booking_changes;
@@ -0,0 +1,72 @@
+-- Percentage discounts are whole percents of the trip's rental, fixed discounts are
+-- in the minor unit of the currency. A null max_redemptions has no global limit
+CREATE TABLE IF NOT EXISTS promo_codes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  code TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  discount_type TEXT NOT NULL CHECK (discount_type IN ('fixed', 'percentage')),
+  discount_value INT NOT NULL CHECK (discount_value > 0),
+  expires_at TIMESTAMPTZ,
+  first_trip_only BOOLEAN DEFAULT FALSE NOT NULL,
+  max_redemptions INT CHECK (max_redemptions > 0),
+  max_redemptions_per_user INT DEFAULT 1 NOT NULL CHECK (max_redemptions_per_user > 0),
+  min_trip_days INT DEFAULT 1 NOT NULL CHECK (min_trip_days > 0),
+  user_id uuid REFERENCES users (id) ON DELETE SET NULL,
+  CHECK (discount_type = 'fixed' OR discount_value <= 100)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  discount_amount INT NOT NULL CHECK (discount_amount > 0),
+  promo_code_id uuid NOT NULL REFERENCES promo_codes (id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS promo_redemptions_promo_code_id_user_id_idx ON promo_redemptions (promo_code_id, user_id);
+
+-- Every user gets a code to refer others with. Existing users get one as well
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS referral_code TEXT DEFAULT upper(substr(md5(random()::text), 1, 8)) NOT NULL UNIQUE,
+  ADD COLUMN IF NOT EXISTS referral_rewarded_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS referred_by uuid REFERENCES users (id) ON DELETE SET NULL;
+
+-- A user's credit balance is the sum of their entries. Spending credits is a
+-- negative entry for the booking they were spent on
+CREATE TABLE IF NOT EXISTS credit_entries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount <> 0),
+  booking_id uuid REFERENCES bookings (id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  description TEXT DEFAULT '' NOT NULL,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS credit_entries_user_id_idx ON credit_entries (user_id, created_at);
+
+-- total_amount stays what the renter pays, these are what was taken off it
+ALTER TABLE bookings
+  ADD COLUMN IF NOT EXISTS credit_amount INT DEFAULT 0 NOT NULL CHECK (credit_amount >= 0),
+  ADD COLUMN IF NOT EXISTS discount_amount INT DEFAULT 0 NOT NULL CHECK (discount_amount >= 0);
+
+-- The platform pays hosts for the discounts and credits it gives renters
+ALTER TABLE ledger_accounts
+  DROP CONSTRAINT IF EXISTS ledger_accounts_type_check,
+  ADD CONSTRAINT ledger_accounts_type_check
+  CHECK (type IN ('deposits', 'host', 'payment_provider', 'platform_revenue', 'promotions', 'renter', 'tax'));
+
+---- create above / drop below ----
+
+DELETE FROM ledger_accounts WHERE type = 'promotions';
+
+ALTER TABLE ledger_accounts
+  DROP CONSTRAINT IF EXISTS ledger_accounts_type_check,
+  ADD CONSTRAINT ledger_accounts_type_check
+  CHECK (type IN ('deposits', 'host', 'payment_provider', 'platform_revenue', 'renter', 'tax'));
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS credit_amount, DROP COLUMN IF EXISTS discount_amount;
+DROP TABLE IF EXISTS credit_entries;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code, DROP COLUMN IF EXISTS referral_rewarded_at, DROP COLUMN IF EXISTS referred_by;
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
@@ -0,0 +1,47 @@
+-- Listings made before these existed have them unset until their host fills them
+-- in. ev_range is in kilometres and only applies to electric vehicles
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS category TEXT
+  CHECK (category IN ('compact', 'convertible', 'luxury', 'minivan', 'pickup', 'sedan', 'suv', 'van')),
+  ADD COLUMN IF NOT EXISTS doors INT CHECK (doors BETWEEN 2 AND 6),
+  ADD COLUMN IF NOT EXISTS ev_range INT CHECK (ev_range > 0),
+  ADD COLUMN IF NOT EXISTS features TEXT[] DEFAULT '{}' NOT NULL
+  CHECK (features <@ ARRAY[
+    'air_conditioning', 'all_wheel_drive', 'android_auto', 'apple_carplay', 'bluetooth',
+    'child_seat', 'gps', 'heated_seats', 'sunroof', 'usb_charger'
+  ]),
+  ADD COLUMN IF NOT EXISTS fuel_type TEXT CHECK (fuel_type IN ('diesel', 'electric', 'hybrid', 'petrol')),
+  ADD COLUMN IF NOT EXISTS model TEXT,
+  ADD COLUMN IF NOT EXISTS seats INT CHECK (seats BETWEEN 1 AND 15),
+  ADD COLUMN IF NOT EXISTS transmission TEXT CHECK (transmission IN ('automatic', 'manual')),
+  ADD COLUMN IF NOT EXISTS year INT CHECK (year >= 1950),
+  ADD CONSTRAINT vehicles_ev_range_check CHECK (ev_range IS NULL OR fuel_type = 'electric');
+
+CREATE INDEX IF NOT EXISTS vehicles_category_idx ON vehicles (category);
+CREATE INDEX IF NOT EXISTS vehicles_features_idx ON vehicles USING GIN (features);
+
+-- Great-circle distance between two points stored as POINT(latitude, longitude)
+CREATE OR REPLACE FUNCTION distance_in_km(a POINT, b POINT) RETURNS DOUBLE PRECISION AS $$
+  SELECT 2 * 6371 * asin(least(1, sqrt(
+    sin(radians(b[0] - a[0]) / 2) ^ 2 +
+    cos(radians(a[0])) * cos(radians(b[0])) * sin(radians(b[1] - a[1]) / 2) ^ 2
+  )))
+$$ LANGUAGE SQL IMMUTABLE STRICT;
+
+---- create above / drop below ----
+
+DROP FUNCTION IF EXISTS distance_in_km(POINT, POINT);
+DROP INDEX IF EXISTS vehicles_features_idx;
+DROP INDEX IF EXISTS vehicles_category_idx;
+
+ALTER TABLE vehicles
+  DROP CONSTRAINT IF EXISTS vehicles_ev_range_check,
+  DROP COLUMN IF EXISTS category,
+  DROP COLUMN IF EXISTS doors,
+  DROP COLUMN IF EXISTS ev_range,
+  DROP COLUMN IF EXISTS features,
+  DROP COLUMN IF EXISTS fuel_type,
+  DROP COLUMN IF EXISTS model,
+  DROP COLUMN IF EXISTS seats,
+  DROP COLUMN IF EXISTS transmission,
+  DROP COLUMN IF EXISTS year;
@@ -0,0 +1,16 @@
+-- A VIN can only be on one listed vehicle at a time, so a host can relist a car
+-- after unlisting it but two hosts can't list the same car. Listings made before
+-- this have no VIN
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS manufacturer TEXT DEFAULT '' NOT NULL,
+  ADD COLUMN IF NOT EXISTS vin TEXT CHECK (vin ~ '^[A-HJ-NPR-Z0-9]{17}$');
+
+CREATE UNIQUE INDEX IF NOT EXISTS vehicles_vin_key ON vehicles (vin) WHERE unlisted_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS vehicles_vin_key;
+
+ALTER TABLE vehicles
+  DROP COLUMN IF EXISTS manufacturer,
+  DROP COLUMN IF EXISTS vin;
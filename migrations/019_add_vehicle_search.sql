@@ -0,0 +1,28 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The 'simple' configuration is used so that makes and models aren't stemmed.
+-- search_text backs the trigram matching that tolerates typos
+ALTER TABLE vehicles
+  ADD COLUMN IF NOT EXISTS description TEXT DEFAULT '' NOT NULL,
+  ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    lower(make || ' ' || coalesce(model, '') || ' ' || name || ' ' || coalesce(category, ''))
+  ) STORED,
+  ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', make || ' ' || coalesce(model, '')), 'A') ||
+    setweight(to_tsvector('simple', name || ' ' || coalesce(category, '')), 'B') ||
+    setweight(to_tsvector('simple', description), 'C') ||
+    setweight(to_tsvector('simple', address), 'D')
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS vehicles_search_text_idx ON vehicles USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS vehicles_search_vector_idx ON vehicles USING GIN (search_vector);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS vehicles_search_vector_idx;
+DROP INDEX IF EXISTS vehicles_search_text_idx;
+
+ALTER TABLE vehicles
+  DROP COLUMN IF EXISTS search_vector,
+  DROP COLUMN IF EXISTS search_text,
+  DROP COLUMN IF EXISTS description;
@@ -0,0 +1,50 @@
+-- Any change to a listing makes it worth matching against saved searches again
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW() NOT NULL;
+
+CREATE INDEX IF NOT EXISTS vehicles_updated_at_idx ON vehicles (updated_at);
+
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER vehicles_updated_at
+  BEFORE UPDATE ON vehicles
+  FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- filters holds the same filters as a vehicle search. Listings changed after
+-- matched_at haven't been matched against the search yet
+CREATE TABLE IF NOT EXISTS saved_searches (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  filters JSONB DEFAULT '{}' NOT NULL,
+  frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'instant')),
+  matched_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  name TEXT NOT NULL,
+  notified_at TIMESTAMPTZ,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id);
+
+-- A listing is only ever sent once for each saved search
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  notified_at TIMESTAMPTZ,
+  saved_search_id uuid NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  PRIMARY KEY (saved_search_id, vehicle_id)
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_matches_unnotified_idx ON saved_search_matches (saved_search_id) WHERE notified_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS saved_search_matches;
+DROP TABLE IF EXISTS saved_searches;
+DROP TRIGGER IF EXISTS vehicles_updated_at ON vehicles;
+DROP FUNCTION IF EXISTS set_updated_at;
+DROP INDEX IF EXISTS vehicles_updated_at_idx;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,24 @@
+-- When the host accepted or declined a booking request. Requests the renter
+-- withdrew before then have none
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS responded_at TIMESTAMPTZ;
+
+-- Rolling metrics the host response metrics job keeps up to date. Rates are the
+-- share of booking requests and messages answered in time and response times are
+-- median minutes. They're null when the host had nothing to answer in the period
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS response_metrics_updated_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS response_rate_30_days NUMERIC(4,3) CHECK (response_rate_30_days BETWEEN 0 AND 1),
+  ADD COLUMN IF NOT EXISTS response_rate_90_days NUMERIC(4,3) CHECK (response_rate_90_days BETWEEN 0 AND 1),
+  ADD COLUMN IF NOT EXISTS response_time_30_days INT CHECK (response_time_30_days >= 0),
+  ADD COLUMN IF NOT EXISTS response_time_90_days INT CHECK (response_time_90_days >= 0);
+
+---- create above / drop below ----
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS response_metrics_updated_at,
+  DROP COLUMN IF EXISTS response_rate_30_days,
+  DROP COLUMN IF EXISTS response_rate_90_days,
+  DROP COLUMN IF EXISTS response_time_30_days,
+  DROP COLUMN IF EXISTS response_time_90_days;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS responded_at;
@@ -0,0 +1,18 @@
+-- A co-host acts for the vehicle's owner with the permissions they were given
+-- once they have accepted the invitation
+CREATE TABLE IF NOT EXISTS vehicle_cohosts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  accepted_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  invited_by uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  permissions TEXT[] DEFAULT '{}' NOT NULL CHECK (permissions <@ ARRAY['calendar', 'check_in', 'earnings', 'messages']),
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  UNIQUE (vehicle_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_cohosts_user_id_idx ON vehicle_cohosts (user_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS vehicle_cohosts;
@@ -0,0 +1,11 @@
+-- The id a fleet host's own systems know the vehicle by, which bulk imports match
+-- on before the VIN
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS external_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS vehicles_user_id_external_id_key ON vehicles (user_id, external_id) WHERE external_id IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS vehicles_user_id_external_id_key;
+
+ALTER TABLE vehicles DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,37 @@
+-- The secret that lets other platforms read the vehicle's calendar feed without
+-- logging in. It's created the first time the host asks for the feed
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS calendar_token TEXT UNIQUE;
+
+-- External iCalendar feeds the calendar sync job imports busy periods from
+CREATE TABLE IF NOT EXISTS vehicle_calendars (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  name VARCHAR(100) DEFAULT '' NOT NULL,
+  sync_error TEXT,
+  synced_at TIMESTAMPTZ,
+  url TEXT NOT NULL,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  UNIQUE (vehicle_id, url)
+);
+
+-- Periods the vehicle can't be booked for. Blackouts with a calendar were imported
+-- from it and are replaced every time it's synced, the rest were added by the host
+CREATE TABLE IF NOT EXISTS vehicle_blackouts (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  calendar_id uuid REFERENCES vehicle_calendars (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  end_at TIMESTAMPTZ NOT NULL,
+  start_at TIMESTAMPTZ NOT NULL,
+  summary TEXT DEFAULT '' NOT NULL,
+  vehicle_id uuid NOT NULL REFERENCES vehicles (id) ON DELETE CASCADE,
+  CHECK (end_at > start_at)
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_blackouts_vehicle_id_end_at_idx ON vehicle_blackouts (vehicle_id, end_at);
+CREATE INDEX IF NOT EXISTS vehicle_blackouts_calendar_id_idx ON vehicle_blackouts (calendar_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS vehicle_blackouts;
+DROP TABLE IF EXISTS vehicle_calendars;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS calendar_token;
@@ -0,0 +1,22 @@
+-- Invoice numbers run without gaps within a year. The counter row is locked until
+-- the invoice that took the number is saved or rolled back
+CREATE TABLE IF NOT EXISTS invoice_counters (
+  year INT PRIMARY KEY,
+  last_number INT NOT NULL CHECK (last_number > 0)
+);
+
+-- A booking is invoiced once, when it's confirmed. The PDF lives in file storage
+CREATE TABLE IF NOT EXISTS invoices (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  booking_id uuid UNIQUE NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  currency TEXT NOT NULL,
+  file_key TEXT NOT NULL,
+  number TEXT UNIQUE NOT NULL,
+  total_amount INT NOT NULL
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_counters;
@@ -0,0 +1,53 @@
+-- A jurisdiction's taxes on rentals. A rule applies to a vehicle when one of the
+-- comma separated parts of its address is address_keyword, ignoring case, and
+-- its location is within the bounds, each only when set, so a rule with neither
+-- applies everywhere. Rates are in basis points and surcharges in the minor unit
+-- of the currency per day
+CREATE TABLE IF NOT EXISTS tax_rules (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  address_keyword TEXT,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  jurisdiction VARCHAR(100) NOT NULL,
+  max_latitude DOUBLE PRECISION,
+  max_longitude DOUBLE PRECISION,
+  min_latitude DOUBLE PRECISION,
+  min_longitude DOUBLE PRECISION,
+  per_day_surcharge INT DEFAULT 0 NOT NULL CHECK (per_day_surcharge >= 0),
+  rental_tax_basis_points INT DEFAULT 0 NOT NULL CHECK (rental_tax_basis_points BETWEEN 0 AND 10000),
+  vat_basis_points INT DEFAULT 0 NOT NULL CHECK (vat_basis_points BETWEEN 0 AND 10000),
+  CHECK ((min_latitude IS NULL) = (max_latitude IS NULL) AND (min_latitude IS NULL) = (min_longitude IS NULL) AND (min_latitude IS NULL) = (max_longitude IS NULL)),
+  CHECK (min_latitude <= max_latitude AND min_longitude <= max_longitude),
+  CHECK (per_day_surcharge > 0 OR rental_tax_basis_points > 0 OR vat_basis_points > 0)
+);
+
+-- The taxes a booking was charged. Lines without a charge are the trip's and are
+-- replaced when its dates change, the rest were charged with the booking charge
+CREATE TABLE IF NOT EXISTS booking_tax_lines (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  amount INT NOT NULL CHECK (amount > 0),
+  basis_points INT DEFAULT 0 NOT NULL,
+  booking_id uuid NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+  charge_id uuid REFERENCES booking_charges (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+  description TEXT NOT NULL,
+  jurisdiction VARCHAR(100) NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('rental_tax', 'surcharge', 'vat'))
+);
+
+CREATE INDEX IF NOT EXISTS booking_tax_lines_booking_id_idx ON booking_tax_lines (booking_id);
+
+-- total_amount includes tax_amount. A change keeps the lines it was priced with
+-- so that the booking gets the same ones when it's approved
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS tax_amount INT DEFAULT 0 NOT NULL CHECK (tax_amount >= 0);
+ALTER TABLE booking_charges ADD COLUMN IF NOT EXISTS tax_amount INT DEFAULT 0 NOT NULL CHECK (tax_amount >= 0);
+ALTER TABLE booking_changes
+  ADD COLUMN IF NOT EXISTS tax_amount INT DEFAULT 0 NOT NULL CHECK (tax_amount >= 0),
+  ADD COLUMN IF NOT EXISTS tax_lines JSONB DEFAULT '[]' NOT NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE booking_changes DROP COLUMN IF EXISTS tax_lines, DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE booking_charges DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE bookings DROP COLUMN IF EXISTS tax_amount;
+DROP TABLE IF EXISTS booking_tax_lines;
+DROP TABLE IF EXISTS tax_rules;
@@ -0,0 +1,16 @@
+-- Failed overage charges are kept for the record, so only a charge that went
+-- through is one per booking and a failed one can be retried
+DROP INDEX IF EXISTS booking_charges_overage_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS booking_charges_overage_idx ON booking_charges (booking_id) 
+  WHERE type = 'overage' AND failure_reason = '';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS booking_charges_overage_idx;
+DELETE FROM booking_charges AS c 
+WHERE c.type = 'overage' AND EXISTS (
+  SELECT 1 FROM booking_charges 
+  WHERE booking_id = c.booking_id AND type = 'overage' AND (failure_reason = '' OR created_at > c.created_at)
+  AND id <> c.id
+);
+CREATE UNIQUE INDEX IF NOT EXISTS booking_charges_overage_idx ON booking_charges (booking_id) WHERE type = 'overage';
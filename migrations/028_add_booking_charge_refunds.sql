@@ -0,0 +1,7 @@
+-- Charges are refunded when the booking they were made for is cancelled or refunded
+ALTER TABLE booking_charges 
+  ADD COLUMN IF NOT EXISTS amount_refunded INT DEFAULT 0 NOT NULL CHECK (amount_refunded BETWEEN 0 AND amount);
+
+---- create above / drop below ----
+
+ALTER TABLE booking_charges DROP COLUMN IF EXISTS amount_refunded;
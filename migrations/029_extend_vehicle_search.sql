@@ -0,0 +1,23 @@
+-- Typos in the description and address of a vehicle are tolerated like in its
+-- name. Autocomplete matches on the make and model, so those get their own indexes
+DROP INDEX IF EXISTS vehicles_search_text_idx;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS search_text;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+  lower(make || ' ' || coalesce(model, '') || ' ' || name || ' ' || coalesce(category, '') || ' ' || description || ' ' || address)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS vehicles_search_text_idx ON vehicles USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS vehicles_make_model_idx ON vehicles USING GIN ((lower(make || ' ' || coalesce(model, ''))) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS vehicles_model_idx ON vehicles USING GIN ((lower(model)) gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS vehicles_model_idx;
+DROP INDEX IF EXISTS vehicles_make_model_idx;
+DROP INDEX IF EXISTS vehicles_search_text_idx;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS search_text;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+  lower(make || ' ' || coalesce(model, '') || ' ' || name || ' ' || coalesce(category, ''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS vehicles_search_text_idx ON vehicles USING GIN (search_text gin_trgm_ops);
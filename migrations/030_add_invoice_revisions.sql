@@ -0,0 +1,16 @@
+-- A booking gets a new invoice with the next revision, and its own number, each
+-- time what the renter paid for it changes. The latest revision replaces the ones
+-- before it
+ALTER TABLE invoices
+  DROP CONSTRAINT IF EXISTS invoices_booking_id_key,
+  ADD COLUMN IF NOT EXISTS revision INT DEFAULT 1 NOT NULL CHECK (revision > 0),
+  ADD CONSTRAINT invoices_booking_id_revision_key UNIQUE (booking_id, revision);
+
+---- create above / drop below ----
+
+DELETE FROM invoices WHERE revision > 1;
+
+ALTER TABLE invoices
+  DROP CONSTRAINT IF EXISTS invoices_booking_id_revision_key,
+  DROP COLUMN IF EXISTS revision,
+  ADD CONSTRAINT invoices_booking_id_key UNIQUE (booking_id);
@@ -0,0 +1,20 @@
+-- The tax in the payment's amount. What it captures and refunds carries its own
+-- share of that tax, so the tax charged with later booking charges isn't counted
+-- again. Existing payments hold the trip's tax less what those charges took
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS tax_amount INT DEFAULT 0 NOT NULL;
+
+UPDATE payments AS p SET tax_amount = LEAST(p.amount, GREATEST(0, b.tax_amount - (
+  SELECT COALESCE(SUM(c.tax_amount), 0)
+  FROM booking_charges AS c
+  WHERE c.booking_id = b.id AND c.type = 'modification' AND c.failure_reason = ''
+)))
+FROM bookings AS b
+WHERE b.id = p.booking_id;
+
+ALTER TABLE payments
+  DROP CONSTRAINT IF EXISTS payments_tax_amount_check,
+  ADD CONSTRAINT payments_tax_amount_check CHECK (tax_amount BETWEEN 0 AND amount);
+
+---- create above / drop below ----
+
+ALTER TABLE payments DROP COLUMN IF EXISTS tax_amount;
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	AuditActionApproveVerification      = "verification.approve"
+	AuditActionCreatePromoCode          = "promo_code.create"
+	AuditActionCreateTaxRule            = "tax_rule.create"
+	AuditActionDeleteTaxRule            = "tax_rule.delete"
+	AuditActionRefundBooking            = "booking.refund"
+	AuditActionRejectVerification       = "verification.reject"
+	AuditActionResolveClaim             = "claim.resolve"
+	AuditActionRetryOverageCharge       = "booking.retry_overage_charge"
+	AuditActionRelistVehicle            = "vehicle.relist"
+	AuditActionSuspendUser              = "user.suspend"
+	AuditActionUnlistVehicle            = "vehicle.unlist"
+	AuditActionUnsuspendUser            = "user.unsuspend"
+	AuditActionUpdateUserRole           = "user.update_role"
+	AuditActionViewVerificationDocument = "verification.view_document"
+
+	AuditTargetBooking          = "booking"
+	AuditTargetClaim            = "claim"
+	AuditTargetPromoCode        = "promo_code"
+	AuditTargetTaxRule          = "tax_rule"
+	AuditTargetUser             = "user"
+	AuditTargetVehicle          = "vehicle"
+	AuditTargetVerificationCase = "verification_case"
+)
+
+type AuditLog struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	ActorID    string    `json:"actor_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Metadata   gin.H     `json:"metadata"`
+	TargetID   string    `json:"target_id"`
+	TargetType string    `json:"target_type"`
+}
+
+func (auditLog *AuditLog) Insert(ctx context.Context, querier Querier) error {
+	if auditLog.Metadata == nil {
+		auditLog.Metadata = gin.H{}
+	}
+
+	options := SQLOptions{
+		Arguments:     []interface{}{auditLog.Action, auditLog.ActorID, auditLog.Metadata, auditLog.TargetID, auditLog.TargetType},
+		InsertColumns: []string{"action", "actor_id", "metadata", "target_id", "target_type"},
+		ReturnColumns: []string{"id", "created_at"},
+		Statement:     InsertStatement,
+		TableName:     config.AuditLogsTable,
+	}
+	sql := buildQuery(options)
+	return querier.QueryRow(ctx, sql, options.Arguments...).Scan(&auditLog.ID, &auditLog.CreatedAt)
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	BookingStatusCancelled  = "cancelled"
+	BookingStatusCompleted  = "completed"
+	BookingStatusConfirmed  = "confirmed"
+	BookingStatusDeclined   = "declined"
+	BookingStatusExpired    = "expired"
+	BookingStatusInProgress = "in_progress"
+	BookingStatusPending    = "pending"
+)
+
+// Bookings in these statuses hold the vehicle for their dates
+var BlockingBookingStatuses = []string{BookingStatusPending, BookingStatusConfirmed, BookingStatusInProgress}
+
+type Booking struct {
+	ID                 string     `json:"id"`
+	CancellationPolicy string     `json:"cancellation_policy"`
+	CompletedAt        *time.Time `json:"completed_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	CreditAmount       int        `json:"credit_amount"`
+	DailyMileageLimit  *int       `json:"daily_mileage_limit"`
+	DiscountAmount     int        `json:"discount_amount"`
+	EndAt              time.Time  `json:"end_at"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	FuelFee            int        `json:"fuel_fee"`
+	HostID             string     `json:"host_id"`
+	MileageFee         int        `json:"mileage_fee"`
+	RespondedAt        *time.Time `json:"responded_at"`
+	SecurityDeposit    int        `json:"security_deposit"`
+	StartAt            time.Time  `json:"start_at"`
+	Status             string     `json:"status"`
+	TaxAmount          int        `json:"tax_amount"`
+	TotalAmount        int        `json:"total_amount"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	UserID             string     `json:"user_id"`
+	VehicleID          string     `json:"vehicle_id"`
+}
+
+// Every started 24 hours is charged as a full day
+func CalculateBookingDays(startAt, endAt time.Time) int {
+	return int(math.Ceil(endAt.Sub(startAt).Hours() / 24))
+}
+
+// Takes the booking's discount and credits off subtotal, e.g. when the booking is
+// priced again for new dates
+func (booking *Booking) ApplyDiscounts(subtotal int) int {
+	total := subtotal - booking.DiscountAmount - booking.CreditAmount
+	if total < config.MinimumPaymentAmount {
+		total = config.MinimumPaymentAmount
+	}
+
+	return total
+}
+
+func (booking *Booking) IsParticipant(userId string) bool {
+	return booking.UserID == userId || booking.HostID == userId
+}
+
+// Whether the user can act on the host's side of the booking, either as the host
+// or as a co-host of the vehicle with the permission
+func (booking *Booking) IsManagedBy(ctx context.Context, querier Querier, userId string, permission string) (bool, error) {
+	if booking.HostID == userId {
+		return true, nil
+	}
+
+	return IsVehicleManager(ctx, querier, booking.VehicleID, userId, permission)
+}
+
+// Bookings in excludedBookingIds are left out, e.g. the booking whose dates are being changed
+func HasOverlappingBooking(ctx context.Context, querier Querier, vehicleId string, startAt, endAt time.Time, excludedBookingIds ...string) (bool, error) {
+	overlaps := false
+	sql := `SELECT EXISTS (
+		SELECT 1 FROM bookings
+		WHERE vehicle_id = $1 AND status = ANY($2) AND start_at < $4 AND end_at > $3 AND id <> ALL($5::uuid[])
+	)`
+	arguments := []interface{}{vehicleId, BlockingBookingStatuses, startAt, endAt, append([]string{}, excludedBookingIds...)}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(&overlaps)
+	return overlaps, err
+}
+
+func InsertBooking(ctx context.Context, querier Querier, booking *Booking) error {
+	sql := `
+	INSERT INTO bookings (
+		cancellation_policy, credit_amount, daily_mileage_limit, discount_amount, end_at, expires_at, fuel_fee, mileage_fee, security_deposit, 
+		start_at, status, tax_amount, total_amount, user_id, vehicle_id
+	) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) 
+	RETURNING id, created_at, updated_at`
+	arguments := []interface{}{
+		booking.CancellationPolicy,
+		booking.CreditAmount,
+		booking.DailyMileageLimit,
+		booking.DiscountAmount,
+		booking.EndAt,
+		booking.ExpiresAt,
+		booking.FuelFee,
+		booking.MileageFee,
+		booking.SecurityDeposit,
+		booking.StartAt,
+		booking.Status,
+		booking.TaxAmount,
+		booking.TotalAmount,
+		booking.UserID,
+		booking.VehicleID,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&booking.ID, &booking.CreatedAt, &booking.UpdatedAt)
+}
+
+func SelectBooking(ctx context.Context, querier Querier, bookingId string) (*Booking, error) {
+	return selectBooking(ctx, querier, bookingId, "")
+}
+
+// Locks the booking row so that concurrent status changes are applied one after the other
+func SelectBookingForUpdate(ctx context.Context, querier Querier, bookingId string) (*Booking, error) {
+	return selectBooking(ctx, querier, bookingId, "FOR UPDATE OF b")
+}
+
+func selectBooking(ctx context.Context, querier Querier, bookingId string, lockingClause string) (*Booking, error) {
+	sql := `
+	SELECT b.id, b.cancellation_policy, b.completed_at, b.created_at, b.credit_amount, b.daily_mileage_limit, b.discount_amount, b.end_at, b.expires_at,
+		b.fuel_fee, v.user_id, b.mileage_fee, b.responded_at, b.security_deposit, b.start_at, b.status, b.tax_amount,
+		b.total_amount, b.updated_at, b.user_id, b.vehicle_id
+	FROM bookings AS b
+	JOIN vehicles AS v ON b.vehicle_id = v.id
+	WHERE b.id = $1 ` + lockingClause
+	booking := &Booking{}
+	destination := []interface{}{
+		&booking.ID,
+		&booking.CancellationPolicy,
+		&booking.CompletedAt,
+		&booking.CreatedAt,
+		&booking.CreditAmount,
+		&booking.DailyMileageLimit,
+		&booking.DiscountAmount,
+		&booking.EndAt,
+		&booking.ExpiresAt,
+		&booking.FuelFee,
+		&booking.HostID,
+		&booking.MileageFee,
+		&booking.RespondedAt,
+		&booking.SecurityDeposit,
+		&booking.StartAt,
+		&booking.Status,
+		&booking.TaxAmount,
+		&booking.TotalAmount,
+		&booking.UpdatedAt,
+		&booking.UserID,
+		&booking.VehicleID,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId).Scan(destination...)
+	return booking, err
+}
+
+// Completing a booking also stamps completed_at, which starts the payout hold
+// period. A pending booking that is confirmed or declined stamps responded_at
+func UpdateBookingStatus(ctx context.Context, querier Querier, booking *Booking, status string) *SQLResponse {
+	sql := `
+	UPDATE bookings SET 
+		completed_at = CASE WHEN $1 = 'completed' THEN NOW() ELSE completed_at END, 
+		responded_at = CASE WHEN status = 'pending' AND $1 IN ('confirmed', 'declined') THEN NOW() ELSE responded_at END, 
+		status = $1, 
+		updated_at = NOW() 
+	WHERE id = $2 
+	RETURNING completed_at, responded_at, status, updated_at`
+	destination := []interface{}{&booking.CompletedAt, &booking.RespondedAt, &booking.Status, &booking.UpdatedAt}
+	err := querier.QueryRow(ctx, sql, status, booking.ID).Scan(destination...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &SQLResponse{
+			StatusCode: http.StatusNotFound,
+			Body:       gin.H{"message": "Booking not found"},
+		}
+	}
+
+	if err != nil {
+		return &SQLResponse{
+			StatusCode: http.StatusInternalServerError,
+			Body:       gin.H{"message": err.Error()},
+		}
+	}
+
+	return nil
+}
+
+// Moves the booking to the dates of an approved change, along with the taxes it
+// was priced with
+func UpdateBookingDates(ctx context.Context, querier Querier, booking *Booking, change *BookingChange) error {
+	sql := `
+	UPDATE bookings SET end_at = $1, start_at = $2, tax_amount = $3, total_amount = $4, updated_at = NOW()
+	WHERE id = $5
+	RETURNING end_at, start_at, tax_amount, total_amount, updated_at`
+	arguments := []interface{}{change.EndAt, change.StartAt, change.TaxAmount, change.TotalAmount, booking.ID}
+	destination := []interface{}{&booking.EndAt, &booking.StartAt, &booking.TaxAmount, &booking.TotalAmount, &booking.UpdatedAt}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(destination...); err != nil {
+		return err
+	}
+
+	return InsertBookingTaxLines(ctx, querier, booking.ID, nil, change.TaxLines)
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BookingChangeStatusApproved  = "approved"
+	BookingChangeStatusCancelled = "cancelled"
+	BookingChangeStatusDeclined  = "declined"
+	BookingChangeStatusPending   = "pending"
+)
+
+// A renter's request to move or extend their trip. TotalAmount is what the booking
+// costs with the new dates, in the minor unit of the currency, and includes the
+// taxes in TaxLines
+type BookingChange struct {
+	ID          string     `json:"id"`
+	BookingID   string     `json:"booking_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	EndAt       time.Time  `json:"end_at"`
+	RespondedAt *time.Time `json:"responded_at"`
+	StartAt     time.Time  `json:"start_at"`
+	Status      string     `json:"status"`
+	TaxAmount   int        `json:"tax_amount"`
+	TaxLines    []TaxLine  `json:"tax_lines"`
+	TotalAmount int        `json:"total_amount"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UserID      *string    `json:"user_id"`
+}
+
+// Prices the booking for the change's dates. The booking keeps its discount and
+// credits while the taxes are worked out again with rules, which should be the
+// ones the booking was priced with
+func (change *BookingChange) Price(booking *Booking, rentalFee int, rules []TaxRule) {
+	days := CalculateBookingDays(change.StartAt, change.EndAt)
+	subtotal := days * rentalFee
+	change.TaxLines = CalculateTaxLines(rules, subtotal-booking.DiscountAmount, days)
+	change.TaxAmount = SumTaxLines(change.TaxLines)
+	change.TotalAmount = booking.ApplyDiscounts(subtotal + change.TaxAmount)
+}
+
+// What the renter pays on top of what they already paid, or gets back when negative
+func (change *BookingChange) PriceDifference(booking *Booking) int {
+	return change.TotalAmount - booking.TotalAmount
+}
+
+// Records the answer to a pending change. Cancelling is the renter withdrawing it,
+// which isn't a response
+func (change *BookingChange) UpdateStatus(ctx context.Context, querier Querier, status string) error {
+	sql := `
+	UPDATE booking_changes SET 
+		responded_at = CASE WHEN $1 = 'cancelled' THEN responded_at ELSE NOW() END,
+		status = $1, 
+		updated_at = NOW()
+	WHERE id = $2
+	RETURNING responded_at, status, updated_at`
+	return querier.QueryRow(ctx, sql, status, change.ID).Scan(&change.RespondedAt, &change.Status, &change.UpdatedAt)
+}
+
+func InsertBookingChange(ctx context.Context, querier Querier, change *BookingChange) error {
+	sql := `
+	INSERT INTO booking_changes (booking_id, end_at, start_at, status, tax_amount, tax_lines, total_amount, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at`
+	arguments := []interface{}{
+		change.BookingID,
+		change.EndAt,
+		change.StartAt,
+		change.Status,
+		change.TaxAmount,
+		change.TaxLines,
+		change.TotalAmount,
+		change.UserID,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&change.ID, &change.CreatedAt, &change.UpdatedAt)
+}
+
+// Locks the change so that it is answered once. Changes are always looked up
+// through their booking
+func SelectBookingChangeForUpdate(ctx context.Context, querier Querier, bookingId string, changeId string) (*BookingChange, error) {
+	sql := `
+	SELECT id, booking_id, created_at, end_at, responded_at, start_at, status, tax_amount, tax_lines, total_amount, updated_at, user_id
+	FROM booking_changes
+	WHERE booking_id = $1 AND id = $2
+	FOR UPDATE`
+	change := &BookingChange{}
+	destination := []interface{}{
+		&change.ID,
+		&change.BookingID,
+		&change.CreatedAt,
+		&change.EndAt,
+		&change.RespondedAt,
+		&change.StartAt,
+		&change.Status,
+		&change.TaxAmount,
+		&change.TaxLines,
+		&change.TotalAmount,
+		&change.UpdatedAt,
+		&change.UserID,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId, changeId).Scan(destination...)
+	return change, err
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+const (
+	BookingChargeTypeModification = "modification"
+	BookingChargeTypeOverage      = "overage"
+)
+
+// A charge made after the trip on top of the rental. Charges are captured straight
+// away, a charge that couldn't be is kept with its failure reason so that an admin
+// can retry it. Amounts are in the minor unit of the currency and Amount includes
+// TaxAmount. Captured charges are refunded along with the booking they were made for
+type BookingCharge struct {
+	ID               string    `json:"id"`
+	Amount           int       `json:"amount"`
+	AmountRefunded   int       `json:"amount_refunded"`
+	BookingID        string    `json:"booking_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	Currency         string    `json:"currency"`
+	Description      string    `json:"description"`
+	FailureReason    string    `json:"failure_reason"`
+	Provider         string    `json:"provider"`
+	ProviderIntentID string    `json:"-"`
+	Status           string    `json:"status"`
+	TaxAmount        int       `json:"tax_amount"`
+	Type             string    `json:"type"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Captured charges are split between the host and the platform like the rental,
+// after their taxes
+func InsertBookingCharge(ctx context.Context, querier Querier, charge *BookingCharge) error {
+	sql := `
+	INSERT INTO booking_charges (
+		amount, booking_id, currency, description, failure_reason, provider, provider_intent_id, status, tax_amount, type
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, created_at, updated_at`
+	arguments := []interface{}{
+		charge.Amount,
+		charge.BookingID,
+		charge.Currency,
+		charge.Description,
+		charge.FailureReason,
+		charge.Provider,
+		charge.ProviderIntentID,
+		charge.Status,
+		charge.TaxAmount,
+		charge.Type,
+	}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(&charge.ID, &charge.CreatedAt, &charge.UpdatedAt); err != nil {
+		return err
+	}
+
+	if charge.Status != services.PaymentStatusCaptured {
+		return nil
+	}
+
+	hostId, renterId, err := selectBookingParticipants(ctx, querier, charge.BookingID)
+	if err != nil {
+		return err
+	}
+
+	transaction := newChargeTransaction(hostId, renterId, charge.Amount, charge.TaxAmount, charge.Description, charge.ID, LedgerReferenceBookingCharge)
+	return InsertLedgerTransaction(ctx, querier, transaction)
+}
+
+// Selects the latest failed charge of chargeType, unless another one has gone
+// through since. attempts counts every charge of that type on the booking
+func SelectRetryableBookingCharge(ctx context.Context, querier Querier, bookingId string, chargeType string) (*BookingCharge, int, error) {
+	sql := `
+	SELECT id, amount, created_at, currency, description, failure_reason, provider, status, tax_amount, type, updated_at,
+		(SELECT COUNT(*) FROM booking_charges WHERE booking_id = $1 AND type = $2)
+	FROM booking_charges
+	WHERE booking_id = $1 AND type = $2 AND failure_reason <> '' AND NOT EXISTS (
+		SELECT 1 FROM booking_charges WHERE booking_id = $1 AND type = $2 AND failure_reason = ''
+	)
+	ORDER BY created_at DESC
+	LIMIT 1`
+	charge, attempts := &BookingCharge{BookingID: bookingId}, 0
+	destination := []interface{}{
+		&charge.ID,
+		&charge.Amount,
+		&charge.CreatedAt,
+		&charge.Currency,
+		&charge.Description,
+		&charge.FailureReason,
+		&charge.Provider,
+		&charge.Status,
+		&charge.TaxAmount,
+		&charge.Type,
+		&charge.UpdatedAt,
+		&attempts,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId, chargeType).Scan(destination...)
+	return charge, attempts, err
+}
+
+// Selects and locks the captured charges of the booking that haven't been refunded
+// in full, oldest first
+func SelectRefundableBookingChargesForUpdate(ctx context.Context, querier Querier, bookingId string) ([]*BookingCharge, error) {
+	sql := `
+	SELECT id, amount, amount_refunded, created_at, currency, description, failure_reason, provider, provider_intent_id, status, 
+		tax_amount, type, updated_at
+	FROM booking_charges
+	WHERE booking_id = $1 AND status IN ($2, $3)
+	ORDER BY created_at
+	FOR UPDATE`
+	rows, err := querier.Query(ctx, sql, bookingId, services.PaymentStatusCaptured, services.PaymentStatusPartiallyRefunded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charges := []*BookingCharge{}
+	for rows.Next() {
+		charge := &BookingCharge{BookingID: bookingId}
+		destination := []interface{}{
+			&charge.ID,
+			&charge.Amount,
+			&charge.AmountRefunded,
+			&charge.CreatedAt,
+			&charge.Currency,
+			&charge.Description,
+			&charge.FailureReason,
+			&charge.Provider,
+			&charge.ProviderIntentID,
+			&charge.Status,
+			&charge.TaxAmount,
+			&charge.Type,
+			&charge.UpdatedAt,
+		}
+		if err = rows.Scan(destination...); err != nil {
+			return nil, err
+		}
+
+		charges = append(charges, charge)
+	}
+
+	return charges, rows.Err()
+}
+
+// The part of the charge that can still be refunded
+func (charge *BookingCharge) Refundable() int {
+	return charge.Amount - charge.AmountRefunded
+}
+
+// Stores a refund the provider made on the charge and posts it to the ledger with
+// the charge's share of taxes
+func (charge *BookingCharge) ApplyRefund(ctx context.Context, querier Querier, intent *services.PaymentIntent) error {
+	refundedAmount := intent.AmountRefunded - charge.AmountRefunded
+	if refundedAmount <= 0 {
+		return nil
+	}
+
+	sql := `
+	UPDATE booking_charges SET amount_refunded = $1, status = $2, updated_at = NOW() 
+	WHERE id = $3 
+	RETURNING updated_at`
+	if err := querier.QueryRow(ctx, sql, intent.AmountRefunded, intent.Status, charge.ID).Scan(&charge.UpdatedAt); err != nil {
+		return err
+	}
+
+	charge.AmountRefunded = intent.AmountRefunded
+	charge.Status = intent.Status
+	hostId, renterId, err := selectBookingParticipants(ctx, querier, charge.BookingID)
+	if err != nil {
+		return err
+	}
+
+	tax := calculateTaxShare(refundedAmount, charge.TaxAmount, charge.Amount)
+	description := fmt.Sprintf("Refund of charge for booking %v", charge.BookingID)
+	transaction := newRefundTransaction(hostId, renterId, refundedAmount, tax, description, charge.ID, LedgerReferenceBookingCharge)
+	return InsertLedgerTransaction(ctx, querier, transaction)
+}
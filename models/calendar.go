@@ -0,0 +1,155 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// A period the host has taken the vehicle off the market for. Blackouts with a
+// CalendarID were imported from that calendar and are replaced when it's synced
+type Blackout struct {
+	ID         string    `json:"id"`
+	CalendarID *string   `json:"calendar_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	EndAt      time.Time `json:"end_at"`
+	StartAt    time.Time `json:"start_at"`
+	Summary    string    `json:"summary"`
+	VehicleID  string    `json:"vehicle_id"`
+}
+
+// An iCalendar feed of another platform the vehicle is listed on. SyncError
+// holds why the last sync failed and is cleared by the next one that succeeds
+type VehicleCalendar struct {
+	ID        string     `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Name      string     `json:"name"`
+	SyncError *string    `json:"sync_error"`
+	SyncedAt  *time.Time `json:"synced_at"`
+	URL       string     `json:"url"`
+	VehicleID string     `json:"vehicle_id"`
+}
+
+// Replaces the calendar's blackouts with the events that haven't ended yet. The
+// blackouts the calendar had are kept when the sync fails
+func (calendar *VehicleCalendar) Sync(ctx context.Context, querier Querier, events []services.CalendarEvent) error {
+	if _, err := querier.Exec(ctx, "DELETE FROM vehicle_blackouts WHERE calendar_id = $1", calendar.ID); err != nil {
+		return err
+	}
+
+	endAts, startAts, summaries := []time.Time{}, []time.Time{}, []string{}
+	for _, event := range events {
+		endAts = append(endAts, event.EndAt)
+		startAts = append(startAts, event.StartAt)
+		summaries = append(summaries, event.Summary)
+	}
+
+	sql := `
+	INSERT INTO vehicle_blackouts (calendar_id, end_at, start_at, summary, vehicle_id)
+	SELECT $1, e.end_at, e.start_at, e.summary, $5
+	FROM unnest($2::timestamptz[], $3::timestamptz[], $4::text[]) AS e (end_at, start_at, summary)
+	WHERE e.end_at > NOW()`
+	if _, err := querier.Exec(ctx, sql, calendar.ID, endAts, startAts, summaries, calendar.VehicleID); err != nil {
+		return err
+	}
+
+	sql = "UPDATE vehicle_calendars SET sync_error = NULL, synced_at = NOW() WHERE id = $1 RETURNING sync_error, synced_at"
+	return querier.QueryRow(ctx, sql, calendar.ID).Scan(&calendar.SyncError, &calendar.SyncedAt)
+}
+
+func (calendar *VehicleCalendar) RecordSyncError(ctx context.Context, querier Querier, syncErr error) error {
+	message := syncErr.Error()
+	calendar.SyncError = &message
+	_, err := querier.Exec(ctx, "UPDATE vehicle_calendars SET sync_error = $1 WHERE id = $2", message, calendar.ID)
+	return err
+}
+
+func HasOverlappingBlackout(ctx context.Context, querier Querier, vehicleId string, startAt, endAt time.Time) (bool, error) {
+	overlaps := false
+	sql := `SELECT EXISTS (
+		SELECT 1 FROM vehicle_blackouts WHERE vehicle_id = $1 AND start_at < $3 AND end_at > $2
+	)`
+	err := querier.QueryRow(ctx, sql, vehicleId, startAt, endAt).Scan(&overlaps)
+	return overlaps, err
+}
+
+func InsertBlackout(ctx context.Context, querier Querier, blackout *Blackout) error {
+	sql := `
+	INSERT INTO vehicle_blackouts (end_at, start_at, summary, vehicle_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+	arguments := []interface{}{blackout.EndAt, blackout.StartAt, blackout.Summary, blackout.VehicleID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&blackout.ID, &blackout.CreatedAt)
+}
+
+func InsertVehicleCalendar(ctx context.Context, querier Querier, calendar *VehicleCalendar) error {
+	sql := `
+	INSERT INTO vehicle_calendars (name, url, vehicle_id)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at`
+	arguments := []interface{}{calendar.Name, calendar.URL, calendar.VehicleID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&calendar.ID, &calendar.CreatedAt)
+}
+
+// Returns what makes the vehicle busy from a month ago onwards, as the events of
+// its calendar feed. Only the times are shared, not who booked or why
+func SelectVehicleCalendarEvents(ctx context.Context, querier Querier, vehicleId string) ([]services.CalendarEvent, error) {
+	sql := `
+	SELECT 'booking-' || id || '@pentahire', 'Booked', start_at, end_at FROM bookings
+	WHERE vehicle_id = $1 AND status = ANY($2) AND end_at > NOW() - INTERVAL '30 days'
+	UNION ALL
+	SELECT 'blackout-' || id || '@pentahire', 'Not available', start_at, end_at FROM vehicle_blackouts
+	WHERE vehicle_id = $1 AND end_at > NOW() - INTERVAL '30 days'
+	ORDER BY 3`
+	rows, err := querier.Query(ctx, sql, vehicleId, BlockingBookingStatuses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []services.CalendarEvent{}
+	for rows.Next() {
+		event := services.CalendarEvent{}
+		if err = rows.Scan(&event.UID, &event.Summary, &event.StartAt, &event.EndAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func SelectVehicleCalendars(ctx context.Context, querier Querier) ([]*VehicleCalendar, error) {
+	sql := `
+	SELECT id, created_at, name, sync_error, synced_at, url, vehicle_id
+	FROM vehicle_calendars
+	ORDER BY synced_at NULLS FIRST`
+	rows, err := querier.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calendars := []*VehicleCalendar{}
+	for rows.Next() {
+		calendar := &VehicleCalendar{}
+		destination := []interface{}{
+			&calendar.ID,
+			&calendar.CreatedAt,
+			&calendar.Name,
+			&calendar.SyncError,
+			&calendar.SyncedAt,
+			&calendar.URL,
+			&calendar.VehicleID,
+		}
+		if err = rows.Scan(destination...); err != nil {
+			return nil, err
+		}
+
+		calendars = append(calendars, calendar)
+	}
+
+	return calendars, rows.Err()
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CancellationPolicyFlexible = "flexible"
+	CancellationPolicyModerate = "moderate"
+	CancellationPolicyStrict   = "strict"
+
+	CancelledByHost   = "host"
+	CancelledByRenter = "renter"
+)
+
+// A renter cancelling at least Notice before the trip starts is refunded
+type cancellationTier struct {
+	Notice            time.Duration
+	RefundBasisPoints int
+}
+
+// Tiers are checked in order and the last one applies to anything later,
+// including trips that should already have started
+var cancellationPolicies = map[string][]cancellationTier{
+	CancellationPolicyFlexible: {
+		{Notice: 24 * time.Hour, RefundBasisPoints: 10000},
+		{RefundBasisPoints: 5000},
+	},
+	CancellationPolicyModerate: {
+		{Notice: 5 * 24 * time.Hour, RefundBasisPoints: 10000},
+		{Notice: 24 * time.Hour, RefundBasisPoints: 5000},
+		{RefundBasisPoints: 0},
+	},
+	CancellationPolicyStrict: {
+		{Notice: 7 * 24 * time.Hour, RefundBasisPoints: 10000},
+		{Notice: 48 * time.Hour, RefundBasisPoints: 5000},
+		{RefundBasisPoints: 0},
+	},
+}
+
+// What each party gets out of a cancelled booking. The host is compensated with
+// whatever the renter isn't refunded less the platform fee. Amounts are in the
+// minor unit of the currency
+type Cancellation struct {
+	ID               string    `json:"id"`
+	BookingID        string    `json:"booking_id"`
+	CancelledBy      string    `json:"cancelled_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	HostCompensation int       `json:"host_compensation"`
+	Policy           string    `json:"policy"`
+	RefundAmount     int       `json:"refund_amount"`
+	UserID           *string   `json:"user_id"`
+}
+
+// Works out the cancellation of the booking by userId at the given time. Renters
+// get everything back when the host cancels or hasn't accepted the booking yet,
+// otherwise the booking's policy decides
+func CalculateCancellation(booking *Booking, userId string, at time.Time) *Cancellation {
+	cancellation := &Cancellation{
+		BookingID:    booking.ID,
+		CancelledBy:  CancelledByRenter,
+		Policy:       booking.CancellationPolicy,
+		RefundAmount: booking.TotalAmount,
+		UserID:       &userId,
+	}
+	if booking.HostID == userId {
+		cancellation.CancelledBy = CancelledByHost
+		return cancellation
+	}
+
+	if booking.Status == BookingStatusPending {
+		return cancellation
+	}
+
+	tiers := cancellationPolicies[booking.CancellationPolicy]
+	notice := booking.StartAt.Sub(at)
+	tier := tiers[len(tiers)-1]
+	for _, t := range tiers {
+		if notice >= t.Notice {
+			tier = t
+			break
+		}
+	}
+
+	cancellation.RefundAmount = booking.TotalAmount * tier.RefundBasisPoints / 10000
+	chargeAmount := cancellation.ChargeAmount(booking)
+	cancellation.HostCompensation = chargeAmount - CalculatePlatformFee(chargeAmount)
+	return cancellation
+}
+
+// The part of the booking's total that the renter still pays
+func (cancellation *Cancellation) ChargeAmount(booking *Booking) int {
+	return booking.TotalAmount - cancellation.RefundAmount
+}
+
+func InsertCancellation(ctx context.Context, querier Querier, cancellation *Cancellation) error {
+	sql := `
+	INSERT INTO cancellations (booking_id, cancelled_by, host_compensation, policy, refund_amount, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+	arguments := []interface{}{
+		cancellation.BookingID,
+		cancellation.CancelledBy,
+		cancellation.HostCompensation,
+		cancellation.Policy,
+		cancellation.RefundAmount,
+		cancellation.UserID,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&cancellation.ID, &cancellation.CreatedAt)
+}
+
+func SelectCancellation(ctx context.Context, querier Querier, bookingId string) (*Cancellation, error) {
+	sql := `
+	SELECT id, booking_id, cancelled_by, created_at, host_compensation, policy, refund_amount, user_id
+	FROM cancellations
+	WHERE booking_id = $1`
+	cancellation := &Cancellation{}
+	destination := []interface{}{
+		&cancellation.ID,
+		&cancellation.BookingID,
+		&cancellation.CancelledBy,
+		&cancellation.CreatedAt,
+		&cancellation.HostCompensation,
+		&cancellation.Policy,
+		&cancellation.RefundAmount,
+		&cancellation.UserID,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId).Scan(destination...)
+	return cancellation, err
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ClaimStatusAccepted = "accepted"
+	ClaimStatusDisputed = "disputed"
+	ClaimStatusPending  = "pending"
+	ClaimStatusResolved = "resolved"
+)
+
+// A damage claim filed by the host after a trip. SettledAmount is what was
+// finally captured from the security deposit and is only set once the claim
+// is accepted or resolved
+type Claim struct {
+	ID               string     `json:"id"`
+	Amount           int        `json:"amount"`
+	BookingID        string     `json:"booking_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Description      string     `json:"description"`
+	DisputeReason    string     `json:"dispute_reason"`
+	Photos           []string   `json:"-"`
+	PhotosCount      int        `json:"photos_count"`
+	ResolutionReason string     `json:"resolution_reason"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+	ResolvedBy       *string    `json:"resolved_by"`
+	SettledAmount    *int       `json:"settled_amount"`
+	Status           string     `json:"status"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	UserID           string     `json:"user_id"`
+}
+
+func InsertClaim(ctx context.Context, querier Querier, claim *Claim) error {
+	sql := `
+	INSERT INTO claims (id, amount, booking_id, description, photos, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING created_at, status, updated_at`
+	arguments := []interface{}{claim.ID, claim.Amount, claim.BookingID, claim.Description, claim.Photos, claim.UserID}
+	claim.PhotosCount = len(claim.Photos)
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&claim.CreatedAt, &claim.Status, &claim.UpdatedAt)
+}
+
+func SelectClaim(ctx context.Context, querier Querier, claimId string) (*Claim, error) {
+	return selectClaim(ctx, querier, claimId, "")
+}
+
+// Locks the claim so that the renter's response and an admin's resolution are
+// applied one after the other
+func SelectClaimForUpdate(ctx context.Context, querier Querier, claimId string) (*Claim, error) {
+	return selectClaim(ctx, querier, claimId, "FOR UPDATE")
+}
+
+// Settles the claim for settledAmount. resolvedBy and reason are only given when
+// an admin resolves a dispute
+func (claim *Claim) Settle(ctx context.Context, querier Querier, status string, settledAmount int, resolvedBy *string, reason string) error {
+	sql := `
+	UPDATE claims SET
+		resolution_reason = $1,
+		resolved_at = CASE WHEN $2 = 'resolved' THEN NOW() END,
+		resolved_by = $3,
+		settled_amount = $4,
+		status = $2,
+		updated_at = NOW()
+	WHERE id = $5
+	RETURNING resolution_reason, resolved_at, resolved_by, settled_amount, status, updated_at`
+	arguments := []interface{}{reason, status, resolvedBy, settledAmount, claim.ID}
+	destination := []interface{}{&claim.ResolutionReason, &claim.ResolvedAt, &claim.ResolvedBy, &claim.SettledAmount, &claim.Status, &claim.UpdatedAt}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(destination...)
+}
+
+func (claim *Claim) Dispute(ctx context.Context, querier Querier, reason string) error {
+	sql := `
+	UPDATE claims SET dispute_reason = $1, status = $2, updated_at = NOW()
+	WHERE id = $3
+	RETURNING dispute_reason, status, updated_at`
+	return querier.QueryRow(ctx, sql, reason, ClaimStatusDisputed, claim.ID).Scan(&claim.DisputeReason, &claim.Status, &claim.UpdatedAt)
+}
+
+func selectClaim(ctx context.Context, querier Querier, claimId string, lockingClause string) (*Claim, error) {
+	sql := `
+	SELECT id, amount, booking_id, created_at, description, dispute_reason, photos, resolution_reason,
+		resolved_at, resolved_by, settled_amount, status, updated_at, user_id
+	FROM claims
+	WHERE id = $1 ` + lockingClause
+	claim := &Claim{}
+	destination := []interface{}{
+		&claim.ID,
+		&claim.Amount,
+		&claim.BookingID,
+		&claim.CreatedAt,
+		&claim.Description,
+		&claim.DisputeReason,
+		&claim.Photos,
+		&claim.ResolutionReason,
+		&claim.ResolvedAt,
+		&claim.ResolvedBy,
+		&claim.SettledAmount,
+		&claim.Status,
+		&claim.UpdatedAt,
+		&claim.UserID,
+	}
+	err := querier.QueryRow(ctx, sql, claimId).Scan(destination...)
+	claim.PhotosCount = len(claim.Photos)
+	return claim, err
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CohostPermissionCalendar = "calendar"
+	CohostPermissionCheckIn  = "check_in"
+	CohostPermissionEarnings = "earnings"
+	CohostPermissionMessages = "messages"
+)
+
+// A user the owner of a vehicle invited to help host it. The permissions only
+// apply once the invitation has been accepted
+type Cohost struct {
+	ID          string     `json:"id"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	InvitedBy   string     `json:"invited_by"`
+	Permissions []string   `json:"permissions"`
+	UserID      string     `json:"user_id"`
+	VehicleID   string     `json:"vehicle_id"`
+}
+
+func (cohost *Cohost) IsAccepted() bool {
+	return cohost.AcceptedAt != nil
+}
+
+func (cohost *Cohost) Accept(ctx context.Context, querier Querier) error {
+	sql := "UPDATE vehicle_cohosts SET accepted_at = NOW() WHERE id = $1 RETURNING accepted_at"
+	return querier.QueryRow(ctx, sql, cohost.ID).Scan(&cohost.AcceptedAt)
+}
+
+// Inviting a user who was already invited replaces the permissions they were
+// given without accepting the invitation for them
+func UpsertCohost(ctx context.Context, querier Querier, cohost *Cohost) error {
+	sql := `
+	INSERT INTO vehicle_cohosts (invited_by, permissions, user_id, vehicle_id)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (vehicle_id, user_id) DO UPDATE SET invited_by = EXCLUDED.invited_by, permissions = EXCLUDED.permissions
+	RETURNING id, accepted_at, created_at`
+	arguments := []interface{}{cohost.InvitedBy, cohost.Permissions, cohost.UserID, cohost.VehicleID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&cohost.ID, &cohost.AcceptedAt, &cohost.CreatedAt)
+}
+
+func SelectCohost(ctx context.Context, querier Querier, cohostId string) (*Cohost, error) {
+	sql := `
+	SELECT id, accepted_at, created_at, invited_by, permissions, user_id, vehicle_id
+	FROM vehicle_cohosts
+	WHERE id = $1`
+	cohost := &Cohost{}
+	destination := []interface{}{
+		&cohost.ID,
+		&cohost.AcceptedAt,
+		&cohost.CreatedAt,
+		&cohost.InvitedBy,
+		&cohost.Permissions,
+		&cohost.UserID,
+		&cohost.VehicleID,
+	}
+	err := querier.QueryRow(ctx, sql, cohostId).Scan(destination...)
+	return cohost, err
+}
+
+// The owner of a vehicle can do everything for it and an accepted co-host can do
+// what their permissions allow. An empty permission is met by any accepted co-host
+func IsVehicleManager(ctx context.Context, querier Querier, vehicleId string, userId string, permission string) (bool, error) {
+	isManager := false
+	sql := `
+	SELECT EXISTS (SELECT 1 FROM vehicles WHERE id = $1 AND user_id = $2) OR EXISTS (
+		SELECT 1 FROM vehicle_cohosts
+		WHERE vehicle_id = $1 AND user_id = $2 AND accepted_at IS NOT NULL AND ($3 = '' OR $3 = ANY(permissions))
+	)`
+	err := querier.QueryRow(ctx, sql, vehicleId, userId, permission).Scan(&isManager)
+	return isManager, err
+}
+
+// Selects the users who co-host the vehicle with the permission, e.g. to keep them
+// informed like the owner
+func SelectCohostUserIDs(ctx context.Context, querier Querier, vehicleId string, permission string) ([]string, error) {
+	sql := `
+	SELECT user_id FROM vehicle_cohosts
+	WHERE vehicle_id = $1 AND accepted_at IS NOT NULL AND $2 = ANY(permissions)`
+	rows, err := querier.Query(ctx, sql, vehicleId, permission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIds := []string{}
+	for rows.Next() {
+		userId := ""
+		if err = rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+
+		userIds = append(userIds, userId)
+	}
+
+	return userIds, rows.Err()
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type Conversation struct {
+	ID            string    `json:"id"`
+	BookingID     *string   `json:"booking_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	HostID        string    `json:"host_id"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	RenterID      string    `json:"renter_id"`
+	VehicleID     string    `json:"vehicle_id"`
+}
+
+type Message struct {
+	ID             string     `json:"id"`
+	Body           string     `json:"body"`
+	ConversationID string     `json:"conversation_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at"`
+	SenderID       string     `json:"sender_id"`
+}
+
+func (conversation *Conversation) IsParticipant(userId string) bool {
+	return conversation.HostID == userId || conversation.RenterID == userId
+}
+
+// The host and co-hosts who handle messages share the host's side of a
+// conversation, so only the renter's messages are unread for them
+func CountUnreadMessages(ctx context.Context, querier Querier, userId string) (int, error) {
+	count := 0
+	sql := `
+	SELECT COUNT(*) FROM messages AS m
+	JOIN conversations AS c ON m.conversation_id = c.id
+	WHERE m.read_at IS NULL AND (
+		(c.renter_id = $1 AND m.sender_id <> $1) OR 
+		(m.sender_id = c.renter_id AND (c.host_id = $1 OR EXISTS (
+			SELECT 1 FROM vehicle_cohosts AS vc
+			WHERE vc.vehicle_id = c.vehicle_id AND vc.user_id = $1 AND vc.accepted_at IS NOT NULL AND $2 = ANY(vc.permissions)
+		)))
+	)`
+	err := querier.QueryRow(ctx, sql, userId, CohostPermissionMessages).Scan(&count)
+	return count, err
+}
+
+// There is at most one conversation per booking and one per renter and vehicle
+// outside of a booking, so an existing conversation is returned instead of a new one
+func FindOrInsertConversation(ctx context.Context, querier Querier, conversation *Conversation) error {
+	conflictTarget := "(vehicle_id, renter_id) WHERE booking_id IS NULL"
+	if conversation.BookingID != nil {
+		conflictTarget = "(booking_id) WHERE booking_id IS NOT NULL"
+	}
+
+	sql := `
+	INSERT INTO conversations (booking_id, host_id, renter_id, vehicle_id) 
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT ` + conflictTarget + ` DO UPDATE SET last_message_at = conversations.last_message_at
+	RETURNING id, created_at, last_message_at`
+	arguments := []interface{}{conversation.BookingID, conversation.HostID, conversation.RenterID, conversation.VehicleID}
+	destination := []interface{}{&conversation.ID, &conversation.CreatedAt, &conversation.LastMessageAt}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(destination...)
+}
+
+func InsertMessage(ctx context.Context, querier Querier, message *Message) error {
+	sql := `
+	WITH cte_message AS (
+		INSERT INTO messages (body, conversation_id, sender_id) 
+		VALUES ($1, $2, $3) 
+		RETURNING id, created_at
+	), cte_conversation AS (
+		UPDATE conversations SET last_message_at = NOW() WHERE id = $2
+	)
+	SELECT id, created_at FROM cte_message`
+	arguments := []interface{}{message.Body, message.ConversationID, message.SenderID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&message.ID, &message.CreatedAt)
+}
+
+func SelectConversation(ctx context.Context, querier Querier, conversationId string) (*Conversation, error) {
+	sql := `
+	SELECT id, booking_id, created_at, host_id, last_message_at, renter_id, vehicle_id 
+	FROM conversations 
+	WHERE id = $1`
+	conversation := &Conversation{}
+	destination := []interface{}{
+		&conversation.ID,
+		&conversation.BookingID,
+		&conversation.CreatedAt,
+		&conversation.HostID,
+		&conversation.LastMessageAt,
+		&conversation.RenterID,
+		&conversation.VehicleID,
+	}
+	err := querier.QueryRow(ctx, sql, conversationId).Scan(destination...)
+	return conversation, err
+}
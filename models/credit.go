@@ -0,0 +1,108 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/jackc/pgx/v4"
+)
+
+// Account credit a user can spend on their trips. Amount is negative when the
+// credit is spent and is in the minor unit of the currency
+type CreditEntry struct {
+	ID          string    `json:"id"`
+	Amount      int       `json:"amount"`
+	BookingID   *string   `json:"booking_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	UserID      string    `json:"user_id"`
+}
+
+func InsertCreditEntry(ctx context.Context, querier Querier, entry *CreditEntry) error {
+	sql := `
+	INSERT INTO credit_entries (amount, booking_id, description, user_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+	arguments := []interface{}{entry.Amount, entry.BookingID, entry.Description, entry.UserID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// Gives the renter back the credits spent on a booking that isn't going ahead
+func RestoreBookingCredits(ctx context.Context, querier Querier, booking *Booking) error {
+	if booking.CreditAmount == 0 {
+		return nil
+	}
+
+	entry := &CreditEntry{
+		Amount:      booking.CreditAmount,
+		BookingID:   &booking.ID,
+		Description: fmt.Sprintf("Credits returned from booking %v", booking.ID),
+		UserID:      booking.UserID,
+	}
+	return InsertCreditEntry(ctx, querier, entry)
+}
+
+// Both the referee and whoever referred them get config.ReferralCreditAmount once
+// the referee completes their first trip. Does nothing for users who weren't
+// referred or whose referral has already been rewarded
+func RewardReferral(ctx context.Context, querier Querier, booking *Booking) error {
+	referrerId := ""
+	sql := `
+	UPDATE users SET referral_rewarded_at = NOW()
+	WHERE id = $1 AND referred_by IS NOT NULL AND referral_rewarded_at IS NULL
+	RETURNING referred_by`
+	err := querier.QueryRow(ctx, sql, booking.UserID).Scan(&referrerId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	entries := []*CreditEntry{
+		{Amount: config.ReferralCreditAmount, BookingID: &booking.ID, Description: "Referral reward for your first trip", UserID: booking.UserID},
+		{Amount: config.ReferralCreditAmount, BookingID: &booking.ID, Description: "Referral reward for a friend's first trip", UserID: referrerId},
+	}
+	for _, entry := range entries {
+		if err = InsertCreditEntry(ctx, querier, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func SelectCreditBalance(ctx context.Context, querier Querier, userId string) (int, error) {
+	balance := 0
+	sql := "SELECT COALESCE(SUM(amount), 0) FROM credit_entries WHERE user_id = $1"
+	err := querier.QueryRow(ctx, sql, userId).Scan(&balance)
+	return balance, err
+}
+
+// Locks the user so that the same credits can't be spent by concurrent bookings
+func SelectCreditBalanceForUpdate(ctx context.Context, querier Querier, userId string) (int, error) {
+	if _, err := querier.Exec(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userId); err != nil {
+		return 0, err
+	}
+
+	return SelectCreditBalance(ctx, querier, userId)
+}
+
+// Spends the credits taken off the booking's total
+func SpendBookingCredits(ctx context.Context, querier Querier, booking *Booking) error {
+	if booking.CreditAmount == 0 {
+		return nil
+	}
+
+	entry := &CreditEntry{
+		Amount:      -booking.CreditAmount,
+		BookingID:   &booking.ID,
+		Description: fmt.Sprintf("Credits spent on booking %v", booking.ID),
+		UserID:      booking.UserID,
+	}
+	return InsertCreditEntry(ctx, querier, entry)
+}
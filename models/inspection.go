@@ -0,0 +1,133 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	InspectionTypeCheckIn  = "check_in"
+	InspectionTypeCheckOut = "check_out"
+)
+
+// Photos of an inspection are stored in this order
+var InspectionSides = []string{"front", "back", "left", "right"}
+
+// An inspection is the state of the vehicle at the start or end of a trip as
+// recorded by one party and acknowledged by the other. Distance, the fuel
+// difference and the fees are only set on the check-out
+type Inspection struct {
+	ID                   string     `json:"id"`
+	BookingID            string     `json:"booking_id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	Distance             int        `json:"distance"`
+	FuelDifference       int        `json:"fuel_difference"`
+	FuelFee              int        `json:"fuel_fee"`
+	FuelLevel            int        `json:"fuel_level"`
+	HostAcknowledgedAt   *time.Time `json:"host_acknowledged_at"`
+	MileageFee           int        `json:"mileage_fee"`
+	Odometer             int        `json:"odometer"`
+	Photos               []string   `json:"-"`
+	RenterAcknowledgedAt *time.Time `json:"renter_acknowledged_at"`
+	Type                 string     `json:"type"`
+	UserID               *string    `json:"user_id"`
+}
+
+// Called on the check-out to work out how far the vehicle went and how much fuel
+// or charge is missing since the check-in, and what the booking's pricing rules
+// charge for them
+func (inspection *Inspection) CalculateOverage(checkIn *Inspection, booking *Booking) {
+	inspection.Distance = inspection.Odometer - checkIn.Odometer
+	inspection.FuelDifference = checkIn.FuelLevel - inspection.FuelLevel
+	if booking.DailyMileageLimit != nil {
+		allowance := *booking.DailyMileageLimit * CalculateBookingDays(booking.StartAt, booking.EndAt)
+		if excess := inspection.Distance - allowance; excess > 0 {
+			inspection.MileageFee = excess * booking.MileageFee
+		}
+	}
+
+	if inspection.FuelDifference > 0 {
+		inspection.FuelFee = inspection.FuelDifference * booking.FuelFee
+	}
+}
+
+func (inspection *Inspection) IsAcknowledged() bool {
+	return inspection.HostAcknowledgedAt != nil && inspection.RenterAcknowledgedAt != nil
+}
+
+// Records the acknowledgement of the host or the renter. Acknowledging twice
+// keeps the first timestamp
+func (inspection *Inspection) Acknowledge(ctx context.Context, querier Querier, byHost bool) error {
+	column := "renter_acknowledged_at"
+	if byHost {
+		column = "host_acknowledged_at"
+	}
+
+	sql := `
+	UPDATE inspections SET ` + column + ` = COALESCE(` + column + `, NOW())
+	WHERE id = $1
+	RETURNING host_acknowledged_at, renter_acknowledged_at`
+	return querier.QueryRow(ctx, sql, inspection.ID).Scan(&inspection.HostAcknowledgedAt, &inspection.RenterAcknowledgedAt)
+}
+
+func InsertInspection(ctx context.Context, querier Querier, inspection *Inspection) error {
+	sql := `
+	INSERT INTO inspections (
+		id, booking_id, distance, fuel_difference, fuel_fee, fuel_level, host_acknowledged_at,
+		mileage_fee, odometer, photos, renter_acknowledged_at, type, user_id
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING created_at`
+	arguments := []interface{}{
+		inspection.ID,
+		inspection.BookingID,
+		inspection.Distance,
+		inspection.FuelDifference,
+		inspection.FuelFee,
+		inspection.FuelLevel,
+		inspection.HostAcknowledgedAt,
+		inspection.MileageFee,
+		inspection.Odometer,
+		inspection.Photos,
+		inspection.RenterAcknowledgedAt,
+		inspection.Type,
+		inspection.UserID,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&inspection.CreatedAt)
+}
+
+func SelectInspection(ctx context.Context, querier Querier, bookingId string, inspectionType string) (*Inspection, error) {
+	return selectInspection(ctx, querier, bookingId, inspectionType, "")
+}
+
+// Locks the inspection so that both parties' acknowledgements are applied one after the other
+func SelectInspectionForUpdate(ctx context.Context, querier Querier, bookingId string, inspectionType string) (*Inspection, error) {
+	return selectInspection(ctx, querier, bookingId, inspectionType, "FOR UPDATE")
+}
+
+func selectInspection(ctx context.Context, querier Querier, bookingId string, inspectionType string, lockingClause string) (*Inspection, error) {
+	sql := `
+	SELECT id, booking_id, created_at, distance, fuel_difference, fuel_fee, fuel_level, host_acknowledged_at,
+		mileage_fee, odometer, photos, renter_acknowledged_at, type, user_id
+	FROM inspections
+	WHERE booking_id = $1 AND type = $2 ` + lockingClause
+	inspection := &Inspection{}
+	destination := []interface{}{
+		&inspection.ID,
+		&inspection.BookingID,
+		&inspection.CreatedAt,
+		&inspection.Distance,
+		&inspection.FuelDifference,
+		&inspection.FuelFee,
+		&inspection.FuelLevel,
+		&inspection.HostAcknowledgedAt,
+		&inspection.MileageFee,
+		&inspection.Odometer,
+		&inspection.Photos,
+		&inspection.RenterAcknowledgedAt,
+		&inspection.Type,
+		&inspection.UserID,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId, inspectionType).Scan(destination...)
+	return inspection, err
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// Bookings in these statuses have been confirmed, so the renter is owed an invoice
+var InvoicedBookingStatuses = []string{BookingStatusConfirmed, BookingStatusInProgress, BookingStatusCompleted}
+
+// The receipt of what the renter paid for a booking. A booking gets a new revision,
+// with its own number, each time that changes. The PDF is in file storage under
+// FileKey and is written the first time the invoice is asked for
+type Invoice struct {
+	ID          string    `json:"id"`
+	BookingID   string    `json:"booking_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Currency    string    `json:"currency"`
+	FileKey     string    `json:"-"`
+	Number      string    `json:"number"`
+	Revision    int       `json:"revision"`
+	TotalAmount int       `json:"total_amount"`
+}
+
+// Amounts are in the minor unit of the currency and reductions are negative
+type InvoiceLine struct {
+	Amount      int    `json:"amount"`
+	Description string `json:"description"`
+}
+
+type invoiceParty struct {
+	email string
+	name  string
+}
+
+func (booking *Booking) IsInvoiceable() bool {
+	for _, status := range InvoicedBookingStatuses {
+		if booking.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The lines of the quote the renter paid, with the booking's tax lines. They add up
+// to the booking's total
+func (booking *Booking) InvoiceLines(taxLines []TaxLine) []InvoiceLine {
+	days := CalculateBookingDays(booking.StartAt, booking.EndAt)
+	subtotal := booking.TotalAmount + booking.DiscountAmount + booking.CreditAmount - booking.TaxAmount
+	lines := []InvoiceLine{{Amount: subtotal, Description: fmt.Sprintf("Rental for %d day(s)", days)}}
+	if booking.DiscountAmount > 0 {
+		lines = append(lines, InvoiceLine{Amount: -booking.DiscountAmount, Description: "Promo code discount"})
+	}
+
+	for _, line := range taxLines {
+		lines = append(lines, InvoiceLine{Amount: line.Amount, Description: line.Description})
+	}
+
+	if booking.CreditAmount > 0 {
+		lines = append(lines, InvoiceLine{Amount: -booking.CreditAmount, Description: "Credits"})
+	}
+
+	return lines
+}
+
+// Gives the booking an invoice with the next number of the year for what the
+// renter has paid so far, replacing the one it had. querier should be a
+// transaction that has locked the booking so that the number is only taken when
+// the invoice is saved. The counter stays locked until then, so the PDF is left
+// for WriteFile
+func IssueInvoice(ctx context.Context, querier Querier, booking *Booking) (*Invoice, error) {
+	lines, err := selectInvoiceLines(ctx, querier, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := 0
+	sql := "SELECT COALESCE(MAX(revision), 0) + 1 FROM invoices WHERE booking_id = $1"
+	if err = querier.QueryRow(ctx, sql, booking.ID).Scan(&revision); err != nil {
+		return nil, err
+	}
+
+	year, number := time.Now().Year(), 0
+	sql = `
+	INSERT INTO invoice_counters (year, last_number) VALUES ($1, 1)
+	ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+	RETURNING last_number`
+	if err = querier.QueryRow(ctx, sql, year).Scan(&number); err != nil {
+		return nil, err
+	}
+
+	invoice := &Invoice{
+		BookingID: booking.ID,
+		CreatedAt: time.Now(),
+		Currency:  config.Currency,
+		Number:    fmt.Sprintf("INV-%d-%06d", year, number),
+		Revision:  revision,
+	}
+	invoice.FileKey = fmt.Sprintf("invoices/%d/%v.pdf", year, invoice.Number)
+	for _, line := range lines {
+		invoice.TotalAmount += line.Amount
+	}
+
+	sql = `
+	INSERT INTO invoices (booking_id, created_at, currency, file_key, number, revision, total_amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id`
+	arguments := []interface{}{invoice.BookingID, invoice.CreatedAt, invoice.Currency, invoice.FileKey, invoice.Number, invoice.Revision, invoice.TotalAmount}
+	return invoice, querier.QueryRow(ctx, sql, arguments...).Scan(&invoice.ID)
+}
+
+// Returns the latest revision of the booking's invoice
+func SelectInvoice(ctx context.Context, querier Querier, bookingId string) (*Invoice, error) {
+	sql := `
+	SELECT id, booking_id, created_at, currency, file_key, number, revision, total_amount
+	FROM invoices
+	WHERE booking_id = $1
+	ORDER BY revision DESC
+	LIMIT 1`
+	invoice := &Invoice{}
+	destination := []interface{}{
+		&invoice.ID,
+		&invoice.BookingID,
+		&invoice.CreatedAt,
+		&invoice.Currency,
+		&invoice.FileKey,
+		&invoice.Number,
+		&invoice.Revision,
+		&invoice.TotalAmount,
+	}
+	err := querier.QueryRow(ctx, sql, bookingId).Scan(destination...)
+	return invoice, err
+}
+
+// Renders the invoice's PDF and puts it in file storage. It's rendered from the
+// booking as it is now, which is what the invoice was issued for as long as it's
+// the latest revision
+func (invoice *Invoice) WriteFile(ctx context.Context, querier Querier, booking *Booking) error {
+	host, renter := &invoiceParty{}, &invoiceParty{}
+	vehicleAddress, vehicleName := "", ""
+	var replacedNumber *string
+	sql := `
+	SELECT h.firstname || ' ' || h.lastname, h.email, r.firstname || ' ' || r.lastname, r.email, v.address, v.name,
+		(SELECT number FROM invoices WHERE booking_id = b.id AND revision = $2)
+	FROM bookings AS b
+	JOIN users AS r ON b.user_id = r.id
+	JOIN vehicles AS v ON b.vehicle_id = v.id
+	JOIN users AS h ON v.user_id = h.id
+	WHERE b.id = $1`
+	destination := []interface{}{&host.name, &host.email, &renter.name, &renter.email, &vehicleAddress, &vehicleName, &replacedNumber}
+	if err := querier.QueryRow(ctx, sql, booking.ID, invoice.Revision-1).Scan(destination...); err != nil {
+		return err
+	}
+
+	lines, err := selectInvoiceLines(ctx, querier, booking)
+	if err != nil {
+		return err
+	}
+
+	document := services.NewPDFDocument()
+	document.Text(50, 70, 24, true, "Invoice")
+	document.Text(50, 90, 10, false, "PentaHire")
+	details := [][2]string{
+		{"Invoice number", invoice.Number},
+		{"Date", invoice.CreatedAt.Format("Jan 2, 2006")},
+		{"Booking", booking.ID},
+	}
+	if replacedNumber != nil {
+		details = append(details, [2]string{"Replaces", *replacedNumber})
+	}
+
+	for i, detail := range details {
+		document.Text(300, float64(70+i*16), 10, true, detail[0])
+		document.Text(390, float64(70+i*16), 10, false, detail[1])
+	}
+
+	parties := []struct {
+		title string
+		party *invoiceParty
+		x     float64
+	}{{"Billed to", renter, 50}, {"Host", host, 300}}
+	for _, party := range parties {
+		document.Text(party.x, 150, 11, true, party.title)
+		document.Text(party.x, 166, 10, false, party.party.name)
+		document.Text(party.x, 182, 10, false, party.party.email)
+	}
+
+	document.Text(50, 222, 11, true, "Trip")
+	document.Text(50, 238, 10, false, vehicleName)
+	document.Text(50, 254, 10, false, vehicleAddress)
+	document.Text(50, 270, 10, false, fmt.Sprintf("%v to %v", booking.StartAt.UTC().Format("Jan 2, 2006 15:04 MST"), booking.EndAt.UTC().Format("Jan 2, 2006 15:04 MST")))
+
+	y := 310.0
+	document.Text(50, y, 10, true, "Description")
+	document.Text(430, y, 10, true, "Amount")
+	document.Line(50, y+6, 545, y+6)
+	for _, line := range lines {
+		y += 20
+		document.Text(50, y, 10, false, line.Description)
+		document.Text(430, y, 10, false, formatInvoiceAmount(line.Amount, invoice.Currency))
+	}
+	document.Line(50, y+8, 545, y+8)
+	document.Text(50, y+26, 11, true, "Total paid")
+	document.Text(430, y+26, 11, true, formatInvoiceAmount(invoice.TotalAmount, invoice.Currency))
+	if booking.SecurityDeposit > 0 {
+		note := fmt.Sprintf("A security deposit of %v is held during the trip and released after it.", formatInvoiceAmount(booking.SecurityDeposit, invoice.Currency))
+		document.Text(50, y+60, 9, false, note)
+	}
+
+	buffer := &bytes.Buffer{}
+	if _, err := document.WriteTo(buffer); err != nil {
+		return err
+	}
+
+	return services.GetFileStorage().Put(ctx, invoice.FileKey, buffer)
+}
+
+// The lines of the trip followed by the charges that went through after it, like
+// overage fees, each with its tax lines. Trip changes are part of the trip, which
+// is priced for its new dates
+func selectInvoiceLines(ctx context.Context, querier Querier, booking *Booking) ([]InvoiceLine, error) {
+	taxLines, err := SelectBookingTaxLines(ctx, querier, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := booking.InvoiceLines(taxLines)
+	sql := `
+	SELECT c.amount - c.tax_amount, c.description, COALESCE(array_agg(t.amount ORDER BY t.created_at, t.type, t.jurisdiction) FILTER (WHERE t.id IS NOT NULL), '{}'),
+		COALESCE(array_agg(t.description ORDER BY t.created_at, t.type, t.jurisdiction) FILTER (WHERE t.id IS NOT NULL), '{}')
+	FROM booking_charges AS c
+	LEFT JOIN booking_tax_lines AS t ON t.charge_id = c.id
+	WHERE c.booking_id = $1 AND c.type <> $2 AND c.failure_reason = ''
+	GROUP BY c.id
+	ORDER BY c.created_at`
+	rows, err := querier.Query(ctx, sql, booking.ID, BookingChargeTypeModification)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		line, taxAmounts, taxDescriptions := InvoiceLine{}, []int{}, []string{}
+		if err = rows.Scan(&line.Amount, &line.Description, &taxAmounts, &taxDescriptions); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+		for i, amount := range taxAmounts {
+			lines = append(lines, InvoiceLine{Amount: amount, Description: taxDescriptions[i]})
+		}
+	}
+
+	return lines, rows.Err()
+}
+
+// Formats an amount in the minor unit of the currency like NGN 15,000.00
+func formatInvoiceAmount(amount int, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := strconv.Itoa(amount / 100)
+	groups := []string{}
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	groups = append([]string{digits}, groups...)
+	return fmt.Sprintf("%v%v %v.%02d", sign, currency, strings.Join(groups, ","), amount%100)
+}
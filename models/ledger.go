@@ -0,0 +1,329 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+const (
+	LedgerAccountDeposits        = "deposits"
+	LedgerAccountHost            = "host"
+	LedgerAccountPaymentProvider = "payment_provider"
+	LedgerAccountPlatformRevenue = "platform_revenue"
+	LedgerAccountPromotions      = "promotions"
+	LedgerAccountRenter          = "renter"
+	LedgerAccountTax             = "tax"
+
+	LedgerReferenceBooking         = "booking"
+	LedgerReferenceBookingCharge   = "booking_charge"
+	LedgerReferencePayment         = "payment"
+	LedgerReferencePayout          = "payout"
+	LedgerReferenceSecurityDeposit = "security_deposit"
+
+	LedgerTransactionCharge    = "charge"
+	LedgerTransactionClaim     = "claim"
+	LedgerTransactionPayout    = "payout"
+	LedgerTransactionPromotion = "promotion"
+	LedgerTransactionRefund    = "refund"
+)
+
+var ErrUnbalancedLedgerTransaction = errors.New("ledger transaction is unbalanced")
+
+// Amount is positive for a debit and negative for a credit. UserID is only set
+// for host and renter accounts
+type LedgerEntry struct {
+	AccountType string
+	Amount      int
+	UserID      string
+}
+
+type LedgerTransaction struct {
+	ID            string
+	Description   string
+	Entries       []LedgerEntry
+	ReferenceID   string
+	ReferenceType string
+	Type          string
+}
+
+type LedgerDiscrepancy struct {
+	Field             string      `json:"field"`
+	Ledger            interface{} `json:"ledger"`
+	PaymentID         string      `json:"payment_id,omitempty"`
+	Provider          interface{} `json:"provider"`
+	SecurityDepositID string      `json:"security_deposit_id,omitempty"`
+}
+
+// The platform keeps config.PlatformFeeBasisPoints of every amount a renter pays
+func CalculatePlatformFee(amount int) int {
+	return amount * config.PlatformFeeBasisPoints / 10000
+}
+
+// The database rejects unbalanced transactions as well, this only fails earlier
+// with a clearer error
+func InsertLedgerTransaction(ctx context.Context, querier Querier, transaction *LedgerTransaction) error {
+	total := 0
+	for _, entry := range transaction.Entries {
+		total += entry.Amount
+	}
+
+	if total != 0 || len(transaction.Entries) < 2 {
+		return ErrUnbalancedLedgerTransaction
+	}
+
+	sql := `
+	INSERT INTO ledger_transactions (description, reference_id, reference_type, type)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id`
+	arguments := []interface{}{transaction.Description, transaction.ReferenceID, transaction.ReferenceType, transaction.Type}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(&transaction.ID); err != nil {
+		return err
+	}
+
+	for _, entry := range transaction.Entries {
+		if entry.Amount == 0 {
+			continue
+		}
+
+		accountId, err := selectOrInsertLedgerAccount(ctx, querier, entry.AccountType, entry.UserID)
+		if err != nil {
+			return err
+		}
+
+		sql = "INSERT INTO ledger_entries (account_id, amount, transaction_id) VALUES ($1, $2, $3)"
+		if _, err = querier.Exec(ctx, sql, accountId, entry.Amount, transaction.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Host earnings are credits, so the balance is flipped to be positive when the
+// platform owes the host money
+func SelectHostBalance(ctx context.Context, querier Querier, userId string) (int, error) {
+	balance := 0
+	sql := `
+	SELECT COALESCE(-SUM(e.amount), 0)
+	FROM ledger_entries AS e
+	JOIN ledger_accounts AS a ON e.account_id = a.id
+	WHERE a.type = $1 AND a.user_id = $2`
+	err := querier.QueryRow(ctx, sql, LedgerAccountHost, userId).Scan(&balance)
+	return balance, err
+}
+
+// Compares every payment and security deposit, and the money the ledger says is
+// held at the provider for it, with the provider's own record of the intent
+func ReconcileLedger(ctx context.Context, querier Querier, provider services.PaymentProvider) ([]LedgerDiscrepancy, error) {
+	sql := `
+	SELECT $1, p.id, p.created_at, p.provider_intent_id, p.status, COALESCE(SUM(e.amount), 0)
+	FROM payments AS p
+	LEFT JOIN ledger_transactions AS t ON t.reference_type = $1 AND t.reference_id = p.id
+	LEFT JOIN ledger_entries AS e ON e.transaction_id = t.id
+		AND e.account_id = (SELECT id FROM ledger_accounts WHERE type = $2 AND user_id IS NULL)
+	WHERE p.provider = $3
+	GROUP BY p.id
+	UNION ALL
+	SELECT $4, d.id, d.created_at, d.provider_intent_id, d.status, COALESCE(SUM(e.amount), 0)
+	FROM security_deposits AS d
+	LEFT JOIN ledger_transactions AS t ON t.reference_type = $4 AND t.reference_id = d.id
+	LEFT JOIN ledger_entries AS e ON e.transaction_id = t.id
+		AND e.account_id = (SELECT id FROM ledger_accounts WHERE type = $2 AND user_id IS NULL)
+	WHERE d.provider = $3
+	GROUP BY d.id
+	ORDER BY 3`
+	arguments := []interface{}{LedgerReferencePayment, LedgerAccountPaymentProvider, provider.Name(), LedgerReferenceSecurityDeposit}
+	rows, err := querier.Query(ctx, sql, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type record struct {
+		createdAt     time.Time
+		id            string
+		intentId      string
+		ledgerAmount  int
+		referenceType string
+		status        string
+	}
+	records := []record{}
+	for rows.Next() {
+		r := record{}
+		destination := []interface{}{&r.referenceType, &r.id, &r.createdAt, &r.intentId, &r.status, &r.ledgerAmount}
+		if err = rows.Scan(destination...); err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	discrepancies := []LedgerDiscrepancy{}
+	for _, r := range records {
+		discrepancy := LedgerDiscrepancy{PaymentID: r.id}
+		if r.referenceType == LedgerReferenceSecurityDeposit {
+			discrepancy = LedgerDiscrepancy{SecurityDepositID: r.id}
+		}
+
+		intent, err := provider.GetIntent(ctx, r.intentId)
+		if errors.Is(err, services.ErrPaymentIntentNotFound) {
+			discrepancy.Field, discrepancy.Ledger = "intent", r.intentId
+			discrepancies = append(discrepancies, discrepancy)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%v %v: %w", r.referenceType, r.id, err)
+		}
+
+		if intent.Status != r.status {
+			discrepancy.Field, discrepancy.Ledger, discrepancy.Provider = "status", r.status, intent.Status
+			discrepancies = append(discrepancies, discrepancy)
+		}
+
+		if held := intent.AmountCaptured - intent.AmountRefunded; held != r.ledgerAmount {
+			discrepancy.Field, discrepancy.Ledger, discrepancy.Provider = "held_amount", r.ledgerAmount, held
+			discrepancies = append(discrepancies, discrepancy)
+		}
+	}
+
+	return discrepancies, nil
+}
+
+// Pays the host for the discount and credits taken off a completed trip as if the
+// renter had paid them, at the platform's expense. Credits also pay for whatever
+// tax is left when they bring the total below the booking's tax
+func PostBookingPromotionToLedger(ctx context.Context, querier Querier, booking *Booking) error {
+	amount := booking.DiscountAmount + booking.CreditAmount
+	if amount == 0 {
+		return nil
+	}
+
+	tax := booking.TaxAmount - calculateTaxShare(booking.TotalAmount, booking.TaxAmount, booking.TotalAmount)
+	fee := CalculatePlatformFee(amount - tax)
+	transaction := &LedgerTransaction{
+		Description: fmt.Sprintf("Discounts and credits for booking %v", booking.ID),
+		Entries: []LedgerEntry{
+			{AccountType: LedgerAccountPromotions, Amount: amount},
+			{AccountType: LedgerAccountHost, Amount: -(amount - tax - fee), UserID: booking.HostID},
+			{AccountType: LedgerAccountPlatformRevenue, Amount: -fee},
+			{AccountType: LedgerAccountTax, Amount: -tax},
+		},
+		ReferenceID:   booking.ID,
+		ReferenceType: LedgerReferenceBooking,
+		Type:          LedgerTransactionPromotion,
+	}
+	return InsertLedgerTransaction(ctx, querier, transaction)
+}
+
+// Records the money that moved when a payment's captured or refunded amount grew.
+// The tax is the payment's own, the tax of later charges is posted with them
+func postPaymentToLedger(ctx context.Context, querier Querier, payment *Payment, capturedAmount int, refundedAmount int) error {
+	if capturedAmount == 0 && refundedAmount == 0 {
+		return nil
+	}
+
+	hostId, renterId, err := selectBookingParticipants(ctx, querier, payment.BookingID)
+	if err != nil {
+		return err
+	}
+
+	if capturedAmount > 0 {
+		description := fmt.Sprintf("Charge for booking %v", payment.BookingID)
+		tax := calculateTaxShare(capturedAmount, payment.TaxAmount, payment.Amount)
+		transaction := newChargeTransaction(hostId, renterId, capturedAmount, tax, description, payment.ID, LedgerReferencePayment)
+		if err := InsertLedgerTransaction(ctx, querier, transaction); err != nil {
+			return err
+		}
+	}
+
+	if refundedAmount > 0 {
+		description := fmt.Sprintf("Refund for booking %v", payment.BookingID)
+		tax := calculateTaxShare(refundedAmount, payment.TaxAmount, payment.Amount)
+		transaction := newRefundTransaction(hostId, renterId, refundedAmount, tax, description, payment.ID, LedgerReferencePayment)
+		if err := InsertLedgerTransaction(ctx, querier, transaction); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The renter's account is charged and settled in the same transaction so that it
+// shows what each renter paid while always netting to zero. The taxes in amount
+// are held for the authorities and no platform fee is taken from them
+func newChargeTransaction(hostId string, renterId string, amount int, taxAmount int, description string, referenceId string, referenceType string) *LedgerTransaction {
+	fee := CalculatePlatformFee(amount - taxAmount)
+	return &LedgerTransaction{
+		Description: description,
+		Entries: []LedgerEntry{
+			{AccountType: LedgerAccountRenter, Amount: amount, UserID: renterId},
+			{AccountType: LedgerAccountHost, Amount: -(amount - taxAmount - fee), UserID: hostId},
+			{AccountType: LedgerAccountPlatformRevenue, Amount: -fee},
+			{AccountType: LedgerAccountTax, Amount: -taxAmount},
+			{AccountType: LedgerAccountPaymentProvider, Amount: amount},
+			{AccountType: LedgerAccountRenter, Amount: -amount, UserID: renterId},
+		},
+		ReferenceID:   referenceId,
+		ReferenceType: referenceType,
+		Type:          LedgerTransactionCharge,
+	}
+}
+
+// Reverses a charge of amount, taking back the host's and the platform's shares and
+// the taxes in it
+func newRefundTransaction(hostId string, renterId string, amount int, taxAmount int, description string, referenceId string, referenceType string) *LedgerTransaction {
+	fee := CalculatePlatformFee(amount - taxAmount)
+	return &LedgerTransaction{
+		Description: description,
+		Entries: []LedgerEntry{
+			{AccountType: LedgerAccountHost, Amount: amount - taxAmount - fee, UserID: hostId},
+			{AccountType: LedgerAccountPlatformRevenue, Amount: fee},
+			{AccountType: LedgerAccountTax, Amount: taxAmount},
+			{AccountType: LedgerAccountRenter, Amount: -amount, UserID: renterId},
+			{AccountType: LedgerAccountRenter, Amount: amount, UserID: renterId},
+			{AccountType: LedgerAccountPaymentProvider, Amount: -amount},
+		},
+		ReferenceID:   referenceId,
+		ReferenceType: referenceType,
+		Type:          LedgerTransactionRefund,
+	}
+}
+
+func selectBookingParticipants(ctx context.Context, querier Querier, bookingId string) (string, string, error) {
+	hostId, renterId := "", ""
+	sql := `
+	SELECT v.user_id, b.user_id
+	FROM bookings AS b
+	JOIN vehicles AS v ON b.vehicle_id = v.id
+	WHERE b.id = $1`
+	err := querier.QueryRow(ctx, sql, bookingId).Scan(&hostId, &renterId)
+	return hostId, renterId, err
+}
+
+func selectOrInsertLedgerAccount(ctx context.Context, querier Querier, accountType string, userId string) (string, error) {
+	var owner interface{}
+	conflictTarget := "(type) WHERE user_id IS NULL"
+	if userId != "" {
+		owner = userId
+		conflictTarget = "(type, user_id) WHERE user_id IS NOT NULL"
+	}
+
+	accountId := ""
+	sql := `
+	INSERT INTO ledger_accounts (type, user_id)
+	VALUES ($1, $2)
+	ON CONFLICT ` + conflictTarget + ` DO UPDATE SET type = EXCLUDED.type
+	RETURNING id`
+	err := querier.QueryRow(ctx, sql, accountType, owner).Scan(&accountId)
+	return accountId, err
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	NotificationTypeBookingUpdated    = "booking_updated"
+	NotificationTypeCohostInvitation  = "cohost_invitation"
+	NotificationTypeEmailVerification = "email_verification"
+	NotificationTypePasswordReset     = "password_reset"
+	NotificationTypePayoutFailed      = "payout_failed"
+	NotificationTypeSavedSearch       = "saved_search"
+	NotificationTypeUnreadMessages    = "unread_messages"
+)
+
+// The order preferences are listed in
+var NotificationTypes = []string{
+	NotificationTypeBookingUpdated,
+	NotificationTypeCohostInvitation,
+	NotificationTypeEmailVerification,
+	NotificationTypePasswordReset,
+	NotificationTypePayoutFailed,
+	NotificationTypeSavedSearch,
+	NotificationTypeUnreadMessages,
+}
+
+var defaultNotificationPreferences = map[string]NotificationPreference{
+	NotificationTypeBookingUpdated:    {Email: true, InApp: true},
+	NotificationTypeCohostInvitation:  {Email: true, InApp: true},
+	NotificationTypeEmailVerification: {Email: true},
+	NotificationTypePasswordReset:     {Email: true, InApp: true},
+	NotificationTypePayoutFailed:      {Email: true, InApp: true},
+	NotificationTypeSavedSearch:       {Email: true, InApp: true},
+	NotificationTypeUnreadMessages:    {Email: true},
+}
+
+type Notification struct {
+	ID        string     `json:"id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	Data      gin.H      `json:"data"`
+	ReadAt    *time.Time `json:"read_at"`
+	Title     string     `json:"title"`
+	Type      string     `json:"type"`
+	UserID    string     `json:"user_id"`
+}
+
+type NotificationPreference struct {
+	Email bool   `json:"email"`
+	InApp bool   `json:"in_app"`
+	SMS   bool   `json:"sms"`
+	Type  string `json:"type"`
+}
+
+// Mails the user explicitly asked for, such as a password reset link, can't be turned off
+func IsEmailRequired(notificationType string) bool {
+	return notificationType == NotificationTypeEmailVerification || notificationType == NotificationTypePasswordReset
+}
+
+func IsNotificationType(value string) bool {
+	_, ok := defaultNotificationPreferences[value]
+	return ok
+}
+
+func InsertNotification(ctx context.Context, querier Querier, notification *Notification) error {
+	if notification.Data == nil {
+		notification.Data = gin.H{}
+	}
+
+	sql := `
+	INSERT INTO notifications (body, data, title, type, user_id) 
+	VALUES ($1, $2, $3, $4, $5) 
+	RETURNING id, created_at`
+	arguments := []interface{}{notification.Body, notification.Data, notification.Title, notification.Type, notification.UserID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&notification.ID, &notification.CreatedAt)
+}
+
+func SelectNotificationPreference(ctx context.Context, querier Querier, userId string, notificationType string) (NotificationPreference, error) {
+	preferences, err := SelectNotificationPreferences(ctx, querier, userId)
+	if err != nil {
+		return NotificationPreference{}, err
+	}
+
+	for _, preference := range preferences {
+		if preference.Type == notificationType {
+			return preference, nil
+		}
+	}
+
+	return NotificationPreference{Type: notificationType}, nil
+}
+
+// Returns a preference for every notification type, falling back to the defaults
+// for types the user hasn't changed
+func SelectNotificationPreferences(ctx context.Context, querier Querier, userId string) ([]NotificationPreference, error) {
+	sql := "SELECT email, in_app, sms, type FROM notification_preferences WHERE user_id = $1"
+	rows, err := querier.Query(ctx, sql, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := map[string]NotificationPreference{}
+	for rows.Next() {
+		preference := NotificationPreference{}
+		if err = rows.Scan(&preference.Email, &preference.InApp, &preference.SMS, &preference.Type); err != nil {
+			return nil, err
+		}
+
+		saved[preference.Type] = preference
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	preferences := []NotificationPreference{}
+	for _, notificationType := range NotificationTypes {
+		preference, ok := saved[notificationType]
+		if !ok {
+			preference = defaultNotificationPreferences[notificationType]
+			preference.Type = notificationType
+		}
+
+		preference.Email = preference.Email || IsEmailRequired(notificationType)
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, nil
+}
+
+func UpsertNotificationPreference(ctx context.Context, querier Querier, userId string, preference NotificationPreference) error {
+	sql := `
+	INSERT INTO notification_preferences (email, in_app, sms, type, user_id) 
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id, type) DO UPDATE SET 
+		email = EXCLUDED.email, 
+		in_app = EXCLUDED.in_app, 
+		sms = EXCLUDED.sms, 
+		updated_at = NOW()`
+	arguments := []interface{}{preference.Email, preference.InApp, preference.SMS, preference.Type, userId}
+	_, err := querier.Exec(ctx, sql, arguments...)
+	return err
+}
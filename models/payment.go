@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+const (
+	PaymentReasonAuthorise = "authorise"
+	PaymentReasonCancel    = "cancel"
+	PaymentReasonCapture   = "capture"
+	PaymentReasonCreate    = "create"
+	PaymentReasonRefund    = "refund"
+	PaymentReasonWebhook   = "webhook"
+)
+
+var paymentStatusRanks = map[string]int{
+	services.PaymentStatusPending:           0,
+	services.PaymentStatusAuthorised:        1,
+	services.PaymentStatusCancelled:         2,
+	services.PaymentStatusCaptured:          2,
+	services.PaymentStatusPartiallyRefunded: 3,
+	services.PaymentStatusRefunded:          4,
+}
+
+// Amounts are in the minor unit of the currency and Amount includes TaxAmount
+type Payment struct {
+	ID               string    `json:"id"`
+	Amount           int       `json:"amount"`
+	AmountCaptured   int       `json:"amount_captured"`
+	AmountRefunded   int       `json:"amount_refunded"`
+	BookingID        string    `json:"booking_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	Currency         string    `json:"currency"`
+	PaymentMethodID  string    `json:"-"`
+	Provider         string    `json:"provider"`
+	ProviderIntentID string    `json:"-"`
+	Status           string    `json:"status"`
+	TaxAmount        int       `json:"tax_amount"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Copies the provider's view of the intent onto the payment, records the
+// transition and posts any money that moved to the ledger. Nothing is written
+// when the intent matches what is already stored, which is what makes replaying
+// the same provider state harmless
+func (payment *Payment) Apply(ctx context.Context, querier Querier, intent *services.PaymentIntent, reason string) error {
+	isUnchanged := payment.Status == intent.Status &&
+		payment.AmountCaptured == intent.AmountCaptured &&
+		payment.AmountRefunded == intent.AmountRefunded
+	if isUnchanged {
+		return nil
+	}
+
+	// Webhooks can arrive out of order, and a payment never moves backwards
+	capturedAmount := intent.AmountCaptured - payment.AmountCaptured
+	refundedAmount := intent.AmountRefunded - payment.AmountRefunded
+	isStale := capturedAmount < 0 || refundedAmount < 0 ||
+		paymentStatusRanks[intent.Status] < paymentStatusRanks[payment.Status]
+	if isStale {
+		return nil
+	}
+
+	fromStatus := payment.Status
+	sql := `
+	UPDATE payments SET amount_captured = $1, amount_refunded = $2, status = $3, updated_at = NOW() 
+	WHERE id = $4 
+	RETURNING updated_at`
+	arguments := []interface{}{intent.AmountCaptured, intent.AmountRefunded, intent.Status, payment.ID}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(&payment.UpdatedAt); err != nil {
+		return err
+	}
+
+	payment.AmountCaptured = intent.AmountCaptured
+	payment.AmountRefunded = intent.AmountRefunded
+	payment.Status = intent.Status
+	if err := payment.insertTransition(ctx, querier, &fromStatus, reason); err != nil {
+		return err
+	}
+
+	return postPaymentToLedger(ctx, querier, payment, capturedAmount, refundedAmount)
+}
+
+// Lowers the amount that will be captured from an authorised payment, e.g. when
+// the trip is shortened before it starts. The tax goes down by its share of amount
+func (payment *Payment) ReduceAmount(ctx context.Context, querier Querier, amount int) error {
+	taxAmount := payment.TaxAmount - calculateTaxShare(amount, payment.TaxAmount, payment.Amount)
+	sql := `
+	UPDATE payments SET amount = amount - $1, tax_amount = $2, updated_at = NOW() 
+	WHERE id = $3 
+	RETURNING amount, tax_amount, updated_at`
+	return querier.QueryRow(ctx, sql, amount, taxAmount, payment.ID).Scan(&payment.Amount, &payment.TaxAmount, &payment.UpdatedAt)
+}
+
+func (payment *Payment) insertTransition(ctx context.Context, querier Querier, fromStatus *string, reason string) error {
+	sql := `
+	INSERT INTO payment_transitions (amount_captured, amount_refunded, from_status, payment_id, reason, to_status) 
+	VALUES ($1, $2, $3, $4, $5, $6)`
+	arguments := []interface{}{payment.AmountCaptured, payment.AmountRefunded, fromStatus, payment.ID, reason, payment.Status}
+	_, err := querier.Exec(ctx, sql, arguments...)
+	return err
+}
+
+func InsertPayment(ctx context.Context, querier Querier, payment *Payment) error {
+	sql := `
+	INSERT INTO payments (
+		amount, amount_captured, amount_refunded, booking_id, currency, payment_method_id, provider, provider_intent_id, status, tax_amount
+	) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
+	RETURNING id, created_at, updated_at`
+	arguments := []interface{}{
+		payment.Amount,
+		payment.AmountCaptured,
+		payment.AmountRefunded,
+		payment.BookingID,
+		payment.Currency,
+		payment.PaymentMethodID,
+		payment.Provider,
+		payment.ProviderIntentID,
+		payment.Status,
+		payment.TaxAmount,
+	}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return payment.insertTransition(ctx, querier, nil, PaymentReasonCreate)
+}
+
+// Returns false when the provider already delivered the event
+func InsertPaymentWebhookEvent(ctx context.Context, querier Querier, provider string, event *services.PaymentWebhookEvent, payload []byte) (bool, error) {
+	sql := `
+	INSERT INTO payment_webhook_events (id, payload, provider, type) 
+	VALUES ($1, $2, $3, $4) 
+	ON CONFLICT DO NOTHING`
+	commandTag, err := querier.Exec(ctx, sql, event.ID, payload, provider, event.Type)
+	if err != nil {
+		return false, err
+	}
+
+	return commandTag.RowsAffected() == 1, nil
+}
+
+// Locks the payment so that the API and webhooks apply provider state one at a time
+func SelectPaymentByBookingForUpdate(ctx context.Context, querier Querier, bookingId string) (*Payment, error) {
+	return selectPaymentForUpdate(ctx, querier, "booking_id = $1", bookingId)
+}
+
+func SelectPaymentByIntentForUpdate(ctx context.Context, querier Querier, provider string, intentId string) (*Payment, error) {
+	return selectPaymentForUpdate(ctx, querier, "provider = $1 AND provider_intent_id = $2", provider, intentId)
+}
+
+func selectPaymentForUpdate(ctx context.Context, querier Querier, condition string, arguments ...interface{}) (*Payment, error) {
+	sql := `
+	SELECT id, amount, amount_captured, amount_refunded, booking_id, created_at, currency, payment_method_id, provider, provider_intent_id, status, tax_amount, updated_at 
+	FROM payments 
+	WHERE ` + condition + ` 
+	FOR UPDATE`
+	payment := &Payment{}
+	destination := []interface{}{
+		&payment.ID,
+		&payment.Amount,
+		&payment.AmountCaptured,
+		&payment.AmountRefunded,
+		&payment.BookingID,
+		&payment.CreatedAt,
+		&payment.Currency,
+		&payment.PaymentMethodID,
+		&payment.Provider,
+		&payment.ProviderIntentID,
+		&payment.Status,
+		&payment.TaxAmount,
+		&payment.UpdatedAt,
+	}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(destination...)
+	return payment, err
+}
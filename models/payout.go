@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	PayoutStatusFailed  = "failed"
+	PayoutStatusPaid    = "paid"
+	PayoutStatusPending = "pending"
+)
+
+type PayoutAccount struct {
+	ID                 string    `json:"id"`
+	AccountName        string    `json:"account_name"`
+	AccountNumberLast4 string    `json:"account_number_last4"`
+	BankCode           string    `json:"bank_code"`
+	CreatedAt          time.Time `json:"created_at"`
+	Provider           string    `json:"provider"`
+	ProviderAccountID  string    `json:"-"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	UserID             string    `json:"user_id"`
+	VerifiedAt         time.Time `json:"verified_at"`
+}
+
+// Amounts are in the minor unit of the currency
+type Payout struct {
+	ID              string     `json:"id"`
+	Amount          int        `json:"amount"`
+	Attempts        int        `json:"attempts"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Currency        string     `json:"currency"`
+	FailureReason   string     `json:"failure_reason"`
+	NextAttemptAt   time.Time  `json:"next_attempt_at"`
+	PaidAt          *time.Time `json:"paid_at"`
+	PayoutAccountID string     `json:"payout_account_id"`
+	Status          string     `json:"status"`
+	UserID          string     `json:"user_id"`
+}
+
+// Inserts the payout and moves its amount out of the host's balance straight
+// away, so the same earnings can never be swept twice
+func InsertPayout(ctx context.Context, querier Querier, payout *Payout) error {
+	sql := `
+	INSERT INTO payouts (amount, currency, payout_account_id, user_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, attempts, created_at, next_attempt_at, status`
+	arguments := []interface{}{payout.Amount, payout.Currency, payout.PayoutAccountID, payout.UserID}
+	destination := []interface{}{&payout.ID, &payout.Attempts, &payout.CreatedAt, &payout.NextAttemptAt, &payout.Status}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(destination...); err != nil {
+		return err
+	}
+
+	return InsertLedgerTransaction(ctx, querier, newPayoutTransaction(payout, fmt.Sprintf("Payout %v", payout.ID), payout.Amount))
+}
+
+// Gives the amount of a payout that ran out of attempts back to the host, so their
+// balance shows money that was never paid out
+func ReversePayout(ctx context.Context, querier Querier, payout *Payout) error {
+	transaction := newPayoutTransaction(payout, fmt.Sprintf("Failed payout %v", payout.ID), -payout.Amount)
+	return InsertLedgerTransaction(ctx, querier, transaction)
+}
+
+// Earnings, including booking charges, captured security deposits and promotions, are
+// settled once the trip they came from has been completed for longer than the
+// hold period. Whatever has already been paid out is deducted
+func SelectSettledHostEarnings(ctx context.Context, querier Querier, userId string, completedBefore time.Time) (int, error) {
+	earnings := 0
+	sql := `
+	SELECT COALESCE(SUM(
+		CASE
+			WHEN t.type = $2 THEN -e.amount
+			WHEN b.completed_at <= $3 THEN -e.amount
+			ELSE 0
+		END
+	), 0)
+	FROM ledger_entries AS e
+	JOIN ledger_accounts AS a ON e.account_id = a.id
+	JOIN ledger_transactions AS t ON e.transaction_id = t.id
+	LEFT JOIN payments AS p ON t.reference_type = $4 AND t.reference_id = p.id
+	LEFT JOIN security_deposits AS d ON t.reference_type = $5 AND t.reference_id = d.id
+	LEFT JOIN booking_charges AS bc ON t.reference_type = $6 AND t.reference_id = bc.id
+	LEFT JOIN bookings AS b ON b.id = COALESCE(
+		p.booking_id, d.booking_id, bc.booking_id, CASE WHEN t.reference_type = $8 THEN t.reference_id END
+	)
+	WHERE a.type = $7 AND a.user_id = $1`
+	arguments := []interface{}{
+		userId,
+		LedgerTransactionPayout,
+		completedBefore,
+		LedgerReferencePayment,
+		LedgerReferenceSecurityDeposit,
+		LedgerReferenceBookingCharge,
+		LedgerAccountHost,
+		LedgerReferenceBooking,
+	}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(&earnings)
+	return earnings, err
+}
+
+// A host has a single payout account, so verifying a new one replaces the old.
+// Payouts that ran out of attempts are queued again as the new account may work
+func UpsertPayoutAccount(ctx context.Context, querier Querier, account *PayoutAccount) error {
+	sql := `
+	INSERT INTO payout_accounts (account_name, account_number_last4, bank_code, provider, provider_account_id, user_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (user_id) DO UPDATE SET
+		account_name = EXCLUDED.account_name,
+		account_number_last4 = EXCLUDED.account_number_last4,
+		bank_code = EXCLUDED.bank_code,
+		provider = EXCLUDED.provider,
+		provider_account_id = EXCLUDED.provider_account_id,
+		updated_at = NOW(),
+		verified_at = NOW()
+	RETURNING id, created_at, updated_at, verified_at`
+	arguments := []interface{}{
+		account.AccountName,
+		account.AccountNumberLast4,
+		account.BankCode,
+		account.Provider,
+		account.ProviderAccountID,
+		account.UserID,
+	}
+	destination := []interface{}{&account.ID, &account.CreatedAt, &account.UpdatedAt, &account.VerifiedAt}
+	if err := querier.QueryRow(ctx, sql, arguments...).Scan(destination...); err != nil {
+		return err
+	}
+
+	// A failed payout gave its amount back to the host, so queuing it again takes it out again
+	sql = `
+	UPDATE payouts SET attempts = 0, next_attempt_at = NOW(), status = $1
+	WHERE user_id = $2 AND status = $3
+	RETURNING id, amount`
+	rows, err := querier.Query(ctx, sql, PayoutStatusPending, account.UserID, PayoutStatusFailed)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	payouts := []*Payout{}
+	for rows.Next() {
+		payout := &Payout{UserID: account.UserID}
+		if err = rows.Scan(&payout.ID, &payout.Amount); err != nil {
+			return err
+		}
+
+		payouts = append(payouts, payout)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, payout := range payouts {
+		transaction := newPayoutTransaction(payout, fmt.Sprintf("Payout %v", payout.ID), payout.Amount)
+		if err = InsertLedgerTransaction(ctx, querier, transaction); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A positive amount moves money out of the host's balance and a negative one returns it
+func newPayoutTransaction(payout *Payout, description string, amount int) *LedgerTransaction {
+	return &LedgerTransaction{
+		Description: description,
+		Entries: []LedgerEntry{
+			{AccountType: LedgerAccountHost, Amount: amount, UserID: payout.UserID},
+			{AccountType: LedgerAccountPaymentProvider, Amount: -amount},
+		},
+		ReferenceID:   payout.ID,
+		ReferenceType: LedgerReferencePayout,
+		Type:          LedgerTransactionPayout,
+	}
+}
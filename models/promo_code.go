@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+const (
+	PromoCodeDiscountTypeFixed      = "fixed"
+	PromoCodeDiscountTypePercentage = "percentage"
+)
+
+var (
+	ErrPromoCodeExhausted     = errors.New("promo code has reached its usage limit")
+	ErrPromoCodeExpired       = errors.New("promo code has expired")
+	ErrPromoCodeFirstTripOnly = errors.New("promo code is only for a first trip")
+	ErrPromoCodeTripTooShort  = errors.New("trip is too short for the promo code")
+)
+
+// DiscountValue is a whole percent of the rental for percentage discounts and an
+// amount in the minor unit of the currency for fixed ones. Redemptions are only
+// counted while their booking is going ahead
+type PromoCode struct {
+	ID                    string     `json:"id"`
+	Code                  string     `json:"code"`
+	CreatedAt             time.Time  `json:"created_at"`
+	DiscountType          string     `json:"discount_type"`
+	DiscountValue         int        `json:"discount_value"`
+	ExpiresAt             *time.Time `json:"expires_at"`
+	FirstTripOnly         bool       `json:"first_trip_only"`
+	MaxRedemptions        *int       `json:"max_redemptions"`
+	MaxRedemptionsPerUser int        `json:"max_redemptions_per_user"`
+	MinTripDays           int        `json:"min_trip_days"`
+	UserID                *string    `json:"user_id"`
+}
+
+// What a renter pays for a trip once a promo code and their credits are taken off
+// its rental. Both together never bring the total below config.MinimumPaymentAmount.
+// Taxes are charged on the rental after the promo code but before credits
+type BookingQuote struct {
+	CreditAmount   int        `json:"credit_amount"`
+	Currency       string     `json:"currency"`
+	Days           int        `json:"days"`
+	DiscountAmount int        `json:"discount_amount"`
+	PromoCode      *PromoCode `json:"promo_code"`
+	Subtotal       int        `json:"subtotal"`
+	TaxAmount      int        `json:"tax_amount"`
+	TaxLines       []TaxLine  `json:"tax_lines"`
+	TotalAmount    int        `json:"total_amount"`
+}
+
+func NewBookingQuote(startAt, endAt time.Time, rentalFee int) *BookingQuote {
+	days := CalculateBookingDays(startAt, endAt)
+	return &BookingQuote{
+		Currency:    config.Currency,
+		Days:        days,
+		Subtotal:    days * rentalFee,
+		TaxLines:    []TaxLine{},
+		TotalAmount: days * rentalFee,
+	}
+}
+
+func (quote *BookingQuote) ApplyPromoCode(promoCode *PromoCode) {
+	discount := promoCode.DiscountValue
+	if promoCode.DiscountType == PromoCodeDiscountTypePercentage {
+		discount = quote.Subtotal * promoCode.DiscountValue / 100
+	}
+
+	quote.PromoCode = promoCode
+	quote.DiscountAmount = quote.reduceTotal(discount)
+}
+
+func (quote *BookingQuote) ApplyCredits(balance int) {
+	quote.CreditAmount = quote.reduceTotal(balance)
+}
+
+func (quote *BookingQuote) ApplyTaxes(rules []TaxRule) {
+	quote.TaxLines = CalculateTaxLines(rules, quote.Subtotal-quote.DiscountAmount, quote.Days)
+	quote.TaxAmount = SumTaxLines(quote.TaxLines)
+	quote.TotalAmount += quote.TaxAmount
+}
+
+func (quote *BookingQuote) reduceTotal(amount int) int {
+	if limit := quote.TotalAmount - config.MinimumPaymentAmount; amount > limit {
+		amount = limit
+	}
+
+	if amount < 0 {
+		amount = 0
+	}
+
+	quote.TotalAmount -= amount
+	return amount
+}
+
+// Returns which of the promo code's conditions the renter's trip doesn't meet, if
+// any. Has to be checked before the booking is inserted as it counts as a trip
+func (promoCode *PromoCode) Check(ctx context.Context, querier Querier, userId string, days int) error {
+	if promoCode.ExpiresAt != nil && !promoCode.ExpiresAt.After(time.Now()) {
+		return ErrPromoCodeExpired
+	}
+
+	if days < promoCode.MinTripDays {
+		return ErrPromoCodeTripTooShort
+	}
+
+	redemptionsCount, userRedemptionsCount, tripsCount := 0, 0, 0
+	sql := `
+	SELECT
+		(SELECT COUNT(*) FROM promo_redemptions AS r JOIN bookings AS b ON r.booking_id = b.id
+			WHERE r.promo_code_id = $1 AND b.status = ANY($3)),
+		(SELECT COUNT(*) FROM promo_redemptions AS r JOIN bookings AS b ON r.booking_id = b.id
+			WHERE r.promo_code_id = $1 AND r.user_id = $2 AND b.status = ANY($3)),
+		(SELECT COUNT(*) FROM bookings WHERE user_id = $2 AND status = ANY($3))`
+	statuses := append([]string{BookingStatusCompleted}, BlockingBookingStatuses...)
+	destination := []interface{}{&redemptionsCount, &userRedemptionsCount, &tripsCount}
+	if err := querier.QueryRow(ctx, sql, promoCode.ID, userId, statuses).Scan(destination...); err != nil {
+		return err
+	}
+
+	if promoCode.FirstTripOnly && tripsCount > 0 {
+		return ErrPromoCodeFirstTripOnly
+	}
+
+	if promoCode.MaxRedemptions != nil && redemptionsCount >= *promoCode.MaxRedemptions {
+		return ErrPromoCodeExhausted
+	}
+
+	if userRedemptionsCount >= promoCode.MaxRedemptionsPerUser {
+		return ErrPromoCodeExhausted
+	}
+
+	return nil
+}
+
+func InsertPromoCode(ctx context.Context, querier Querier, promoCode *PromoCode) error {
+	sql := `
+	INSERT INTO promo_codes (
+		code, discount_type, discount_value, expires_at, first_trip_only, max_redemptions, max_redemptions_per_user, min_trip_days, user_id
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at`
+	arguments := []interface{}{
+		promoCode.Code,
+		promoCode.DiscountType,
+		promoCode.DiscountValue,
+		promoCode.ExpiresAt,
+		promoCode.FirstTripOnly,
+		promoCode.MaxRedemptions,
+		promoCode.MaxRedemptionsPerUser,
+		promoCode.MinTripDays,
+		promoCode.UserID,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&promoCode.ID, &promoCode.CreatedAt)
+}
+
+func InsertPromoRedemption(ctx context.Context, querier Querier, booking *Booking, promoCode *PromoCode) error {
+	sql := "INSERT INTO promo_redemptions (booking_id, discount_amount, promo_code_id, user_id) VALUES ($1, $2, $3, $4)"
+	_, err := querier.Exec(ctx, sql, booking.ID, booking.DiscountAmount, promoCode.ID, booking.UserID)
+	return err
+}
+
+// Codes are matched case-insensitively as they are stored in upper case
+func SelectPromoCode(ctx context.Context, querier Querier, code string) (*PromoCode, error) {
+	return selectPromoCode(ctx, querier, code, "")
+}
+
+// Locks the promo code so that concurrent bookings can't redeem it past its limits
+func SelectPromoCodeForUpdate(ctx context.Context, querier Querier, code string) (*PromoCode, error) {
+	return selectPromoCode(ctx, querier, code, "FOR UPDATE")
+}
+
+func selectPromoCode(ctx context.Context, querier Querier, code string, lockingClause string) (*PromoCode, error) {
+	sql := `
+	SELECT id, code, created_at, discount_type, discount_value, expires_at, first_trip_only, max_redemptions,
+		max_redemptions_per_user, min_trip_days, user_id
+	FROM promo_codes
+	WHERE code = upper($1) ` + lockingClause
+	promoCode := &PromoCode{}
+	destination := []interface{}{
+		&promoCode.ID,
+		&promoCode.Code,
+		&promoCode.CreatedAt,
+		&promoCode.DiscountType,
+		&promoCode.DiscountValue,
+		&promoCode.ExpiresAt,
+		&promoCode.FirstTripOnly,
+		&promoCode.MaxRedemptions,
+		&promoCode.MaxRedemptionsPerUser,
+		&promoCode.MinTripDays,
+		&promoCode.UserID,
+	}
+	err := querier.QueryRow(ctx, sql, code).Scan(destination...)
+	return promoCode, err
+}
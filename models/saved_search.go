@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+const (
+	SavedSearchFrequencyDaily   = "daily"
+	SavedSearchFrequencyInstant = "instant"
+)
+
+// Instant saved searches are sent as soon as a listing matches and daily ones
+// are sent as a digest at most once a day. Listings changed after MatchedAt
+// haven't been matched against the search yet
+type SavedSearch struct {
+	ID         string               `json:"id"`
+	CreatedAt  time.Time            `json:"created_at"`
+	Filters    VehicleSearchFilters `json:"filters"`
+	Frequency  string               `json:"frequency"`
+	MatchedAt  time.Time            `json:"-"`
+	Name       string               `json:"name"`
+	NotifiedAt *time.Time           `json:"notified_at"`
+	UserID     string               `json:"user_id"`
+}
+
+// A saved search for dates that have started can't match anything anymore
+func (savedSearch *SavedSearch) IsStale() bool {
+	return !savedSearch.Filters.StartAt.IsZero() && savedSearch.Filters.StartAt.Before(time.Now())
+}
+
+// Records the listed vehicles, other than the user's own, that were changed
+// after the search was last matched and up to until and now match it. A vehicle's
+// updated_at is when the change started, so a change committed after the last
+// match could be older than it. Going back SavedSearchMatchOverlap catches those
+// and the vehicles matched already are skipped
+func (savedSearch *SavedSearch) Match(ctx context.Context, querier Querier, until time.Time) error {
+	since := savedSearch.MatchedAt.Add(-config.SavedSearchMatchOverlap)
+	if since.Before(savedSearch.CreatedAt) {
+		since = savedSearch.CreatedAt
+	}
+
+	search := savedSearch.Filters.Build(savedSearch.ID, savedSearch.UserID, since, until)
+	conditions := append(search.Conditions, "v.user_id <> $2", "v.updated_at > $3", "v.updated_at <= $4")
+	sql := fmt.Sprintf(`
+	INSERT INTO saved_search_matches (saved_search_id, vehicle_id)
+	SELECT $1::uuid, v.id FROM vehicles AS v
+	WHERE %v
+	ON CONFLICT DO NOTHING`, strings.Join(conditions, " AND "))
+	if _, err := querier.Exec(ctx, sql, search.Arguments...); err != nil {
+		return err
+	}
+
+	_, err := querier.Exec(ctx, "UPDATE saved_searches SET matched_at = $2 WHERE id = $1", savedSearch.ID, until)
+	return err
+}
+
+func InsertSavedSearch(ctx context.Context, querier Querier, savedSearch *SavedSearch) error {
+	sql := `
+	INSERT INTO saved_searches (filters, frequency, name, user_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, matched_at`
+	arguments := []interface{}{savedSearch.Filters, savedSearch.Frequency, savedSearch.Name, savedSearch.UserID}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&savedSearch.ID, &savedSearch.CreatedAt, &savedSearch.MatchedAt)
+}
+
+// Only the saved searches last matched before until are returned
+func SelectSavedSearchesToMatch(ctx context.Context, querier Querier, until time.Time) ([]*SavedSearch, error) {
+	sql := `
+	SELECT id, created_at, filters, frequency, matched_at, name, notified_at, user_id
+	FROM saved_searches
+	WHERE matched_at < $1`
+	rows, err := querier.Query(ctx, sql, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	savedSearches := []*SavedSearch{}
+	for rows.Next() {
+		savedSearch := &SavedSearch{}
+		destination := []interface{}{
+			&savedSearch.ID,
+			&savedSearch.CreatedAt,
+			&savedSearch.Filters,
+			&savedSearch.Frequency,
+			&savedSearch.MatchedAt,
+			&savedSearch.Name,
+			&savedSearch.NotifiedAt,
+			&savedSearch.UserID,
+		}
+		if err = rows.Scan(destination...); err != nil {
+			return nil, err
+		}
+
+		savedSearches = append(savedSearches, savedSearch)
+	}
+
+	return savedSearches, rows.Err()
+}
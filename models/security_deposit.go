@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/services"
+)
+
+// A security deposit is held on the renter's payment method when the trip starts
+// and is only captured to settle a damage claim. Amounts are in the minor unit of
+// the currency
+type SecurityDeposit struct {
+	ID               string    `json:"id"`
+	Amount           int       `json:"amount"`
+	AmountCaptured   int       `json:"amount_captured"`
+	BookingID        string    `json:"booking_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	Currency         string    `json:"currency"`
+	Provider         string    `json:"provider"`
+	ProviderIntentID string    `json:"-"`
+	Status           string    `json:"status"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Copies the provider's view of the intent onto the deposit and credits the host
+// with whatever was captured. Like payments, a deposit never moves backwards
+func (deposit *SecurityDeposit) Apply(ctx context.Context, querier Querier, intent *services.PaymentIntent) error {
+	capturedAmount := intent.AmountCaptured - deposit.AmountCaptured
+	isStale := capturedAmount < 0 || paymentStatusRanks[intent.Status] < paymentStatusRanks[deposit.Status]
+	if isStale || (capturedAmount == 0 && intent.Status == deposit.Status) {
+		return nil
+	}
+
+	sql := `
+	UPDATE security_deposits SET amount_captured = $1, status = $2, updated_at = NOW()
+	WHERE id = $3
+	RETURNING updated_at`
+	if err := querier.QueryRow(ctx, sql, intent.AmountCaptured, intent.Status, deposit.ID).Scan(&deposit.UpdatedAt); err != nil {
+		return err
+	}
+
+	deposit.AmountCaptured = intent.AmountCaptured
+	deposit.Status = intent.Status
+	return postSecurityDepositToLedger(ctx, querier, deposit, capturedAmount)
+}
+
+func InsertSecurityDeposit(ctx context.Context, querier Querier, deposit *SecurityDeposit) error {
+	sql := `
+	INSERT INTO security_deposits (amount, booking_id, currency, provider, provider_intent_id, status)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, updated_at`
+	arguments := []interface{}{
+		deposit.Amount,
+		deposit.BookingID,
+		deposit.Currency,
+		deposit.Provider,
+		deposit.ProviderIntentID,
+		deposit.Status,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&deposit.ID, &deposit.CreatedAt, &deposit.UpdatedAt)
+}
+
+func SelectSecurityDepositByBookingForUpdate(ctx context.Context, querier Querier, bookingId string) (*SecurityDeposit, error) {
+	return selectSecurityDepositForUpdate(ctx, querier, "booking_id = $1", bookingId)
+}
+
+func SelectSecurityDepositByIntentForUpdate(ctx context.Context, querier Querier, provider string, intentId string) (*SecurityDeposit, error) {
+	return selectSecurityDepositForUpdate(ctx, querier, "provider = $1 AND provider_intent_id = $2", provider, intentId)
+}
+
+// The host keeps all of a captured deposit since it pays for damage to their
+// vehicle, so no platform fee is taken from it
+func postSecurityDepositToLedger(ctx context.Context, querier Querier, deposit *SecurityDeposit, capturedAmount int) error {
+	if capturedAmount == 0 {
+		return nil
+	}
+
+	hostId, renterId, err := selectBookingParticipants(ctx, querier, deposit.BookingID)
+	if err != nil {
+		return err
+	}
+
+	transaction := &LedgerTransaction{
+		Description: fmt.Sprintf("Damage claim for booking %v", deposit.BookingID),
+		Entries: []LedgerEntry{
+			{AccountType: LedgerAccountRenter, Amount: capturedAmount, UserID: renterId},
+			{AccountType: LedgerAccountHost, Amount: -capturedAmount, UserID: hostId},
+			{AccountType: LedgerAccountPaymentProvider, Amount: capturedAmount},
+			{AccountType: LedgerAccountRenter, Amount: -capturedAmount, UserID: renterId},
+		},
+		ReferenceID:   deposit.ID,
+		ReferenceType: LedgerReferenceSecurityDeposit,
+		Type:          LedgerTransactionClaim,
+	}
+	return InsertLedgerTransaction(ctx, querier, transaction)
+}
+
+func selectSecurityDepositForUpdate(ctx context.Context, querier Querier, condition string, arguments ...interface{}) (*SecurityDeposit, error) {
+	sql := `
+	SELECT id, amount, amount_captured, booking_id, created_at, currency, provider, provider_intent_id, status, updated_at
+	FROM security_deposits
+	WHERE ` + condition + `
+	FOR UPDATE`
+	deposit := &SecurityDeposit{}
+	destination := []interface{}{
+		&deposit.ID,
+		&deposit.Amount,
+		&deposit.AmountCaptured,
+		&deposit.BookingID,
+		&deposit.CreatedAt,
+		&deposit.Currency,
+		&deposit.Provider,
+		&deposit.ProviderIntentID,
+		&deposit.Status,
+		&deposit.UpdatedAt,
+	}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(destination...)
+	return deposit, err
+}
@@ -1,8 +1,12 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 )
 
 const (
@@ -81,3 +85,11 @@ func buildQuery(options SQLOptions) string {
 		panic("invalid sql statement")
 	}
 }
+
+// Querier is implemented by both *pgxpool.Pool and pgx.Tx so that a query can
+// take part in a transaction when the caller has one open
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
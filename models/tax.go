@@ -0,0 +1,445 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TaxLineTypeRentalTax = "rental_tax"
+	TaxLineTypeSurcharge = "surcharge"
+	TaxLineTypeVAT       = "vat"
+)
+
+// The taxes of a jurisdiction. A rule applies to a vehicle when AddressKeyword is
+// one of the comma separated parts of its address, like its city or state, and its
+// location is within the bounds, each only when set, so a rule with neither
+// applies to every vehicle. Rates are in basis points and PerDaySurcharge is in
+// the minor unit of the currency
+type TaxRule struct {
+	ID                   string    `json:"id"`
+	AddressKeyword       *string   `json:"address_keyword"`
+	CreatedAt            time.Time `json:"created_at"`
+	Jurisdiction         string    `json:"jurisdiction"`
+	MaxLatitude          *float64  `json:"max_latitude"`
+	MaxLongitude         *float64  `json:"max_longitude"`
+	MinLatitude          *float64  `json:"min_latitude"`
+	MinLongitude         *float64  `json:"min_longitude"`
+	PerDaySurcharge      int       `json:"per_day_surcharge"`
+	RentalTaxBasisPoints int       `json:"rental_tax_basis_points"`
+	VATBasisPoints       int       `json:"vat_basis_points"`
+}
+
+// A tax a renter was charged. BasisPoints is 0 for surcharges
+type TaxLine struct {
+	Amount       int    `json:"amount"`
+	BasisPoints  int    `json:"basis_points"`
+	Description  string `json:"description"`
+	Jurisdiction string `json:"jurisdiction"`
+	Type         string `json:"type"`
+}
+
+// What the host earned and the taxes collected on their bookings in a month.
+// GrossAmount is what was paid towards them, including the discounts and credits
+// the platform paid for
+type TaxSummary struct {
+	Bookings    int
+	Earnings    int
+	GrossAmount int
+	Month       time.Month
+	PlatformFee int
+	TaxAmount   int
+}
+
+// Rental taxes are a share of taxableAmount and surcharges are charged for each of
+// the days. VAT is charged last, on taxableAmount along with the other taxes. The
+// lines come out rental taxes first, then surcharges, then VAT
+func CalculateTaxLines(rules []TaxRule, taxableAmount int, days int) []TaxLine {
+	if taxableAmount < 0 {
+		taxableAmount = 0
+	}
+
+	lines := []TaxLine{}
+	for _, rule := range rules {
+		if amount := taxableAmount * rule.RentalTaxBasisPoints / 10000; amount > 0 {
+			lines = append(lines, TaxLine{
+				Amount:       amount,
+				BasisPoints:  rule.RentalTaxBasisPoints,
+				Description:  fmt.Sprintf("%v rental tax (%v)", rule.Jurisdiction, formatBasisPoints(rule.RentalTaxBasisPoints)),
+				Jurisdiction: rule.Jurisdiction,
+				Type:         TaxLineTypeRentalTax,
+			})
+		}
+	}
+
+	for _, rule := range rules {
+		if amount := days * rule.PerDaySurcharge; amount > 0 {
+			lines = append(lines, TaxLine{
+				Amount:       amount,
+				Description:  fmt.Sprintf("%v surcharge for %d day(s)", rule.Jurisdiction, days),
+				Jurisdiction: rule.Jurisdiction,
+				Type:         TaxLineTypeSurcharge,
+			})
+		}
+	}
+
+	vatBase := taxableAmount + SumTaxLines(lines)
+	for _, rule := range rules {
+		if amount := vatBase * rule.VATBasisPoints / 10000; amount > 0 {
+			lines = append(lines, TaxLine{
+				Amount:       amount,
+				BasisPoints:  rule.VATBasisPoints,
+				Description:  fmt.Sprintf("%v VAT (%v)", rule.Jurisdiction, formatBasisPoints(rule.VATBasisPoints)),
+				Jurisdiction: rule.Jurisdiction,
+				Type:         TaxLineTypeVAT,
+			})
+		}
+	}
+
+	return lines
+}
+
+func SumTaxLines(lines []TaxLine) int {
+	total := 0
+	for _, line := range lines {
+		total += line.Amount
+	}
+
+	return total
+}
+
+// Trip lines have no chargeId and are replaced whenever the trip is priced again
+func InsertBookingTaxLines(ctx context.Context, querier Querier, bookingId string, chargeId *string, lines []TaxLine) error {
+	if chargeId == nil {
+		if _, err := querier.Exec(ctx, "DELETE FROM booking_tax_lines WHERE booking_id = $1 AND charge_id IS NULL", bookingId); err != nil {
+			return err
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	amounts, basisPoints, descriptions, jurisdictions, types := []int{}, []int{}, []string{}, []string{}, []string{}
+	for _, line := range lines {
+		amounts = append(amounts, line.Amount)
+		basisPoints = append(basisPoints, line.BasisPoints)
+		descriptions = append(descriptions, line.Description)
+		jurisdictions = append(jurisdictions, line.Jurisdiction)
+		types = append(types, line.Type)
+	}
+
+	sql := `
+	INSERT INTO booking_tax_lines (amount, basis_points, booking_id, charge_id, description, jurisdiction, type)
+	SELECT l.amount, l.basis_points, $1, $2, l.description, l.jurisdiction, l.type
+	FROM unnest($3::int[], $4::int[], $5::text[], $6::text[], $7::text[]) AS l (amount, basis_points, description, jurisdiction, type)`
+	_, err := querier.Exec(ctx, sql, bookingId, chargeId, amounts, basisPoints, descriptions, jurisdictions, types)
+	return err
+}
+
+func InsertTaxRule(ctx context.Context, querier Querier, rule *TaxRule) error {
+	sql := `
+	INSERT INTO tax_rules (
+		address_keyword, jurisdiction, max_latitude, max_longitude, min_latitude, min_longitude, per_day_surcharge,
+		rental_tax_basis_points, vat_basis_points
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at`
+	arguments := []interface{}{
+		rule.AddressKeyword,
+		rule.Jurisdiction,
+		rule.MaxLatitude,
+		rule.MaxLongitude,
+		rule.MinLatitude,
+		rule.MinLongitude,
+		rule.PerDaySurcharge,
+		rule.RentalTaxBasisPoints,
+		rule.VATBasisPoints,
+	}
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&rule.ID, &rule.CreatedAt)
+}
+
+// A retried charge takes over the tax lines of the charge that failed
+func MoveBookingTaxLines(ctx context.Context, querier Querier, fromChargeId string, toChargeId string) error {
+	_, err := querier.Exec(ctx, "UPDATE booking_tax_lines SET charge_id = $1 WHERE charge_id = $2", toChargeId, fromChargeId)
+	return err
+}
+
+// The trip's lines, leaving out the ones charged with booking charges
+func SelectBookingTaxLines(ctx context.Context, querier Querier, bookingId string) ([]TaxLine, error) {
+	sql := `
+	SELECT amount, basis_points, description, jurisdiction, type
+	FROM booking_tax_lines
+	WHERE booking_id = $1 AND charge_id IS NULL
+	ORDER BY created_at, array_position(ARRAY['rental_tax', 'surcharge', 'vat'], type), jurisdiction`
+	rows, err := querier.Query(ctx, sql, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []TaxLine{}
+	for rows.Next() {
+		line := TaxLine{}
+		if err = rows.Scan(&line.Amount, &line.BasisPoints, &line.Description, &line.Jurisdiction, &line.Type); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+// Sums up the ledger entries of the host's bookings by the month of the year, in
+// UTC, they were made in. Every month of the year is returned, including the ones
+// without any entries
+func SelectHostTaxSummaries(ctx context.Context, querier Querier, userId string, year int) ([]TaxSummary, error) {
+	sql := `
+	WITH cte_transactions AS (
+		SELECT t.id, b.id AS booking_id, date_part('month', t.created_at AT TIME ZONE 'UTC')::int AS month
+		FROM ledger_transactions AS t
+		LEFT JOIN payments AS p ON t.reference_type = $4 AND t.reference_id = p.id
+		LEFT JOIN security_deposits AS d ON t.reference_type = $5 AND t.reference_id = d.id
+		LEFT JOIN booking_charges AS bc ON t.reference_type = $6 AND t.reference_id = bc.id
+		JOIN bookings AS b ON b.id = COALESCE(
+			p.booking_id, d.booking_id, bc.booking_id, CASE WHEN t.reference_type = $7 THEN t.reference_id END
+		)
+		JOIN vehicles AS v ON b.vehicle_id = v.id
+		WHERE v.user_id = $1 AND t.created_at >= $2 AND t.created_at < $3
+	)
+	SELECT t.month,
+		COUNT(DISTINCT t.booking_id),
+		COALESCE(-SUM(e.amount) FILTER (WHERE a.type = $8 AND a.user_id = $1), 0),
+		COALESCE(-SUM(e.amount) FILTER (WHERE a.type = $9), 0),
+		COALESCE(-SUM(e.amount) FILTER (WHERE a.type = $10), 0)
+	FROM cte_transactions AS t
+	JOIN ledger_entries AS e ON e.transaction_id = t.id
+	JOIN ledger_accounts AS a ON e.account_id = a.id
+	GROUP BY t.month`
+	arguments := []interface{}{
+		userId,
+		time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		LedgerReferencePayment,
+		LedgerReferenceSecurityDeposit,
+		LedgerReferenceBookingCharge,
+		LedgerReferenceBooking,
+		LedgerAccountHost,
+		LedgerAccountPlatformRevenue,
+		LedgerAccountTax,
+	}
+	rows, err := querier.Query(ctx, sql, arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]TaxSummary, 12)
+	for i := range summaries {
+		summaries[i].Month = time.Month(i + 1)
+	}
+
+	for rows.Next() {
+		month, summary := 0, TaxSummary{}
+		if err = rows.Scan(&month, &summary.Bookings, &summary.Earnings, &summary.PlatformFee, &summary.TaxAmount); err != nil {
+			return nil, err
+		}
+
+		summary.GrossAmount = summary.Earnings + summary.PlatformFee + summary.TaxAmount
+		summary.Month = time.Month(month)
+		summaries[month-1] = summary
+	}
+
+	return summaries, rows.Err()
+}
+
+// Ordered by jurisdiction so that a trip's lines always come out the same way.
+// Only the most specific of the rules that charge a tax is applied for it, see
+// applyTaxRulePrecedence
+func SelectVehicleTaxRules(ctx context.Context, querier Querier, vehicleId string) ([]TaxRule, error) {
+	sql := `
+	SELECT r.id, r.address_keyword, r.created_at, r.jurisdiction, r.max_latitude, r.max_longitude, r.min_latitude, r.min_longitude,
+		r.per_day_surcharge, r.rental_tax_basis_points, r.vat_basis_points
+	FROM tax_rules AS r
+	JOIN vehicles AS v ON v.id = $1
+	WHERE (r.address_keyword IS NULL OR lower(r.address_keyword) IN (
+			SELECT trim(part) FROM unnest(string_to_array(lower(v.address), ',')) AS part
+		))
+		AND (r.min_latitude IS NULL OR (
+			v.location IS NOT NULL
+			AND v.location[0] BETWEEN r.min_latitude AND r.max_latitude
+			AND v.location[1] BETWEEN r.min_longitude AND r.max_longitude
+		))
+	ORDER BY r.jurisdiction, r.created_at`
+	rows, err := querier.Query(ctx, sql, vehicleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []TaxRule{}
+	for rows.Next() {
+		rule := TaxRule{}
+		destination := []interface{}{
+			&rule.ID,
+			&rule.AddressKeyword,
+			&rule.CreatedAt,
+			&rule.Jurisdiction,
+			&rule.MaxLatitude,
+			&rule.MaxLongitude,
+			&rule.MinLatitude,
+			&rule.MinLongitude,
+			&rule.PerDaySurcharge,
+			&rule.RentalTaxBasisPoints,
+			&rule.VATBasisPoints,
+		}
+		if err = rows.Scan(destination...); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applyTaxRulePrecedence(rules), nil
+}
+
+// A city's VAT replaces the national one rather than being added to it. A rule
+// with both an address keyword and bounds is more specific than one with either,
+// which is more specific than one with neither. The latest rule wins between rules
+// that are as specific. Rules are left out once they don't charge anything
+func applyTaxRulePrecedence(rules []TaxRule) []TaxRule {
+	rentalTax, surcharge, vat := -1, -1, -1
+	isMoreSpecific := func(i int, j int) bool {
+		if j == -1 {
+			return true
+		}
+
+		if a, b := rules[i].specificity(), rules[j].specificity(); a != b {
+			return a > b
+		}
+
+		return rules[i].CreatedAt.After(rules[j].CreatedAt)
+	}
+	for i, rule := range rules {
+		if rule.RentalTaxBasisPoints > 0 && isMoreSpecific(i, rentalTax) {
+			rentalTax = i
+		}
+
+		if rule.PerDaySurcharge > 0 && isMoreSpecific(i, surcharge) {
+			surcharge = i
+		}
+
+		if rule.VATBasisPoints > 0 && isMoreSpecific(i, vat) {
+			vat = i
+		}
+	}
+
+	applicable := []TaxRule{}
+	for i, rule := range rules {
+		if i != rentalTax {
+			rule.RentalTaxBasisPoints = 0
+		}
+
+		if i != surcharge {
+			rule.PerDaySurcharge = 0
+		}
+
+		if i != vat {
+			rule.VATBasisPoints = 0
+		}
+
+		if rule.RentalTaxBasisPoints > 0 || rule.PerDaySurcharge > 0 || rule.VATBasisPoints > 0 {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	return applicable
+}
+
+func (rule *TaxRule) specificity() int {
+	specificity := 0
+	if rule.AddressKeyword != nil {
+		specificity++
+	}
+
+	if rule.MinLatitude != nil {
+		specificity++
+	}
+
+	return specificity
+}
+
+// The rules the booking was priced with, rebuilt from its trip lines, so that its
+// changes and overage fees are taxed at the same rates however the rules have
+// changed since. Surcharges were charged for each day of the trip
+func SelectBookingTaxRules(ctx context.Context, querier Querier, booking *Booking) ([]TaxRule, error) {
+	lines, err := SelectBookingTaxLines(ctx, querier, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	days := CalculateBookingDays(booking.StartAt, booking.EndAt)
+	indexes := map[string]int{}
+	rules := []TaxRule{}
+	for _, line := range lines {
+		index, ok := indexes[line.Jurisdiction]
+		if !ok {
+			index = len(rules)
+			indexes[line.Jurisdiction] = index
+			rules = append(rules, TaxRule{Jurisdiction: line.Jurisdiction})
+		}
+
+		switch line.Type {
+		case TaxLineTypeRentalTax:
+			rules[index].RentalTaxBasisPoints += line.BasisPoints
+		case TaxLineTypeSurcharge:
+			if days > 0 {
+				rules[index].PerDaySurcharge += line.Amount / days
+			}
+		case TaxLineTypeVAT:
+			rules[index].VATBasisPoints += line.BasisPoints
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Jurisdiction < rules[j].Jurisdiction })
+	return rules, nil
+}
+
+// Taxes are collected out of every amount captured in proportion to the tax of
+// the payment or charge it was captured from, so that refunds give back the same
+// share
+func calculateTaxShare(amount int, taxAmount int, totalAmount int) int {
+	if totalAmount <= 0 || taxAmount <= 0 {
+		return 0
+	}
+
+	share := amount * taxAmount / totalAmount
+	if share > taxAmount {
+		share = taxAmount
+	}
+
+	if share > amount {
+		share = amount
+	}
+
+	return share
+}
+
+// Formats basis points as a percent like 7.5%
+func formatBasisPoints(basisPoints int) string {
+	percent := strconv.Itoa(basisPoints / 100)
+	if fraction := basisPoints % 100; fraction > 0 {
+		percent += strings.TrimRight(fmt.Sprintf(".%02d", fraction), "0")
+	}
+
+	return percent + "%"
+}
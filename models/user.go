@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
@@ -26,26 +27,52 @@ type passwordConfig struct {
 }
 
 type User struct {
-	ID              string    `json:"id"`
-	AverageRating   float64   `json:"average_rating"`
-	Email           string    `json:"email,omitempty"`
-	Firstname       string    `json:"firstname,omitempty"`
-	Image           string    `json:"image"`
-	Is2FAEnabled    bool      `json:"is_2fa_enabled"`
-	IsEmailVerified bool      `json:"is_email_verified"`
-	IsPhoneVerified bool      `json:"is_phone_verified"`
-	Lastname        string    `json:"lastname,omitempty"`
-	OTPSecretKey    string    `json:"otp_secret_key,omitempty"`
-	PhoneNo         string    `json:"phone_no,omitempty"`
-	Password        string    `json:"password,omitempty"`
-	ReviewsCount    int       `json:"reviews_count"`
-	TripsCount      int       `json:"trips_count"`
-	Vehicles        []gin.H   `json:"vehicles,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID                 string      `json:"id"`
+	AverageRating      float64     `json:"average_rating"`
+	Badges             []string    `json:"badges,omitempty"`
+	Email              string      `json:"email,omitempty"`
+	Firstname          string      `json:"firstname,omitempty"`
+	GuestStats         *GuestStats `json:"guest,omitempty"`
+	HostStats          *HostStats  `json:"host,omitempty"`
+	Image              string      `json:"image"`
+	Is2FAEnabled       bool        `json:"is_2fa_enabled"`
+	IsEmailVerified    bool        `json:"is_email_verified"`
+	IsIdentityVerified bool        `json:"is_identity_verified"`
+	IsPhoneVerified    bool        `json:"is_phone_verified"`
+	Lastname           string      `json:"lastname,omitempty"`
+	OTPSecretKey       string      `json:"otp_secret_key,omitempty"`
+	PhoneNo            string      `json:"phone_no,omitempty"`
+	Password           string      `json:"password,omitempty"`
+	ReviewsCount       int         `json:"reviews_count"`
+	Role               string      `json:"role"`
+	TripsCount         int         `json:"trips_count"`
+	CreatedAt          time.Time   `json:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at"`
 	// TODO: host and guest reviews_count, reviews, average_rating
 }
 
+// What a user's public profile shows about their trips as a renter
+type GuestStats struct {
+	TripsCount int `json:"trips_count"`
+}
+
+// What a user's public profile shows about their hosting
+type HostStats struct {
+	ListingsCount int `json:"listings_count"`
+	ResponseMetrics
+	TripsCount int `json:"trips_count"`
+}
+
+// Kept up to date by the host response metrics job. Rates are the share of
+// booking requests and messages answered in time and response times are median
+// minutes. They're nil when the host had nothing to answer in the period
+type ResponseMetrics struct {
+	ResponseRate30Days *float64 `json:"response_rate_30_days"`
+	ResponseRate90Days *float64 `json:"response_rate_90_days"`
+	ResponseTime30Days *int     `json:"response_time_30_days"`
+	ResponseTime90Days *int     `json:"response_time_90_days"`
+}
+
 func (user *User) NormalizeFields(new bool) {
 	user.Email = strings.ToLower(user.Email)
 	if new {
@@ -86,6 +113,7 @@ func (user *User) GenerateAccessToken() (string, error) {
 	claims := &services.AccessTokenClaims{
 		Email: user.Email,
 		ID:    user.ID,
+		Role:  user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Second * config.AccessTokenTTLInSeconds)),
 			Issuer:    config.ClientOrigin,
@@ -120,26 +148,134 @@ func (user *User) HashPassword() error {
 	return nil
 }
 
-func (user *User) SendEmailVerificationMail(token string) error {
-	name := fmt.Sprintf("%v %v", user.Firstname, user.Lastname)
-	to := mail.NewEmail(name, user.Email)
+// Attachments like the booking's invoice are only sent by mail
+func (user *User) SendBookingUpdatedMail(ctx context.Context, booking *Booking, title string, attachments ...services.MailAttachment) error {
+	link := fmt.Sprintf("%v/bookings/%v", config.ClientOrigin, booking.ID)
+	notification := &Notification{
+		Body:   fmt.Sprintf("Your booking from %v to %v is %v", booking.StartAt.Format("Jan 2"), booking.EndAt.Format("Jan 2"), booking.Status),
+		Data:   gin.H{"booking_id": booking.ID, "status": booking.Status},
+		Title:  title,
+		Type:   NotificationTypeBookingUpdated,
+		UserID: user.ID,
+	}
+	data := gin.H{"firstname": user.Firstname, "link": link, "status": booking.Status, "title": title}
+	option := services.MailOption{Attachments: attachments, Data: data, TemplateID: config.BookingUpdatedTemplateID}
+	return user.notify(ctx, notification, option)
+}
+
+func (user *User) SendCohostInvitationMail(ctx context.Context, cohost *Cohost, vehicleName string) error {
+	link := fmt.Sprintf("%v/account/cohost-invitations", config.ClientOrigin)
+	notification := &Notification{
+		Body:   fmt.Sprintf("You have been invited to co-host %v", vehicleName),
+		Data:   gin.H{"cohost_id": cohost.ID, "permissions": cohost.Permissions, "vehicle_id": cohost.VehicleID},
+		Title:  "You have been invited to co-host a vehicle",
+		Type:   NotificationTypeCohostInvitation,
+		UserID: user.ID,
+	}
+	data := gin.H{"firstname": user.Firstname, "link": link, "permissions": cohost.Permissions, "vehicle": vehicleName}
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.CohostInviteTemplateID})
+}
+
+func (user *User) SendEmailVerificationMail(ctx context.Context, token string) error {
 	link := fmt.Sprintf("%v/verify-email/%v", config.ClientOrigin, token)
+	notification := &Notification{
+		Body:   fmt.Sprintf("A verification link has been sent to %v", user.Email),
+		Title:  "Verify your email",
+		Type:   NotificationTypeEmailVerification,
+		UserID: user.ID,
+	}
 	data := gin.H{"link": link}
-
-	option := services.MailOption{To: to, Data: data, TemplateID: config.VerifyEmailTemplateID}
-	response, err := services.SendMail(option)
-	log.Printf("SendEmailVerificationMail StatusCode %+v\n", response.StatusCode)
-	return err
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.VerifyEmailTemplateID})
 }
 
-func (user *User) SendPasswordResetMail(token string) error {
-	name := fmt.Sprintf("%v %v", user.Firstname, user.Lastname)
-	to := mail.NewEmail(name, user.Email)
+func (user *User) SendPasswordResetMail(ctx context.Context, token string) error {
 	link := fmt.Sprintf("%v/reset-password/", config.ClientOrigin)
+	notification := &Notification{
+		Body:   "A link to reset your password has been sent to your email. Ignore it if you didn't ask for it",
+		Title:  "Password reset requested",
+		Type:   NotificationTypePasswordReset,
+		UserID: user.ID,
+	}
 	data := gin.H{"email": user.Email, "link": link, "token": token}
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.ResetPasswordTemplateID})
+}
+
+func (user *User) SendPayoutFailedMail(ctx context.Context, payout *Payout) error {
+	link := fmt.Sprintf("%v/account/payouts", config.ClientOrigin)
+	body := "We couldn't send your payout and will try again soon"
+	if payout.Status == PayoutStatusFailed {
+		body = "We couldn't send your payout. Please check your payout account"
+	}
 
-	option := services.MailOption{To: to, Data: data, TemplateID: config.ResetPasswordTemplateID}
+	notification := &Notification{
+		Body:   body,
+		Data:   gin.H{"amount": payout.Amount, "currency": payout.Currency, "payout_id": payout.ID, "status": payout.Status},
+		Title:  "Your payout failed",
+		Type:   NotificationTypePayoutFailed,
+		UserID: user.ID,
+	}
+	data := gin.H{"amount": payout.Amount, "body": body, "currency": payout.Currency, "firstname": user.Firstname, "link": link}
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.PayoutFailedTemplateID})
+}
+
+// Each match has the name of the saved search and the vehicle that matched it
+func (user *User) SendSavedSearchMail(ctx context.Context, matches []gin.H) error {
+	link := fmt.Sprintf("%v/account/saved-searches", config.ClientOrigin)
+	notification := &Notification{
+		Body:   fmt.Sprintf("%v new vehicles match your saved searches", len(matches)),
+		Data:   gin.H{"count": len(matches)},
+		Title:  "New vehicles match your saved searches",
+		Type:   NotificationTypeSavedSearch,
+		UserID: user.ID,
+	}
+	data := gin.H{"count": len(matches), "firstname": user.Firstname, "link": link, "matches": matches}
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.SavedSearchTemplateID})
+}
+
+func (user *User) SendUnreadMessagesMail(ctx context.Context, count int) error {
+	link := fmt.Sprintf("%v/messages", config.ClientOrigin)
+	notification := &Notification{
+		Body:   fmt.Sprintf("You have %v unread messages", count),
+		Data:   gin.H{"count": count},
+		Title:  "You have unread messages",
+		Type:   NotificationTypeUnreadMessages,
+		UserID: user.ID,
+	}
+	data := gin.H{"count": count, "firstname": user.Firstname, "link": link}
+	return user.notify(ctx, notification, services.MailOption{Data: data, TemplateID: config.UnreadMessagesTemplateID})
+}
+
+// Delivers the notification on the channels the user has enabled for its type.
+// SMS preferences are stored but nothing is sent until an SMS provider is set up
+func (user *User) notify(ctx context.Context, notification *Notification, option services.MailOption) error {
+	pool := services.GetPostgresConnectionPool()
+	preference, err := SelectNotificationPreference(ctx, pool, user.ID, notification.Type)
+	if err != nil {
+		return err
+	}
+
+	if preference.InApp {
+		if err = InsertNotification(ctx, pool, notification); err != nil {
+			return err
+		}
+
+		event := services.Event{Type: services.EventNotificationCreated, Data: notification}
+		if err = services.PublishEvent(ctx, user.ID, event); err != nil {
+			log.Printf("notify %v: %v\n", user.ID, err)
+		}
+	}
+
+	if !preference.Email {
+		return nil
+	}
+
+	name := fmt.Sprintf("%v %v", user.Firstname, user.Lastname)
+	option.To = mail.NewEmail(name, user.Email)
 	response, err := services.SendMail(option)
-	log.Printf("SendPasswordResetMail StatusCode %+v\n", response.StatusCode)
-	return err
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%v mail StatusCode %+v\n", notification.Type, response.StatusCode)
+	return nil
 }
@@ -1,24 +1,129 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	FuelTypeDiesel   = "diesel"
+	FuelTypeElectric = "electric"
+	FuelTypeHybrid   = "hybrid"
+	FuelTypePetrol   = "petrol"
+
+	TransmissionAutomatic = "automatic"
+	TransmissionManual    = "manual"
+)
+
+// The attributes renters filter on are null for listings whose host hasn't filled
+// them in yet. EVRange is in kilometres
 type Vehicle struct {
-	ID            string    `json:"id"`
-	Address       string    `json:"address,omitempty"`
-	AverageRating float64   `json:"average_rating"`
-	CreatedAt     time.Time `json:"created_at"`
-	Image         string    `json:"image"`
-	IsRented      bool      `json:"is_rented"`
-	Make          string    `json:"make"  binding:"required"`
-	Name          string    `json:"name" binding:"required"`
-	Location      *Location `json:"location,omitempty"`
-	RentalFee     int       `json:"rental_fee"`
-	ReviewsCount  int       `json:"reviews_count"`
-	TripsCount    int       `json:"trips_count"`
-	User          gin.H     `json:"user,omitempty"`
-	UserID        string    `json:"user_id,omitempty"`
+	ID                      string                  `json:"id"`
+	Address                 string                  `json:"address,omitempty"`
+	AverageRating           float64                 `json:"average_rating"`
+	CancellationPolicy      string                  `json:"cancellation_policy"`
+	Category                *string                 `json:"category"`
+	CreatedAt               time.Time               `json:"created_at"`
+	DailyMileageLimit       *int                    `json:"daily_mileage_limit"`
+	Description             string                  `json:"description"`
+	Doors                   *int                    `json:"doors"`
+	EVRange                 *int                    `json:"ev_range"`
+	ExternalID              *string                 `json:"external_id,omitempty"`
+	Features                []string                `json:"features"`
+	FuelFee                 int                     `json:"fuel_fee"`
+	FuelType                *string                 `json:"fuel_type"`
+	Image                   string                  `json:"image"`
+	InstantBook             bool                    `json:"instant_book"`
+	InstantBookRequirements InstantBookRequirements `json:"instant_book_requirements"`
+	IsRented                bool                    `json:"is_rented"`
+	Make                    string                  `json:"make"  binding:"required"`
+	Manufacturer            string                  `json:"manufacturer"`
+	Model                   *string                 `json:"model"`
+	Name                    string                  `json:"name" binding:"required"`
+	Location                *Location               `json:"location,omitempty"`
+	MileageFee              int                     `json:"mileage_fee"`
+	RentalFee               int                     `json:"rental_fee"`
+	ReviewsCount            int                     `json:"reviews_count"`
+	Seats                   *int                    `json:"seats"`
+	SecurityDeposit         int                     `json:"security_deposit"`
+	Transmission            *string                 `json:"transmission"`
+	TripsCount              int                     `json:"trips_count"`
+	User                    gin.H                   `json:"user,omitempty"`
+	UserID                  string                  `json:"user_id,omitempty"`
+	VIN                     *string                 `json:"vin,omitempty"`
+	Year                    *int                    `json:"year"`
+}
+
+// What a renter needs for their booking of an instant book vehicle to be confirmed
+// without the host. Renters always need a verified email and phone number, a
+// requirement of 0 isn't checked
+type InstantBookRequirements struct {
+	MinAccountAgeDays int     `json:"min_account_age_days"`
+	MinRating         float64 `json:"min_rating"`
+	MinTrips          int     `json:"min_trips"`
+}
+
+func (requirements *InstantBookRequirements) AreMetBy(ctx context.Context, querier Querier, userId string) (bool, error) {
+	eligible := false
+	sql := `
+	SELECT email_verified_at IS NOT NULL 
+		AND phone_verified_at IS NOT NULL 
+		AND created_at <= NOW() - make_interval(days => $2) 
+		AND average_rating >= $3 
+		AND trips_count >= $4
+	FROM users
+	WHERE id = $1`
+	arguments := []interface{}{userId, requirements.MinAccountAgeDays, requirements.MinRating, requirements.MinTrips}
+	err := querier.QueryRow(ctx, sql, arguments...).Scan(&eligible)
+	return eligible, err
+}
+
+func InsertVehicle(ctx context.Context, querier Querier, vehicle *Vehicle) error {
+	sql := `
+	INSERT INTO vehicles (
+		address, category, description, doors, ev_range, external_id, features, fuel_type, location, make, 
+		manufacturer, model, name, rental_fee, seats, transmission, user_id, vin, year
+	) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, POINT($9, $10), $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	RETURNING id, cancellation_policy, created_at`
+	arguments := append(vehicleListingArguments(vehicle), vehicle.UserID, vehicle.VIN, vehicle.Year)
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&vehicle.ID, &vehicle.CancellationPolicy, &vehicle.CreatedAt)
+}
+
+// Replaces what the host lists the vehicle with. Its pricing and booking settings
+// are left as they are, and so is its external id when none is given
+func UpdateVehicleListing(ctx context.Context, querier Querier, vehicle *Vehicle) error {
+	sql := `
+	UPDATE vehicles SET 
+		address = $1, category = $2, description = $3, doors = $4, ev_range = $5, external_id = COALESCE($6, external_id), features = $7, 
+		fuel_type = $8, location = POINT($9, $10), make = $11, manufacturer = $12, model = $13, name = $14, 
+		rental_fee = $15, seats = $16, transmission = $17, vin = $19, year = $20
+	WHERE id = $18
+	RETURNING cancellation_policy, created_at`
+	arguments := append(vehicleListingArguments(vehicle), vehicle.ID, vehicle.VIN, vehicle.Year)
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&vehicle.CancellationPolicy, &vehicle.CreatedAt)
+}
+
+func vehicleListingArguments(vehicle *Vehicle) []interface{} {
+	return []interface{}{
+		vehicle.Address,
+		vehicle.Category,
+		vehicle.Description,
+		vehicle.Doors,
+		vehicle.EVRange,
+		vehicle.ExternalID,
+		vehicle.Features,
+		vehicle.FuelType,
+		vehicle.Location.Latitude,
+		vehicle.Location.Longitude,
+		vehicle.Make,
+		vehicle.Manufacturer,
+		vehicle.Model,
+		vehicle.Name,
+		vehicle.RentalFee,
+		vehicle.Seats,
+		vehicle.Transmission,
+	}
 }
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+var seaterPattern = regexp.MustCompile(`(?i)\b(\d{1,2})[ -]?seaters?\b`)
+
+// The filters renters search vehicles with, which are also what a saved search
+// stores. A number of seats in the text, like "7 seater", is taken as the
+// minimum seats
+type VehicleSearchFilters struct {
+	Category     string    `form:"category" json:"category,omitempty" binding:"omitempty,oneof=compact convertible luxury minivan pickup sedan suv van"`
+	EndAt        time.Time `form:"end_at" json:"end_at,omitzero"`
+	Features     []string  `form:"features" json:"features,omitempty" binding:"max=10,dive,oneof=air_conditioning all_wheel_drive android_auto apple_carplay bluetooth child_seat gps heated_seats sunroof usb_charger"`
+	FuelType     string    `form:"fuel_type" json:"fuel_type,omitempty" binding:"omitempty,oneof=diesel electric hybrid petrol"`
+	Latitude     *float64  `form:"latitude" json:"latitude,omitempty" binding:"omitempty,gte=-90,lte=90"`
+	Longitude    *float64  `form:"longitude" json:"longitude,omitempty" binding:"omitempty,gte=-180,lte=180"`
+	Make         string    `form:"make" json:"make,omitempty" binding:"max=50"`
+	MaxPrice     int       `form:"max_price" json:"max_price,omitempty" binding:"omitempty,gt=0"`
+	MaxYear      int       `form:"max_year" json:"max_year,omitempty" binding:"omitempty,gte=1950"`
+	MinDoors     int       `form:"min_doors" json:"min_doors,omitempty" binding:"omitempty,gte=2,lte=6"`
+	MinEVRange   int       `form:"min_ev_range" json:"min_ev_range,omitempty" binding:"omitempty,gt=0"`
+	MinPrice     int       `form:"min_price" json:"min_price,omitempty" binding:"omitempty,gt=0"`
+	MinSeats     int       `form:"min_seats" json:"min_seats,omitempty" binding:"omitempty,gte=1,lte=15"`
+	MinYear      int       `form:"min_year" json:"min_year,omitempty" binding:"omitempty,gte=1950"`
+	Query        string    `form:"q" json:"q,omitempty" binding:"max=100"`
+	RadiusInKm   float64   `form:"radius" json:"radius,omitempty" binding:"omitempty,gt=0,lte=500"`
+	StartAt      time.Time `form:"start_at" json:"start_at,omitzero"`
+	Transmission string    `form:"transmission" json:"transmission,omitempty" binding:"omitempty,oneof=automatic manual"`
+}
+
+// What the filters turn into for a query on vehicles aliased v. Distance and
+// Relevance are SQL expressions that are empty when there's no point or text
+type VehicleSearchSQL struct {
+	Arguments  []interface{}
+	Conditions []string
+	Distance   string
+	Relevance  string
+}
+
+func (filters *VehicleSearchFilters) HasDates() bool {
+	return !filters.StartAt.IsZero() || !filters.EndAt.IsZero()
+}
+
+// Builds the conditions only listed vehicles that match the filters meet. Their
+// placeholders are numbered after the arguments given
+func (filters *VehicleSearchFilters) Build(arguments ...interface{}) *VehicleSearchSQL {
+	text := strings.TrimSpace(filters.Query)
+	minSeats := filters.MinSeats
+	if match := seaterPattern.FindStringSubmatch(text); match != nil {
+		text = strings.TrimSpace(strings.Replace(text, match[0], " ", 1))
+		if seats, _ := strconv.Atoi(match[1]); minSeats == 0 {
+			minSeats = seats
+		}
+	}
+
+	search := &VehicleSearchSQL{Arguments: arguments, Conditions: []string{"v.unlisted_at IS NULL"}}
+	conditions := []struct {
+		condition string
+		value     interface{}
+		isSet     bool
+	}{
+		{"v.category = $%v", filters.Category, filters.Category != ""},
+		{"v.doors >= $%v", filters.MinDoors, filters.MinDoors != 0},
+		{"v.ev_range >= $%v", filters.MinEVRange, filters.MinEVRange != 0},
+		{"v.features @> $%v", filters.Features, len(filters.Features) != 0},
+		{"v.fuel_type = $%v", filters.FuelType, filters.FuelType != ""},
+		{"lower(v.make) = lower($%v)", filters.Make, filters.Make != ""},
+		{"v.rental_fee <= $%v", filters.MaxPrice, filters.MaxPrice != 0},
+		{"v.rental_fee >= $%v", filters.MinPrice, filters.MinPrice != 0},
+		{"v.seats >= $%v", minSeats, minSeats != 0},
+		{"v.transmission = $%v", filters.Transmission, filters.Transmission != ""},
+		{"v.year <= $%v", filters.MaxYear, filters.MaxYear != 0},
+		{"v.year >= $%v", filters.MinYear, filters.MinYear != 0},
+	}
+	for _, condition := range conditions {
+		if condition.isSet {
+			search.Arguments = append(search.Arguments, condition.value)
+			search.Conditions = append(search.Conditions, fmt.Sprintf(condition.condition, len(search.Arguments)))
+		}
+	}
+
+	if filters.HasDates() {
+		search.Arguments = append(search.Arguments, BlockingBookingStatuses, filters.StartAt, filters.EndAt)
+		count := len(search.Arguments)
+		search.Conditions = append(search.Conditions, fmt.Sprintf(`NOT EXISTS (
+			SELECT 1 FROM bookings AS b
+			WHERE b.vehicle_id = v.id AND b.status = ANY($%[1]v) AND b.start_at < $%[3]v AND b.end_at > $%[2]v
+		) AND NOT EXISTS (
+			SELECT 1 FROM vehicle_blackouts AS vb
+			WHERE vb.vehicle_id = v.id AND vb.start_at < $%[3]v AND vb.end_at > $%[2]v
+		)`, count-2, count-1, count))
+	}
+
+	// Words match through full-text search and misspellings through trigrams
+	if text != "" {
+		search.Arguments = append(search.Arguments, text)
+		count := len(search.Arguments)
+		query := fmt.Sprintf("websearch_to_tsquery('simple', $%v)", count)
+		search.Conditions = append(search.Conditions, fmt.Sprintf("(v.search_vector @@ %v OR lower($%v) <%% v.search_text)", query, count))
+		search.Relevance = fmt.Sprintf("(ts_rank(v.search_vector, %v) + word_similarity(lower($%v), v.search_text))", query, count)
+	}
+
+	if filters.Latitude != nil && filters.Longitude != nil {
+		radius := filters.RadiusInKm
+		if radius == 0 {
+			radius = config.DefaultSearchRadiusInKm
+		}
+
+		search.Arguments = append(search.Arguments, *filters.Latitude, *filters.Longitude, radius)
+		count := len(search.Arguments)
+		search.Distance = fmt.Sprintf("distance_in_km(v.location, POINT($%v, $%v))", count-2, count-1)
+		search.Conditions = append(search.Conditions, fmt.Sprintf("%v <= $%v", search.Distance, count))
+	}
+
+	return search
+}
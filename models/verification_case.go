@@ -0,0 +1,46 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	VerificationStatusApproved = "approved"
+	VerificationStatusPending  = "pending"
+	VerificationStatusRejected = "rejected"
+
+	VerificationTypeDriverLicence = "driver_licence"
+	VerificationTypeIdentity      = "identity"
+)
+
+type VerificationCase struct {
+	ID              string     `json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Documents       []string   `json:"-"`
+	DocumentsCount  int        `json:"documents_count"`
+	RejectionReason string     `json:"rejection_reason"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	Status          string     `json:"status"`
+	Type            string     `json:"type"`
+	UserID          string     `json:"user_id"`
+}
+
+func HasApprovedVerification(ctx context.Context, querier Querier, userId string, verificationType string) (bool, error) {
+	approved := false
+	sql := `SELECT EXISTS (
+		SELECT 1 FROM verification_cases WHERE user_id = $1 AND type = $2 AND status = 'approved'
+	)`
+	err := querier.QueryRow(ctx, sql, userId, verificationType).Scan(&approved)
+	return approved, err
+}
+
+func InsertVerificationCase(ctx context.Context, querier Querier, verificationCase *VerificationCase) error {
+	sql := `
+	INSERT INTO verification_cases (id, documents, type, user_id) 
+	VALUES ($1, $2, $3, $4) 
+	RETURNING created_at, status`
+	arguments := []interface{}{verificationCase.ID, verificationCase.Documents, verificationCase.Type, verificationCase.UserID}
+	verificationCase.DocumentsCount = len(verificationCase.Documents)
+	return querier.QueryRow(ctx, sql, arguments...).Scan(&verificationCase.CreatedAt, &verificationCase.Status)
+}
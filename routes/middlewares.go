@@ -1,9 +1,12 @@
 package routes
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/gin-gonic/gin"
 )
@@ -37,7 +40,67 @@ func Authorizer(credentialsRequired bool) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user", user)
+		// Role changes and suspensions must take effect before the token expires, so
+		// the claims are checked against the user's current row
+		claims := user.(*services.AccessTokenClaims)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		isSuspended := false
+		sqlOptions := models.SQLOptions{
+			Arguments:         []interface{}{claims.ID},
+			AfterTableClauses: `WHERE id = $1`,
+			ReturnColumns:     []string{"role", "suspended_at IS NOT NULL AS is_suspended"},
+			Destination:       []interface{}{&claims.Role, &isSuspended},
+		}
+		response := models.SelectUserRow(ctx, sqlOptions)
+		if response != nil && response.StatusCode == http.StatusInternalServerError {
+			c.AbortWithStatusJSON(response.StatusCode, response.Body)
+			return
+		}
+
+		if response != nil && credentialsRequired {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Token is invalid or has expired"})
+			return
+		}
+
+		if response != nil {
+			c.Next()
+			return
+		}
+
+		if isSuspended && credentialsRequired {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Your account has been suspended"})
+			return
+		}
+
+		if isSuspended {
+			c.Next()
+			return
+		}
+
+		c.Set("user", claims)
 		c.Next()
 	}
 }
+
+// Must be used after Authorizer(true) so that the user's claims are available
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("user")
+		claims, ok := value.(*services.AccessTokenClaims)
+		if !exists || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Token is invalid or has expired"})
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "You don't have permission to perform this action"})
+	}
+}
@@ -21,7 +21,7 @@ func SetupRouter() *gin.Engine {
 		AllowOrigins:     []string{config.ClientOrigin},
 		AllowMethods:     []string{"GET", "POST", "DELETE", "PUT", "PATCH"},
 		AllowHeaders:     []string{"Origin", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "X-Skipped-Vehicles"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -31,11 +31,48 @@ func SetupRouter() *gin.Engine {
 	})
 
 	accountRouter := router.Group("/account").Use(Authorizer(true))
+	accountRouter.GET("/balance", handlers.GetBalance)
+	accountRouter.GET("/cohost-invitations", handlers.GetCohostInvitations)
+	accountRouter.POST("/cohost-invitations/:id/accept", handlers.AcceptCohostInvitation)
+	accountRouter.GET("/credits", handlers.GetCredits)
 	accountRouter.DELETE("/otp-key", handlers.DeleteOTPKey)
 	accountRouter.GET("/otp-key", handlers.GetOTPKey)
 	accountRouter.POST("/otp-key/confirm", handlers.ConfirmOTPKey)
 	accountRouter.PUT("/password", handlers.UpdatePassword)
+	accountRouter.GET("/payout-account", handlers.GetPayoutAccount)
+	accountRouter.PUT("/payout-account", handlers.UpdatePayoutAccount)
+	accountRouter.GET("/payouts", handlers.GetPayouts)
 	accountRouter.PUT("/profile", handlers.UpdateProfile)
+	accountRouter.GET("/saved-searches", handlers.GetSavedSearches)
+	accountRouter.POST("/saved-searches", handlers.CreateSavedSearch)
+	accountRouter.DELETE("/saved-searches/:id", handlers.DeleteSavedSearch)
+	accountRouter.GET("/tax-summary", handlers.ExportTaxSummary)
+	accountRouter.GET("/verifications", handlers.GetVerificationCases)
+	accountRouter.POST("/verifications", handlers.CreateVerificationCase)
+
+	adminRouter := router.Group("/admin").Use(Authorizer(true), RequireRole(config.RoleAdmin, config.RoleSupport))
+	adminRouter.GET("/audit-logs", RequireRole(config.RoleAdmin), handlers.GetAuditLogs)
+	adminRouter.GET("/bookings", handlers.SearchBookings)
+	adminRouter.GET("/claims", handlers.SearchClaims)
+	adminRouter.POST("/claims/:id/resolve", RequireRole(config.RoleAdmin), handlers.ResolveClaim)
+	adminRouter.POST("/bookings/:id/refund", RequireRole(config.RoleAdmin), handlers.RefundBooking)
+	adminRouter.POST("/bookings/:id/retry-overage-charge", RequireRole(config.RoleAdmin), handlers.RetryOverageCharge)
+	adminRouter.GET("/promo-codes", handlers.GetPromoCodes)
+	adminRouter.POST("/promo-codes", RequireRole(config.RoleAdmin), handlers.CreatePromoCode)
+	adminRouter.GET("/reconciliation", RequireRole(config.RoleAdmin), handlers.GetReconciliation)
+	adminRouter.GET("/tax-rules", handlers.GetTaxRules)
+	adminRouter.POST("/tax-rules", RequireRole(config.RoleAdmin), handlers.CreateTaxRule)
+	adminRouter.DELETE("/tax-rules/:id", RequireRole(config.RoleAdmin), handlers.DeleteTaxRule)
+	adminRouter.GET("/users", handlers.SearchUsers)
+	adminRouter.PUT("/users/:id/role", RequireRole(config.RoleAdmin), handlers.UpdateUserRole)
+	adminRouter.POST("/users/:id/suspend", RequireRole(config.RoleAdmin), handlers.SuspendUser)
+	adminRouter.POST("/users/:id/unsuspend", RequireRole(config.RoleAdmin), handlers.UnsuspendUser)
+	adminRouter.POST("/vehicles/:id/relist", RequireRole(config.RoleAdmin), handlers.RelistVehicle)
+	adminRouter.POST("/vehicles/:id/unlist", RequireRole(config.RoleAdmin), handlers.UnlistVehicle)
+	adminRouter.GET("/verifications", handlers.SearchVerificationCases)
+	adminRouter.POST("/verifications/:id/approve", RequireRole(config.RoleAdmin), handlers.ApproveVerificationCase)
+	adminRouter.GET("/verifications/:id/documents/:index", RequireRole(config.RoleAdmin), handlers.GetVerificationDocument)
+	adminRouter.POST("/verifications/:id/reject", RequireRole(config.RoleAdmin), handlers.RejectVerificationCase)
 
 	authRouter := router.Group("/auth")
 	authRouter.POST("/login", handlers.Login)
@@ -45,15 +82,85 @@ func SetupRouter() *gin.Engine {
 	authRouter.POST("/register", handlers.Register)
 	authRouter.POST("/reset-password", handlers.ResetPassword)
 
+	bookingRouter := router.Group("/bookings").Use(Authorizer(true))
+	bookingRouter.GET("", handlers.GetBookings)
+	bookingRouter.POST("", handlers.CreateBooking)
+	bookingRouter.POST("/quote", handlers.QuoteBooking)
+	bookingRouter.GET("/:id", handlers.GetBooking)
+	bookingRouter.POST("/:id/accept", handlers.AcceptBooking)
+	bookingRouter.POST("/:id/cancel", handlers.CancelBooking)
+	bookingRouter.GET("/:id/cancellation", handlers.GetCancellation)
+	bookingRouter.GET("/:id/changes", handlers.GetBookingChanges)
+	bookingRouter.POST("/:id/changes", handlers.CreateBookingChange)
+	bookingRouter.POST("/:id/changes/:changeId/approve", handlers.ApproveBookingChange)
+	bookingRouter.POST("/:id/changes/:changeId/cancel", handlers.CancelBookingChange)
+	bookingRouter.POST("/:id/changes/:changeId/decline", handlers.DeclineBookingChange)
+	bookingRouter.POST("/:id/check-in", handlers.CheckInBooking)
+	bookingRouter.POST("/:id/check-out", handlers.CheckOutBooking)
+	bookingRouter.POST("/:id/claims", handlers.CreateClaim)
+	bookingRouter.POST("/:id/complete", handlers.CompleteBooking)
+	bookingRouter.POST("/:id/decline", handlers.DeclineBooking)
+	bookingRouter.GET("/:id/inspections", handlers.GetInspections)
+	bookingRouter.GET("/:id/invoice", handlers.GetBookingInvoice)
+	bookingRouter.POST("/:id/inspections/:type/acknowledge", handlers.AcknowledgeInspection)
+	bookingRouter.GET("/:id/inspections/:type/photos/:side", handlers.GetInspectionPhoto)
+	bookingRouter.POST("/:id/start", handlers.StartBooking)
+
+	claimRouter := router.Group("/claims").Use(Authorizer(true))
+	claimRouter.GET("/:id", handlers.GetClaim)
+	claimRouter.POST("/:id/accept", handlers.AcceptClaim)
+	claimRouter.POST("/:id/dispute", handlers.DisputeClaim)
+	claimRouter.GET("/:id/photos/:index", handlers.GetClaimPhoto)
+
+	conversationRouter := router.Group("/conversations").Use(Authorizer(true))
+	conversationRouter.GET("", handlers.GetConversations)
+	conversationRouter.POST("", handlers.CreateConversation)
+	conversationRouter.GET("/:id/messages", handlers.GetMessages)
+	conversationRouter.POST("/:id/messages", handlers.SendMessage)
+	conversationRouter.POST("/:id/read", handlers.MarkConversationRead)
+
+	router.GET("/events", Authorizer(true), handlers.StreamEvents)
+
 	notificationRouter := router.Group("/notification")
-	notificationRouter.POST("/verify-email", handlers.VerifyEmail)
+	notificationRouter.GET("", Authorizer(true), handlers.GetNotifications)
+	notificationRouter.POST("/:id/read", Authorizer(true), handlers.MarkNotificationRead)
 	notificationRouter.POST("/forgot-password", handlers.ForgotPassword)
+	notificationRouter.GET("/preferences", Authorizer(true), handlers.GetNotificationPreferences)
+	notificationRouter.PUT("/preferences/:type", Authorizer(true), handlers.UpdateNotificationPreference)
+	notificationRouter.POST("/read", Authorizer(true), handlers.MarkAllNotificationsRead)
+	notificationRouter.POST("/verify-email", handlers.VerifyEmail)
+
+	paymentRouter := router.Group("/payments")
+	paymentRouter.POST("/webhook", handlers.HandlePaymentWebhook)
 
 	userRouter := router.Group("/users")
 	userRouter.GET("/:id", handlers.GetUser)
 
 	vehicleRouter := router.Group("/vehicles")
+	vehicleRouter.GET("", handlers.SearchVehicles)
+	vehicleRouter.POST("", Authorizer(true), handlers.CreateVehicle)
+	vehicleRouter.GET("/autocomplete", handlers.AutocompleteVehicles)
+	vehicleRouter.GET("/export", Authorizer(true), handlers.ExportVehicles)
+	vehicleRouter.POST("/import", Authorizer(true), handlers.ImportVehicles)
+	vehicleRouter.GET("/vin/:vin", Authorizer(true), handlers.DecodeVIN)
 	vehicleRouter.GET("/:id", handlers.GetVehicle)
+	vehicleRouter.PUT("/:id/attributes", Authorizer(true), handlers.UpdateVehicleAttributes)
+	vehicleRouter.GET("/:id/blackouts", Authorizer(true), handlers.GetBlackouts)
+	vehicleRouter.POST("/:id/blackouts", Authorizer(true), handlers.CreateBlackout)
+	vehicleRouter.DELETE("/:id/blackouts/:blackoutId", Authorizer(true), handlers.DeleteBlackout)
+	vehicleRouter.POST("/:id/calendar-token", Authorizer(true), handlers.ResetVehicleCalendarToken)
+	vehicleRouter.GET("/:id/calendar.ics", handlers.GetVehicleCalendarFeed)
+	vehicleRouter.GET("/:id/calendars", Authorizer(true), handlers.GetVehicleCalendars)
+	vehicleRouter.POST("/:id/calendars", Authorizer(true), handlers.CreateVehicleCalendar)
+	vehicleRouter.DELETE("/:id/calendars/:calendarId", Authorizer(true), handlers.DeleteVehicleCalendar)
+	vehicleRouter.PUT("/:id/cancellation-policy", Authorizer(true), handlers.UpdateCancellationPolicy)
+	vehicleRouter.GET("/:id/cohosts", Authorizer(true), handlers.GetCohosts)
+	vehicleRouter.POST("/:id/cohosts", Authorizer(true), handlers.CreateCohost)
+	vehicleRouter.DELETE("/:id/cohosts/:cohostId", Authorizer(true), handlers.DeleteCohost)
+	vehicleRouter.GET("/:id/earnings", Authorizer(true), handlers.GetVehicleEarnings)
+	vehicleRouter.PUT("/:id/instant-book", Authorizer(true), handlers.UpdateInstantBook)
+	vehicleRouter.PUT("/:id/pricing-rules", Authorizer(true), handlers.UpdatePricingRules)
+	vehicleRouter.PUT("/:id/security-deposit", Authorizer(true), handlers.UpdateSecurityDeposit)
 
 	verificationRouter := router.Group("/verification")
 	verificationRouter.POST("/email", handlers.EmailVerification)
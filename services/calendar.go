@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+var (
+	ErrCalendarAddressNotAllowed = errors.New("calendar address is not allowed")
+	ErrInvalidCalendar           = errors.New("invalid calendar")
+
+	// Calendar links are given by hosts, so they're refused when they lead to the
+	// internal network. Tests serve their feeds locally and turn this on
+	AllowPrivateCalendarAddresses = false
+
+	calendarDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	calendarTextEscaper     = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	calendarTextUnescaper   = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+// A period the vehicle is busy for, as read from or written to an iCalendar
+// (RFC 5545) feed
+type CalendarEvent struct {
+	EndAt   time.Time
+	StartAt time.Time
+	Summary string
+	UID     string
+}
+
+type calendarProperty struct {
+	name       string
+	parameters map[string]string
+	value      string
+}
+
+// Downloads the feed at url and returns its busy periods. Feeds larger than
+// config.MaxCalendarSizeInBytes are rejected rather than read in part
+func FetchCalendarEvents(ctx context.Context, url string) ([]CalendarEvent, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Accept", "text/calendar")
+	dialer := &net.Dialer{Timeout: config.CalendarFetchTimeout, Control: checkCalendarConnection}
+	client := http.Client{
+		CheckRedirect: checkCalendarRedirect,
+		Timeout:       config.CalendarFetchTimeout,
+		Transport:     &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: config.CalendarFetchTimeout},
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar responded with status %v", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, config.MaxCalendarSizeInBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > config.MaxCalendarSizeInBytes {
+		return nil, fmt.Errorf("calendar is larger than %v bytes", config.MaxCalendarSizeInBytes)
+	}
+
+	return ParseCalendar(bytes.NewReader(body))
+}
+
+// Runs before every connection is made, after the host has been resolved, so a
+// name that resolves to a public address when it's checked and to a private one
+// when it's dialed still can't get through
+func checkCalendarConnection(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !isPublicCalendarIP(ip) {
+		return ErrCalendarAddressNotAllowed
+	}
+
+	return nil
+}
+
+// Redirects have to stay on http or https and lead to public addresses like the
+// link itself
+func checkCalendarRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("calendar redirected too many times")
+	}
+
+	if request.URL.Scheme != "http" && request.URL.Scheme != "https" {
+		return ErrCalendarAddressNotAllowed
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(request.Context(), request.URL.Hostname())
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if !isPublicCalendarIP(address.IP) {
+			return ErrCalendarAddressNotAllowed
+		}
+	}
+
+	return nil
+}
+
+func isPublicCalendarIP(ip net.IP) bool {
+	if AllowPrivateCalendarAddresses {
+		return true
+	}
+
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// Returns the events of the calendar that make the vehicle busy. Cancelled and
+// transparent events are left out and recurring events only count once, which is
+// how booking platforms publish their feeds. Dates and times without a zone are
+// taken as UTC
+func ParseCalendar(reader io.Reader) ([]CalendarEvent, error) {
+	lines, err := unfoldCalendarLines(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, ErrInvalidCalendar
+	}
+
+	events := []CalendarEvent{}
+	components := []string{}
+	var event map[string]calendarProperty
+	for _, line := range lines {
+		property, ok := parseCalendarProperty(line)
+		if !ok {
+			return nil, ErrInvalidCalendar
+		}
+
+		switch property.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(property.value))
+			if len(components) == 2 && components[1] == "VEVENT" {
+				event = map[string]calendarProperty{}
+			}
+			continue
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(property.value) {
+				return nil, ErrInvalidCalendar
+			}
+
+			if len(components) == 2 && components[1] == "VEVENT" {
+				calendarEvent, busy, err := newCalendarEvent(event)
+				if err != nil {
+					return nil, err
+				}
+
+				if busy {
+					events = append(events, calendarEvent)
+				}
+			}
+			components = components[:len(components)-1]
+			continue
+		}
+
+		if len(components) == 2 && components[1] == "VEVENT" {
+			event[property.name] = property
+		}
+	}
+
+	if len(components) != 0 {
+		return nil, ErrInvalidCalendar
+	}
+
+	return events, nil
+}
+
+// Writes the events as a calendar named name that other platforms can subscribe to
+func WriteCalendar(writer io.Writer, name string, events []CalendarEvent) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Pentahire//Pentahire API//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + calendarTextEscaper.Replace(name),
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+calendarTextEscaper.Replace(event.UID),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+event.StartAt.UTC().Format("20060102T150405Z"),
+			"DTEND:"+event.EndAt.UTC().Format("20060102T150405Z"),
+			"SUMMARY:"+calendarTextEscaper.Replace(event.Summary),
+			"TRANSP:OPAQUE",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	bufferedWriter := bufio.NewWriter(writer)
+	for _, line := range lines {
+		if _, err := bufferedWriter.WriteString(foldCalendarLine(line)); err != nil {
+			return err
+		}
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// Lines longer than 75 octets are split with a CRLF followed by a space, without
+// splitting a character
+func foldCalendarLine(line string) string {
+	builder := strings.Builder{}
+	width := 0
+	for _, character := range line {
+		size := utf8.RuneLen(character)
+		if width+size > 75 {
+			builder.WriteString("\r\n ")
+			width = 1
+		}
+
+		builder.WriteRune(character)
+		width += size
+	}
+
+	builder.WriteString("\r\n")
+	return builder.String()
+}
+
+// Joins lines that were folded back together and drops empty ones
+func unfoldCalendarLines(reader io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), config.MaxCalendarSizeInBytes)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// Splits a content line like DTSTART;TZID=Africa/Lagos:20240101T100000 into its
+// name, parameters and value. Colons and semicolons inside quoted parameter values
+// don't count
+func parseCalendarProperty(line string) (calendarProperty, bool) {
+	property := calendarProperty{parameters: map[string]string{}}
+	quoted := false
+	start := 0
+	parts := []string{}
+	for i, character := range line {
+		switch {
+		case character == '"':
+			quoted = !quoted
+		case character == ';' && !quoted:
+			parts = append(parts, line[start:i])
+			start = i + 1
+		case character == ':' && !quoted:
+			parts = append(parts, line[start:i])
+			property.name = strings.ToUpper(parts[0])
+			for _, parameter := range parts[1:] {
+				key, value, _ := strings.Cut(parameter, "=")
+				property.parameters[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+			property.value = line[i+1:]
+			return property, property.name != ""
+		}
+	}
+
+	return property, false
+}
+
+// Works out the period of an event. An event without an end lasts for its
+// duration, a day when it starts on a date, or no time at all
+func newCalendarEvent(properties map[string]calendarProperty) (CalendarEvent, bool, error) {
+	event := CalendarEvent{
+		Summary: calendarTextUnescaper.Replace(properties["SUMMARY"].value),
+		UID:     calendarTextUnescaper.Replace(properties["UID"].value),
+	}
+	if strings.EqualFold(properties["STATUS"].value, "CANCELLED") || strings.EqualFold(properties["TRANSP"].value, "TRANSPARENT") {
+		return event, false, nil
+	}
+
+	start, ok := properties["DTSTART"]
+	if !ok {
+		return event, false, ErrInvalidCalendar
+	}
+
+	startAt, isDate, err := parseCalendarTime(start)
+	if err != nil {
+		return event, false, err
+	}
+
+	endAt := startAt
+	if end, ok := properties["DTEND"]; ok {
+		if endAt, _, err = parseCalendarTime(end); err != nil {
+			return event, false, err
+		}
+	} else if duration, ok := properties["DURATION"]; ok {
+		if endAt, err = addCalendarDuration(startAt, duration.value); err != nil {
+			return event, false, err
+		}
+	} else if isDate {
+		endAt = startAt.AddDate(0, 0, 1)
+	}
+
+	event.StartAt, event.EndAt = startAt, endAt
+	if event.UID == "" {
+		event.UID = fmt.Sprintf("%v-%v", startAt.Unix(), endAt.Unix())
+	}
+
+	return event, endAt.After(startAt), nil
+}
+
+func parseCalendarTime(property calendarProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(property.value)
+	if strings.EqualFold(property.parameters["VALUE"], "DATE") || len(value) == 8 {
+		date, err := time.Parse("20060102", value)
+		if err != nil {
+			return date, true, ErrInvalidCalendar
+		}
+
+		return date, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		dateTime, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return dateTime, false, ErrInvalidCalendar
+		}
+
+		return dateTime, false, nil
+	}
+
+	location := time.UTC
+	if zone := property.parameters["TZID"]; zone != "" {
+		if zoneLocation, err := time.LoadLocation(zone); err == nil {
+			location = zoneLocation
+		}
+	}
+
+	dateTime, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return dateTime, false, ErrInvalidCalendar
+	}
+
+	return dateTime.UTC(), false, nil
+}
+
+// Adds a duration like P1DT12H. Weeks and days are added to the date so that
+// they're calendar days
+func addCalendarDuration(startAt time.Time, value string) (time.Time, error) {
+	matches := calendarDurationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if matches == nil || matches[0] == "P" || strings.HasSuffix(matches[0], "T") {
+		return startAt, ErrInvalidCalendar
+	}
+
+	numbers := make([]int, 5)
+	for i, match := range matches[2:] {
+		if match != "" {
+			numbers[i], _ = strconv.Atoi(match)
+		}
+	}
+
+	sign := 1
+	if matches[1] == "-" {
+		sign = -1
+	}
+
+	duration := time.Duration(numbers[2])*time.Hour + time.Duration(numbers[3])*time.Minute + time.Duration(numbers[4])*time.Second
+	return startAt.AddDate(0, 0, sign*(numbers[0]*7+numbers[1])).Add(time.Duration(sign) * duration), nil
+}
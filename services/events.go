@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	EventBookingUpdated      = "booking.updated"
+	EventConversationRead    = "conversation.read"
+	EventMessageCreated      = "message.created"
+	EventNotificationCreated = "notification.created"
+)
+
+type Event struct {
+	Data interface{} `json:"data"`
+	Type string      `json:"type"`
+}
+
+// Events go through redis rather than straight to the open streams so that a
+// user connected to any API instance receives them
+func PublishEvent(ctx context.Context, userId string, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return redisClient.Publish(ctx, config.RedisEventsChannelPrefix+userId, payload).Err()
+}
+
+func SubscribeToEvents(ctx context.Context, userId string) *redis.PubSub {
+	return redisClient.Subscribe(ctx, config.RedisEventsChannelPrefix+userId)
+}
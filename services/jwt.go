@@ -12,6 +12,7 @@ type JWTOptions struct {
 type AccessTokenClaims struct {
 	Email string `json:"email"`
 	ID    string `json:"_id"`
+	Role  string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"encoding/base64"
+
 	"github.com/Ekenzy-101/Pentahire-API/config"
 	"github.com/gin-gonic/gin"
 	"github.com/sendgrid/rest"
@@ -8,10 +10,17 @@ import (
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
 )
 
+type MailAttachment struct {
+	Content  []byte
+	Filename string
+	Type     string
+}
+
 type MailOption struct {
-	To         *mail.Email
-	Data       gin.H
-	TemplateID string
+	Attachments []MailAttachment
+	To          *mail.Email
+	Data        gin.H
+	TemplateID  string
 }
 
 func SendMail(options MailOption) (*rest.Response, error) {
@@ -29,6 +38,14 @@ func SendMail(options MailOption) (*rest.Response, error) {
 	v3Mail := mail.NewV3Mail()
 	v3Mail.SetTemplateID(options.TemplateID).SetFrom(from)
 	v3Mail.AddPersonalizations(personalization)
+	for _, attachment := range options.Attachments {
+		v3Attachment := mail.NewAttachment()
+		v3Attachment.SetContent(base64.StdEncoding.EncodeToString(attachment.Content))
+		v3Attachment.SetDisposition("attachment")
+		v3Attachment.SetFilename(attachment.Filename)
+		v3Attachment.SetType(attachment.Type)
+		v3Mail.AddAttachment(v3Attachment)
+	}
 
 	request := sendgrid.GetRequest(config.SendgridAPIKey, "/v3/mail/send", "https://api.sendgrid.com")
 	request.Method = "POST"
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/google/uuid"
+)
+
+const (
+	PaymentStatusAuthorised        = "authorised"
+	PaymentStatusCancelled         = "cancelled"
+	PaymentStatusCaptured          = "captured"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusPending           = "pending"
+	PaymentStatusRefunded          = "refunded"
+
+	// Payment methods the fake provider understands, named after the test cards of real providers
+	FakePaymentMethodDeclined = "pm_card_declined"
+	FakePaymentMethodVisa     = "pm_card_visa"
+)
+
+var (
+	ErrInvalidPaymentState     = errors.New("payment is not in a state that allows this operation")
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrPaymentDeclined         = errors.New("payment was declined")
+	ErrPaymentIntentNotFound   = errors.New("payment intent not found")
+
+	paymentProvider PaymentProvider
+)
+
+// Amounts are in the minor unit of the currency
+type PaymentIntent struct {
+	ID             string `json:"id"`
+	Amount         int    `json:"amount"`
+	AmountCaptured int    `json:"amount_captured"`
+	AmountRefunded int    `json:"amount_refunded"`
+	Currency       string `json:"currency"`
+	Status         string `json:"status"`
+}
+
+type PaymentWebhookEvent struct {
+	ID     string        `json:"id"`
+	Intent PaymentIntent `json:"intent"`
+	Type   string        `json:"type"`
+}
+
+// PaymentProvider mirrors the intent based flow of providers like Stripe. An intent
+// is created and authorised when a booking is requested, captured when the trip
+// starts and refunded in part or in full when it is cancelled
+type PaymentProvider interface {
+	Authorise(ctx context.Context, intentId string, paymentMethodId string) (*PaymentIntent, error)
+	Cancel(ctx context.Context, intentId string, idempotencyKey string) (*PaymentIntent, error)
+	Capture(ctx context.Context, intentId string, amount int, idempotencyKey string) (*PaymentIntent, error)
+	CreateIntent(ctx context.Context, amount int, currency string, idempotencyKey string) (*PaymentIntent, error)
+	GetIntent(ctx context.Context, intentId string) (*PaymentIntent, error)
+	Name() string
+	ParseWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error)
+	Refund(ctx context.Context, intentId string, amount int, idempotencyKey string) (*PaymentIntent, error)
+}
+
+// Keys are derived from the amount the caller last stored as refunded, so retrying
+// an operation whose database write was rolled back replays the provider's earlier
+// result instead of moving the money twice, while a later refund gets a new key
+func PaymentIdempotencyKey(operation string, intentId string, amountRefunded int) string {
+	return fmt.Sprintf("%v_%v_%v", operation, intentId, amountRefunded)
+}
+
+// FakePaymentProvider keeps intents in memory so development and tests don't need
+// a provider account. Intents are lost when the process restarts
+type FakePaymentProvider struct {
+	WebhookSecret string
+
+	intents         map[string]*PaymentIntent
+	idempotencyKeys map[string]string
+	mutex           sync.Mutex
+	operations      map[string]PaymentIntent
+}
+
+func NewFakePaymentProvider(webhookSecret string) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		WebhookSecret:   webhookSecret,
+		intents:         map[string]*PaymentIntent{},
+		idempotencyKeys: map[string]string{},
+		operations:      map[string]PaymentIntent{},
+	}
+}
+
+func (provider *FakePaymentProvider) Authorise(ctx context.Context, intentId string, paymentMethodId string) (*PaymentIntent, error) {
+	return provider.update(intentId, func(intent *PaymentIntent) error {
+		if intent.Status != PaymentStatusPending {
+			return ErrInvalidPaymentState
+		}
+
+		if paymentMethodId != FakePaymentMethodVisa {
+			return ErrPaymentDeclined
+		}
+
+		intent.Status = PaymentStatusAuthorised
+		return nil
+	})
+}
+
+func (provider *FakePaymentProvider) Cancel(ctx context.Context, intentId string, idempotencyKey string) (*PaymentIntent, error) {
+	return provider.updateOnce(intentId, idempotencyKey, func(intent *PaymentIntent) error {
+		if intent.Status != PaymentStatusPending && intent.Status != PaymentStatusAuthorised {
+			return ErrInvalidPaymentState
+		}
+
+		intent.Status = PaymentStatusCancelled
+		return nil
+	})
+}
+
+// Capturing less than the authorised amount releases the rest
+func (provider *FakePaymentProvider) Capture(ctx context.Context, intentId string, amount int, idempotencyKey string) (*PaymentIntent, error) {
+	return provider.updateOnce(intentId, idempotencyKey, func(intent *PaymentIntent) error {
+		if intent.Status != PaymentStatusAuthorised || amount <= 0 || amount > intent.Amount {
+			return ErrInvalidPaymentState
+		}
+
+		intent.AmountCaptured = amount
+		intent.Status = PaymentStatusCaptured
+		return nil
+	})
+}
+
+func (provider *FakePaymentProvider) CreateIntent(ctx context.Context, amount int, currency string, idempotencyKey string) (*PaymentIntent, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if intentId, ok := provider.idempotencyKeys[idempotencyKey]; ok {
+		intent := *provider.intents[intentId]
+		return &intent, nil
+	}
+
+	intent := &PaymentIntent{
+		ID:       "pi_fake_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Amount:   amount,
+		Currency: currency,
+		Status:   PaymentStatusPending,
+	}
+	provider.intents[intent.ID] = intent
+	provider.idempotencyKeys[idempotencyKey] = intent.ID
+	copied := *intent
+	return &copied, nil
+}
+
+func (provider *FakePaymentProvider) GetIntent(ctx context.Context, intentId string) (*PaymentIntent, error) {
+	return provider.update(intentId, func(intent *PaymentIntent) error {
+		return nil
+	})
+}
+
+func (provider *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+func (provider *FakePaymentProvider) ParseWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error) {
+	if err := VerifyWebhookSignature(payload, signature, provider.WebhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	event := &PaymentWebhookEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (provider *FakePaymentProvider) Refund(ctx context.Context, intentId string, amount int, idempotencyKey string) (*PaymentIntent, error) {
+	return provider.updateOnce(intentId, idempotencyKey, func(intent *PaymentIntent) error {
+		isRefundable := intent.Status == PaymentStatusCaptured || intent.Status == PaymentStatusPartiallyRefunded
+		if !isRefundable || amount <= 0 || intent.AmountRefunded+amount > intent.AmountCaptured {
+			return ErrInvalidPaymentState
+		}
+
+		intent.AmountRefunded += amount
+		intent.Status = PaymentStatusPartiallyRefunded
+		if intent.AmountRefunded == intent.AmountCaptured {
+			intent.Status = PaymentStatusRefunded
+		}
+
+		return nil
+	})
+}
+
+func (provider *FakePaymentProvider) update(intentId string, change func(intent *PaymentIntent) error) (*PaymentIntent, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	intent, ok := provider.intents[intentId]
+	if !ok {
+		return nil, ErrPaymentIntentNotFound
+	}
+
+	if err := change(intent); err != nil {
+		return nil, err
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Like update, but a key that was already used returns the intent as it was after
+// the first call without changing it again
+func (provider *FakePaymentProvider) updateOnce(intentId string, idempotencyKey string, change func(intent *PaymentIntent) error) (*PaymentIntent, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if result, ok := provider.operations[idempotencyKey]; ok {
+		return &result, nil
+	}
+
+	intent, ok := provider.intents[intentId]
+	if !ok {
+		return nil, ErrPaymentIntentNotFound
+	}
+
+	if err := change(intent); err != nil {
+		return nil, err
+	}
+
+	provider.operations[idempotencyKey] = *intent
+	copied := *intent
+	return &copied, nil
+}
+
+// Signatures look like "t=<unix seconds>,v1=<hex hmac>" where the HMAC-SHA256 covers
+// "<unix seconds>.<payload>". The timestamp guards against replayed requests
+func SignWebhookPayload(payload []byte, secret string, timestamp time.Time) string {
+	seconds := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%v,v1=%v", seconds, computeWebhookSignature(payload, secret, seconds))
+}
+
+func VerifyWebhookSignature(payload []byte, signature string, secret string, now time.Time) error {
+	if secret == "" {
+		return ErrInvalidWebhookSignature
+	}
+
+	seconds, expected := "", ""
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			seconds = value
+		case "v1":
+			expected = value
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || expected == "" {
+		return ErrInvalidWebhookSignature
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > config.PaymentWebhookTolerance || age < -config.PaymentWebhookTolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	actual := computeWebhookSignature(payload, secret, seconds)
+	if !hmac.Equal([]byte(actual), []byte(expected)) {
+		return ErrInvalidWebhookSignature
+	}
+
+	return nil
+}
+
+func computeWebhookSignature(payload []byte, secret string, seconds string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(seconds + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func CreatePaymentProvider() PaymentProvider {
+	// An empty secret would let anyone sign webhooks, so it's never defaulted
+	if config.PaymentWebhookSecret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET must be set")
+	}
+
+	switch config.PaymentProvider {
+	case "fake":
+		paymentProvider = NewFakePaymentProvider(config.PaymentWebhookSecret)
+	default:
+		log.Fatalf("unsupported payment provider %q", config.PaymentProvider)
+	}
+
+	return paymentProvider
+}
+
+func GetPaymentProvider() PaymentProvider {
+	return paymentProvider
+}
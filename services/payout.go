@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/google/uuid"
+)
+
+const (
+	// Account numbers the fake payout provider treats specially
+	FakeAccountNumberFailingTransfers = "0000000001"
+	FakeAccountNumberUnverifiable     = "0000000000"
+)
+
+var (
+	ErrPayoutAccountNotVerified = errors.New("payout account could not be verified")
+	ErrTransferFailed           = errors.New("transfer failed")
+
+	payoutProvider PayoutProvider
+)
+
+type PayoutAccountDetails struct {
+	AccountName       string
+	AccountNumber     string
+	BankCode          string
+	ProviderAccountID string
+}
+
+// PayoutProvider sends hosts their earnings. Accounts are verified with the bank
+// once and then referred to by the provider's id for every transfer
+type PayoutProvider interface {
+	CreateTransfer(ctx context.Context, providerAccountId string, amount int, currency string, idempotencyKey string) (string, error)
+	Name() string
+	VerifyAccount(ctx context.Context, bankCode string, accountNumber string) (*PayoutAccountDetails, error)
+}
+
+type FakePayoutProvider struct {
+	accounts  map[string]string
+	transfers map[string]string
+	mutex     sync.Mutex
+}
+
+func NewFakePayoutProvider() *FakePayoutProvider {
+	return &FakePayoutProvider{accounts: map[string]string{}, transfers: map[string]string{}}
+}
+
+func (provider *FakePayoutProvider) CreateTransfer(ctx context.Context, providerAccountId string, amount int, currency string, idempotencyKey string) (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if transferId, ok := provider.transfers[idempotencyKey]; ok {
+		return transferId, nil
+	}
+
+	accountNumber, ok := provider.accounts[providerAccountId]
+	if !ok || accountNumber == FakeAccountNumberFailingTransfers || amount <= 0 {
+		return "", ErrTransferFailed
+	}
+
+	transferId := "tr_fake_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	provider.transfers[idempotencyKey] = transferId
+	return transferId, nil
+}
+
+func (provider *FakePayoutProvider) Name() string {
+	return "fake"
+}
+
+func (provider *FakePayoutProvider) VerifyAccount(ctx context.Context, bankCode string, accountNumber string) (*PayoutAccountDetails, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if accountNumber == FakeAccountNumberUnverifiable {
+		return nil, ErrPayoutAccountNotVerified
+	}
+
+	details := &PayoutAccountDetails{
+		AccountName:       "Fake Account Holder",
+		AccountNumber:     accountNumber,
+		BankCode:          bankCode,
+		ProviderAccountID: "acct_fake_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+	}
+	provider.accounts[details.ProviderAccountID] = accountNumber
+	return details, nil
+}
+
+func CreatePayoutProvider() PayoutProvider {
+	switch config.PayoutProvider {
+	case "fake":
+		payoutProvider = NewFakePayoutProvider()
+	default:
+		log.Fatalf("unsupported payout provider %q", config.PayoutProvider)
+	}
+
+	return payoutProvider
+}
+
+func GetPayoutProvider() PayoutProvider {
+	return payoutProvider
+}
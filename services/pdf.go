@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	PDFPageHeight = 842
+	PDFPageWidth  = 595
+)
+
+var pdfTextEscaper = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", "", "\n", " ")
+
+// A minimal A4 PDF writer for generated documents like invoices. It only knows
+// Helvetica and lines, which is all those documents need, and keeps the API free
+// of a PDF dependency
+type PDFDocument struct {
+	pages []*bytes.Buffer
+}
+
+func NewPDFDocument() *PDFDocument {
+	document := &PDFDocument{}
+	document.AddPage()
+	return document
+}
+
+func (document *PDFDocument) AddPage() {
+	document.pages = append(document.pages, &bytes.Buffer{})
+}
+
+// Draws a line on the current page. Like Text, y is measured from the top of the page
+func (document *PDFDocument) Line(x1, y1, x2, y2 float64) {
+	page := document.pages[len(document.pages)-1]
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+// Writes text on the current page with its baseline at y from the top of the page.
+// Characters outside of Latin-1 are written as question marks
+func (document *PDFDocument) Text(x, y float64, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	page := document.pages[len(document.pages)-1]
+	fmt.Fprintf(page, "BT /%v %.1f Tf %.2f %.2f Td (%v) Tj ET\n", font, size, x, PDFPageHeight-y, encodePDFText(text))
+}
+
+func (document *PDFDocument) WriteTo(writer io.Writer) (int64, error) {
+	buffer := &bytes.Buffer{}
+	offsets := []int{}
+	writeObject := func(body string) {
+		offsets = append(offsets, buffer.Len())
+		fmt.Fprintf(buffer, "%d 0 obj\n%v\nendobj\n", len(offsets), body)
+	}
+
+	// The catalog, the page tree and the two fonts come first, then each page
+	// followed by its content stream
+	kids := []string{}
+	for i := range document.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	buffer.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%v] /Count %d >>", strings.Join(kids, " "), len(document.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range document.pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PDFPageWidth, PDFPageHeight, 6+i*2,
+		))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%vendstream", page.Len(), page.String()))
+	}
+
+	xrefOffset := buffer.Len()
+	fmt.Fprintf(buffer, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+	return buffer.WriteTo(writer)
+}
+
+// WinAnsi matches Latin-1 for the characters it has
+func encodePDFText(text string) string {
+	encoded := make([]byte, 0, len(text))
+	for _, character := range pdfTextEscaper.Replace(text) {
+		if character > 0xff || (character >= 0x80 && character < 0xa0) {
+			character = '?'
+		}
+
+		encoded = append(encoded, byte(character))
+	}
+
+	return string(encoded)
+}
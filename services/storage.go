@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrInvalidKey   = errors.New("invalid file key")
+
+	fileStorage FileStorage
+)
+
+// FileStorage abstracts where uploaded files live so that the local backend
+// used in development can be swapped for a cloud one without touching handlers
+type FileStorage interface {
+	Delete(ctx context.Context, key string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Put(ctx context.Context, key string, content io.Reader) error
+}
+
+type LocalFileStorage struct {
+	Directory string
+}
+
+func (storage *LocalFileStorage) Delete(ctx context.Context, key string) error {
+	path, err := storage.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (storage *LocalFileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := storage.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+
+	return file, err
+}
+
+func (storage *LocalFileStorage) Put(ctx context.Context, key string, content io.Reader) error {
+	path, err := storage.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Keys are slash separated and must stay within the storage directory
+func (storage *LocalFileStorage) resolve(key string) (string, error) {
+	cleanKey := filepath.Clean("/" + key)
+	if key == "" || strings.HasSuffix(key, "/") || cleanKey != "/"+key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(storage.Directory, filepath.FromSlash(cleanKey)), nil
+}
+
+func CreateFileStorage() FileStorage {
+	switch config.StorageDriver {
+	case "local":
+		fileStorage = &LocalFileStorage{Directory: config.StorageDirectory}
+	default:
+		log.Fatalf("unsupported storage driver %q", config.StorageDriver)
+	}
+
+	return fileStorage
+}
+
+func GetFileStorage() FileStorage {
+	return fileStorage
+}
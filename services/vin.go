@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+)
+
+var (
+	ErrInvalidVIN = errors.New("invalid vin")
+
+	vinDecoder VINDecoder
+
+	vinTransliterations = map[rune]int{
+		'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+		'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+		'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+	}
+	vinWeights = []int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+	// The 10th character cycles through these every 30 years starting from 1980
+	vinModelYearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+)
+
+// What could be worked out from a VIN. Make and Manufacturer are empty when the
+// decoder doesn't know the manufacturer
+type DecodedVIN struct {
+	Country      string `json:"country"`
+	Make         string `json:"make"`
+	Manufacturer string `json:"manufacturer"`
+	ModelYear    int    `json:"model_year"`
+	VIN          string `json:"vin"`
+}
+
+// VINDecoder abstracts where vehicle data comes from so that the offline tables
+// can be swapped for a paid lookup service without touching handlers
+type VINDecoder interface {
+	Decode(ctx context.Context, vin string) (*DecodedVIN, error)
+}
+
+type vinManufacturer struct {
+	make         string
+	manufacturer string
+}
+
+// Decodes the world manufacturer identifier and model year from tables that ship
+// with the API. Only common manufacturers are known
+type OfflineVINDecoder struct{}
+
+var (
+	vinCountries = map[byte]string{
+		'1': "United States", '2': "Canada", '3': "Mexico", '4': "United States", '5': "United States",
+		'7': "United States", '9': "Brazil", 'J': "Japan", 'K': "South Korea", 'L': "China", 'M': "India",
+		'S': "United Kingdom", 'T': "Switzerland", 'V': "France", 'W': "Germany", 'Y': "Sweden", 'Z': "Italy",
+	}
+	vinManufacturers = map[string]vinManufacturer{
+		"1FA": {"Ford", "Ford Motor Company"},
+		"1FM": {"Ford", "Ford Motor Company"},
+		"1FT": {"Ford", "Ford Motor Company"},
+		"1G1": {"Chevrolet", "General Motors"},
+		"1GC": {"Chevrolet", "General Motors"},
+		"1HG": {"Honda", "American Honda Motor Company"},
+		"1N4": {"Nissan", "Nissan North America"},
+		"2HG": {"Honda", "Honda of Canada Manufacturing"},
+		"2T1": {"Toyota", "Toyota Motor Manufacturing Canada"},
+		"3FA": {"Ford", "Ford Motor Company"},
+		"3VW": {"Volkswagen", "Volkswagen de Mexico"},
+		"4T1": {"Toyota", "Toyota Motor Manufacturing Kentucky"},
+		"5YJ": {"Tesla", "Tesla"},
+		"7SA": {"Tesla", "Tesla"},
+		"JHM": {"Honda", "Honda Motor Company"},
+		"JN1": {"Nissan", "Nissan Motor Company"},
+		"JTD": {"Toyota", "Toyota Motor Corporation"},
+		"JTE": {"Toyota", "Toyota Motor Corporation"},
+		"JTM": {"Toyota", "Toyota Motor Corporation"},
+		"JTN": {"Toyota", "Toyota Motor Corporation"},
+		"KMH": {"Hyundai", "Hyundai Motor Company"},
+		"KNA": {"Kia", "Kia Corporation"},
+		"LSV": {"Volkswagen", "SAIC Volkswagen"},
+		"SAJ": {"Jaguar", "Jaguar Land Rover"},
+		"SAL": {"Land Rover", "Jaguar Land Rover"},
+		"VF1": {"Renault", "Renault"},
+		"VF3": {"Peugeot", "Stellantis"},
+		"WAU": {"Audi", "Audi"},
+		"WBA": {"BMW", "BMW"},
+		"WDD": {"Mercedes-Benz", "Mercedes-Benz"},
+		"WP0": {"Porsche", "Porsche"},
+		"WVW": {"Volkswagen", "Volkswagen"},
+		"YV1": {"Volvo", "Volvo Cars"},
+		"ZFF": {"Ferrari", "Ferrari"},
+	}
+)
+
+// The model year code repeats every 30 years, so the latest year that isn't after
+// next year's models is taken
+func (decoder *OfflineVINDecoder) Decode(ctx context.Context, vin string) (*DecodedVIN, error) {
+	vin = strings.ToUpper(vin)
+	if err := ValidateVIN(vin); err != nil {
+		return nil, err
+	}
+
+	decoded := &DecodedVIN{Country: vinCountries[vin[0]], VIN: vin}
+	if manufacturer, ok := vinManufacturers[vin[:3]]; ok {
+		decoded.Make = manufacturer.make
+		decoded.Manufacturer = manufacturer.manufacturer
+	}
+
+	if index := strings.IndexByte(vinModelYearCodes, vin[9]); index != -1 {
+		year := 1980 + index
+		for year+30 <= time.Now().Year()+1 {
+			year += 30
+		}
+
+		decoded.ModelYear = year
+	}
+
+	return decoded, nil
+}
+
+// Checks the VIN's structure and, for vehicles made for North America, its check
+// digit. Those VINs start with 1 to 5 and are the only ones where the 9th
+// character has to be a check digit
+func ValidateVIN(vin string) error {
+	if len(vin) != 17 {
+		return ErrInvalidVIN
+	}
+
+	vin = strings.ToUpper(vin)
+	sum := 0
+	for i, character := range vin {
+		value, ok := vinTransliterations[character]
+		if character >= '0' && character <= '9' {
+			value, ok = int(character-'0'), true
+		}
+
+		if !ok {
+			return ErrInvalidVIN
+		}
+
+		sum += value * vinWeights[i]
+	}
+
+	if vin[0] < '1' || vin[0] > '5' {
+		return nil
+	}
+
+	checkDigit := byte('0' + sum%11)
+	if sum%11 == 10 {
+		checkDigit = 'X'
+	}
+
+	if vin[8] != checkDigit {
+		return ErrInvalidVIN
+	}
+
+	return nil
+}
+
+func CreateVINDecoder() VINDecoder {
+	switch config.VINDecoder {
+	case "offline":
+		vinDecoder = &OfflineVINDecoder{}
+	default:
+		log.Fatalf("unsupported vin decoder %q", config.VINDecoder)
+	}
+
+	return vinDecoder
+}
+
+func GetVINDecoder() VINDecoder {
+	return vinDecoder
+}
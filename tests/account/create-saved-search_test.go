@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /account/saved-searches", func() {
+	var (
+		accessToken  string
+		filters      gin.H
+		frequency    string
+		responseBody gin.H
+		userId       string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"filters": filters, "frequency": frequency, "name": "SUVs in Lagos"}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/account/saved-searches", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		filters = gin.H{"category": "suv", "latitude": 6.45, "longitude": 3.39, "max_price": 40000}
+		frequency = models.SavedSearchFrequencyInstant
+		responseBody = gin.H{}
+
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"renter@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&userId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		var err error
+		user := &models.User{ID: userId}
+		accessToken, err = user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a valid request")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the saved search")
+		savedSearch, ok := responseBody["saved_search"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(savedSearch).To(HaveKeyWithValue("frequency", frequency))
+		Expect(savedSearch["filters"]).To(HaveKeyWithValue("category", "suv"))
+
+		By("matching only the listings that appear afterwards")
+		hostId := ""
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&hostId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		sql := `
+		INSERT INTO vehicles (address, category, location, make, name, rental_fee, user_id)
+		VALUES
+			('Lagos', 'suv', POINT(6.5, 3.3), 'Toyota', 'Toyota RAV4', 25000, $1),
+			('Lagos', 'suv', POINT(6.5, 3.3), 'Lexus', 'Lexus LX', 90000, $1)`
+		_, err = pool.Exec(ctx, sql, hostId)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(jobs.MatchSavedSearches(ctx)).To(Succeed())
+
+		count := 0
+		sql = "SELECT COUNT(*) FROM saved_search_matches WHERE notified_at IS NOT NULL"
+		Expect(pool.QueryRow(ctx, sql).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+
+		sql = "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = $2"
+		Expect(pool.QueryRow(ctx, sql, userId, models.NotificationTypeSavedSearch).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a latitude but no longitude")
+		filters = gin.H{"latitude": 6.45}
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a frequency that doesn't exist")
+		frequency = "weekly"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("frequency"))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a daily saved search")
+		frequency = models.SavedSearchFrequencyDaily
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("sending the matches of a day at most once a day")
+		hostId := ""
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&hostId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		InsertVehicle := func(name string) {
+			sql := `
+			INSERT INTO vehicles (address, category, location, make, name, rental_fee, user_id)
+			VALUES ('Lagos', 'suv', POINT(6.5, 3.3), 'Toyota', $1, 25000, $2)`
+			_, err := pool.Exec(ctx, sql, name, hostId)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		CountNotifications := func() int {
+			count := 0
+			sql := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = $2"
+			Expect(pool.QueryRow(ctx, sql, userId, models.NotificationTypeSavedSearch).Scan(&count)).To(Succeed())
+			return count
+		}
+
+		InsertVehicle("Toyota RAV4")
+		Expect(jobs.MatchSavedSearches(ctx)).To(Succeed())
+		Expect(CountNotifications()).To(Equal(1))
+
+		InsertVehicle("Toyota Highlander")
+		Expect(jobs.MatchSavedSearches(ctx)).To(Succeed())
+		Expect(CountNotifications()).To(Equal(1))
+
+		_, err = pool.Exec(ctx, "UPDATE saved_searches SET notified_at = NOW() - INTERVAL '25 hours' WHERE user_id = $1", userId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs.MatchSavedSearches(ctx)).To(Succeed())
+		Expect(CountNotifications()).To(Equal(2))
+
+		count := 0
+		sql := "SELECT COUNT(*) FROM saved_search_matches WHERE notified_at IS NOT NULL"
+		Expect(pool.QueryRow(ctx, sql).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+	})
+})
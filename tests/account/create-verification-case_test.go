@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /account/verifications", func() {
+	var (
+		accessToken      string
+		document         []byte
+		responseBody     gin.H
+		userId           string
+		verificationType string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBody := &bytes.Buffer{}
+		writer := multipart.NewWriter(requestBody)
+		if err := writer.WriteField("type", verificationType); err != nil {
+			return nil, err
+		}
+
+		if document != nil {
+			part, err := writer.CreateFormFile("documents", "licence.png")
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err = part.Write(document); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/account/verifications", requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"test@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&userId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		user := &models.User{ID: userId}
+		token, err := user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		buffer := &bytes.Buffer{}
+		Expect(png.Encode(buffer, image.NewRGBA(image.Rect(0, 0, 1, 1)))).To(Succeed())
+
+		accessToken = token
+		document = buffer.Bytes()
+		responseBody = gin.H{}
+		verificationType = models.VerificationTypeDriverLicence
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with a valid document")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the pending verification case")
+		Expect(responseBody).To(HaveKeyWithValue("verification_case", HaveKeyWithValue("status", models.VerificationStatusPending)))
+
+		By("storing the document")
+		documents := []string{}
+		sql := "SELECT documents FROM verification_cases WHERE user_id = $1"
+		Expect(pool.QueryRow(ctx, sql, userId).Scan(&documents)).To(Succeed())
+		Expect(documents).To(HaveLen(1))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without documents and with an invalid type")
+		document = nil
+		verificationType = "passport"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		actual := helpers.GetMapKeys(responseBody)
+		Expect(actual).To(ContainElements("documents", "type"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a document that is not an image or pdf")
+		document = []byte("plain text is not a licence")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("documents"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request when a verification of the same type is pending")
+		_, err := pool.Exec(ctx, "INSERT INTO verification_cases (type, user_id) VALUES ($1, $2)", verificationType, userId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
@@ -2,8 +2,10 @@ package tests
 
 import (
 	"context"
+	"os"
 	"testing"
 
+	"github.com/Ekenzy-101/Pentahire-API/config"
 	"github.com/Ekenzy-101/Pentahire-API/services"
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -24,9 +26,12 @@ var (
 	_ = BeforeSuite(func() {
 		pool = services.CreatePostgresConnectionPool(ctx)
 		redisClient = services.CreateRedisClient(ctx)
+		services.CreateFileStorage()
+		services.CreatePayoutProvider()
 	})
 
 	_ = AfterSuite(func() {
 		pool.Close()
+		Expect(os.RemoveAll(config.StorageDirectory)).To(Succeed())
 	})
 )
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PUT /account/payout-account", func() {
+	var (
+		accessToken   string
+		accountNumber string
+		bankCode      string
+		responseBody  gin.H
+		userId        string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"account_number": accountNumber, "bank_code": bankCode}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPut, "/account/payout-account", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		accountNumber = "0123456789"
+		bankCode = "058"
+		responseBody = gin.H{}
+
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&userId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		var err error
+		user := &models.User{ID: userId}
+		accessToken, err = user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with an account the bank can verify")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the payout account without the full account number")
+		Expect(responseBody).To(HaveKey("payout_account"))
+		payoutAccount := responseBody["payout_account"].(map[string]interface{})
+		Expect(payoutAccount).To(HaveKeyWithValue("account_number_last4", "6789"))
+		Expect(payoutAccount).NotTo(HaveKey("provider_account_id"))
+	})
+
+	It("should be a success", func() {
+		By("replacing an account whose payout ran out of attempts")
+		accountNumber = services.FakeAccountNumberFailingTransfers
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		payout := &models.Payout{Amount: 5000, Currency: config.Currency, UserID: userId}
+		Expect(pool.QueryRow(ctx, "SELECT id FROM payout_accounts WHERE user_id = $1", userId).Scan(&payout.PayoutAccountID)).To(Succeed())
+		Expect(models.InsertPayout(ctx, pool, payout)).To(Succeed())
+		for attempt := 0; attempt < config.PayoutMaxAttempts; attempt++ {
+			_, err = pool.Exec(ctx, "UPDATE payouts SET next_attempt_at = NOW() WHERE id = $1", payout.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs.SendDuePayouts(ctx)).To(Succeed())
+		}
+
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payouts WHERE id = $1", payout.ID).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(models.PayoutStatusFailed))
+
+		By("giving the failed payout back to the host's balance")
+		balance, err := models.SelectHostBalance(ctx, pool, userId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(balance).To(Equal(0))
+
+		accountNumber = "0123456789"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("sending the payout to the new account on the next run")
+		Expect(jobs.SendDuePayouts(ctx)).To(Succeed())
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payouts WHERE id = $1", payout.ID).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(models.PayoutStatusPaid))
+
+		By("taking the payout out of the host's balance again")
+		balance, err = models.SelectHostBalance(ctx, pool, userId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(balance).To(Equal(-payout.Amount))
+
+		By("notifying the host of every failed attempt")
+		count := 0
+		sql := "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND type = $2"
+		Expect(pool.QueryRow(ctx, sql, userId, models.NotificationTypePayoutFailed).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(config.PayoutMaxAttempts))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an account the bank can't verify")
+		accountNumber = services.FakeAccountNumberUnverifiable
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("account_number"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with invalid inputs")
+		accountNumber = "12345"
+		bankCode = "bank"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("account_number"))
+		Expect(responseBody).To(HaveKey("bank_code"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an invalid access token")
+		token, err := services.SignJWTToken(services.JWTOptions{
+			SigningMethod: jwt.SigningMethodHS256,
+			Claims: services.AccessTokenClaims{
+				ID: userId,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now()),
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 401")
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /admin/tax-rules", func() {
+	var (
+		accessToken    string
+		adminId        string
+		requestBodyMap gin.H
+		responseBody   gin.H
+		role           string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/admin/tax-rules", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		requestBodyMap = gin.H{
+			"address_keyword":         "Lagos",
+			"jurisdiction":            "Lagos",
+			"per_day_surcharge":       500,
+			"rental_tax_basis_points": 500,
+		}
+		responseBody = gin.H{}
+		role = config.RoleAdmin
+	})
+
+	JustBeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"admin@test.com", "Test", "Test", "Test", role},
+			InsertColumns: []string{"email", "firstname", "lastname", "password", "role"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&adminId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		admin := &models.User{ID: adminId, Role: role}
+		token, err := admin.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM tax_rules")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM audit_logs")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as an admin with valid inputs")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the tax rule")
+		rule, ok := responseBody["tax_rule"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(rule).To(HaveKeyWithValue("jurisdiction", "Lagos"))
+		Expect(rule).To(HaveKeyWithValue("rental_tax_basis_points", BeNumerically("==", 500)))
+
+		By("recording the action in the audit log")
+		var actorId string
+		sql := "SELECT actor_id FROM audit_logs WHERE action = $1 AND target_id = $2"
+		Expect(pool.QueryRow(ctx, sql, models.AuditActionCreateTaxRule, rule["id"]).Scan(&actorId)).To(Succeed())
+		Expect(actorId).To(Equal(adminId))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with only some of the bounds")
+		requestBodyMap["min_latitude"] = 4
+		requestBodyMap["max_latitude"] = 14
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("not creating the tax rule")
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM tax_rules").Scan(&count)).To(Succeed())
+		Expect(count).To(BeZero())
+	})
+
+	It("should be an error", func() {
+		By("sending a request without any rate or surcharge")
+		requestBodyMap = gin.H{"jurisdiction": "Lagos"}
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an address keyword of more than one part")
+		requestBodyMap["address_keyword"] = "Ikeja, Lagos"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("address_keyword"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as support")
+		role = config.RoleSupport
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+	})
+})
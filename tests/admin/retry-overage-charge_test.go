@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /admin/bookings/:id/retry-overage-charge", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		charge       *models.BookingCharge
+		responseBody gin.H
+		role         string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodPost, "/admin/bookings/"+bookingId+"/retry-overage-charge", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		responseBody = gin.H{}
+		role = config.RoleAdmin
+	})
+
+	JustBeforeEach(func() {
+		adminId, hostId, renterId, vehicleId := "", "", "", ""
+		options := models.SQLOptions{
+			InsertColumns: []string{"email", "firstname", "lastname", "password", "role"},
+			ReturnColumns: []string{"id"},
+		}
+		for email, id := range map[string]*string{"admin@test.com": &adminId, "host@test.com": &hostId, "renter@test.com": &renterId} {
+			options.Arguments = []interface{}{email, "Test", "Test", "Test", config.RoleRenter}
+			if id == &adminId {
+				options.Arguments[4] = role
+			}
+
+			options.Destination = []interface{}{id}
+			Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+		}
+
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = `
+		INSERT INTO bookings (completed_at, end_at, start_at, status, total_amount, user_id, vehicle_id) 
+		VALUES (NOW(), NOW(), NOW() - INTERVAL '1 day', 'completed', 10000, $1, $2) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, renterId, vehicleId).Scan(&bookingId)).To(Succeed())
+
+		payment := &models.Payment{
+			Amount:           10000,
+			AmountCaptured:   10000,
+			BookingID:        bookingId,
+			Currency:         config.Currency,
+			PaymentMethodID:  services.FakePaymentMethodVisa,
+			Provider:         services.GetPaymentProvider().Name(),
+			ProviderIntentID: "pi_" + bookingId,
+			Status:           services.PaymentStatusCaptured,
+		}
+		Expect(models.InsertPayment(ctx, pool, payment)).To(Succeed())
+
+		charge = &models.BookingCharge{
+			Amount:           3500,
+			BookingID:        bookingId,
+			Currency:         config.Currency,
+			Description:      "Mileage and fuel fees for booking " + bookingId,
+			FailureReason:    services.ErrPaymentDeclined.Error(),
+			Provider:         services.GetPaymentProvider().Name(),
+			ProviderIntentID: "pi_failed_" + bookingId,
+			Status:           services.PaymentStatusCancelled,
+			Type:             models.BookingChargeTypeOverage,
+		}
+		Expect(models.InsertBookingCharge(ctx, pool, charge)).To(Succeed())
+
+		admin := &models.User{ID: adminId, Role: role}
+		token, err := admin.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM audit_logs")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a booking whose overage charge failed")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the captured charge")
+		Expect(responseBody).To(HaveKeyWithValue("charge", HaveKeyWithValue("status", services.PaymentStatusCaptured)))
+		Expect(responseBody).To(HaveKeyWithValue("charge", HaveKeyWithValue("amount", BeNumerically("==", charge.Amount))))
+
+		By("keeping the failed charge for the record")
+		count := 0
+		sql := "SELECT COUNT(*) FROM booking_charges WHERE booking_id = $1 AND type = $2"
+		Expect(pool.QueryRow(ctx, sql, bookingId, models.BookingChargeTypeOverage).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+
+		By("recording the action in the audit log")
+		sql = "SELECT COUNT(*) FROM audit_logs WHERE action = $1 AND target_id = $2"
+		Expect(pool.QueryRow(ctx, sql, models.AuditActionRetryOverageCharge, bookingId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be an error", func() {
+		By("sending a request after the charge has gone through")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+	})
+
+	It("should be an error", func() {
+		By("sending a request when the renter's card is declined again")
+		_, err := pool.Exec(ctx, "UPDATE payments SET payment_method_id = $1 WHERE booking_id = $2", services.FakePaymentMethodDeclined, bookingId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 402")
+		Expect(response).To(HaveHTTPStatus(http.StatusPaymentRequired))
+
+		By("recording the failed attempt so it can be retried again")
+		count := 0
+		sql := "SELECT COUNT(*) FROM booking_charges WHERE booking_id = $1 AND failure_reason <> ''"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			role = config.RoleSupport
+		})
+
+		It("should be an error", func() {
+			By("sending a request as a support agent")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 403")
+			Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+		})
+	})
+})
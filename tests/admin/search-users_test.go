@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/helpers"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /admin/users", func() {
+	var (
+		accessToken  string
+		query        string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		supportId := ""
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"support@test.com", "Test", "Test", "Test", config.RoleSupport},
+			InsertColumns: []string{"email", "firstname", "lastname", "password", "role"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&supportId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		options.Arguments = []interface{}{"jane@test.com", "Jane", "Doe", "Test", config.RoleHost}
+		options.Destination = []interface{}{new(string)}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		support := &models.User{ID: supportId, Role: config.RoleSupport}
+		token, err := support.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+		query = "q=jane"
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as a support agent with a search query")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains only the matching users")
+		users, ok := responseBody["users"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(users).To(HaveLen(1))
+		Expect(helpers.GetMapKeys(users[0])).To(ContainElements("id", "email", "role", "suspended_at"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an invalid role filter")
+		query = "role=superuser"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("role"))
+	})
+})
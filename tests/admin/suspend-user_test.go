@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /admin/users/:id/suspend", func() {
+	var (
+		accessToken  string
+		adminId      string
+		reason       string
+		responseBody gin.H
+		role         string
+		userId       string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"reason": reason}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		url := fmt.Sprintf("/admin/users/%v/suspend", userId)
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		reason = "Repeated no-shows"
+		role = config.RoleAdmin
+		responseBody = gin.H{}
+	})
+
+	JustBeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"admin@test.com", "Test", "Test", "Test", role},
+			InsertColumns: []string{"email", "firstname", "lastname", "password", "role"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&adminId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		options.Arguments = []interface{}{"user@test.com", "Test", "Test", "Test", config.RoleRenter}
+		options.Destination = []interface{}{&userId}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		admin := &models.User{ID: adminId, Role: role}
+		token, err := admin.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM audit_logs")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as an admin with a valid reason")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("suspending the user")
+		var suspensionReason string
+		sql := "SELECT suspension_reason FROM users WHERE id = $1 AND suspended_at IS NOT NULL"
+		Expect(pool.QueryRow(ctx, sql, userId).Scan(&suspensionReason)).To(Succeed())
+		Expect(suspensionReason).To(Equal(reason))
+
+		By("recording the action in the audit log")
+		var actorId string
+		sql = "SELECT actor_id FROM audit_logs WHERE action = $1 AND target_id = $2"
+		Expect(pool.QueryRow(ctx, sql, models.AuditActionSuspendUser, userId).Scan(&actorId)).To(Succeed())
+		Expect(actorId).To(Equal(adminId))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without a reason")
+		reason = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("reason"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an admin token after the role was revoked")
+		_, err := pool.Exec(ctx, "UPDATE users SET role = $1 WHERE id = $2", config.RoleRenter, adminId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an admin token after the admin was suspended")
+		_, err := pool.Exec(ctx, "UPDATE users SET suspended_at = NOW() WHERE id = $1", adminId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("leaving the user unsuspended")
+		count := 0
+		sql := "SELECT COUNT(*) FROM users WHERE id = $1 AND suspended_at IS NOT NULL"
+		Expect(pool.QueryRow(ctx, sql, userId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(0))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an admin token after the admin was deleted")
+		_, err := pool.Exec(ctx, "DELETE FROM users WHERE id = $1", adminId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 401")
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+		Expect(responseBody).To(HaveKeyWithValue("message", "Token is invalid or has expired"))
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			role = config.RoleSupport
+		})
+
+		It("should be an error", func() {
+			By("sending a request as a support agent")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 403")
+			Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+			By("returning a body that contains error messages")
+			Expect(responseBody).To(HaveKey("message"))
+		})
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			role = config.RoleRenter
+		})
+
+		It("should be an error", func() {
+			By("sending a request as a renter")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 403")
+			Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+		})
+	})
+})
@@ -158,4 +158,19 @@ var _ = Describe("POST /auth/login", func() {
 		By("returning a body that contains error messages")
 		Expect(responseBody).To(HaveKey("message"))
 	})
+
+	It("should be an error", func() {
+		By("sending a request with the credentials of a suspended user")
+		_, err := pool.Exec(ctx, "UPDATE users SET suspended_at = NOW() WHERE email = $1", email)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
 })
@@ -77,6 +77,9 @@ var _ = Describe("GET /auth/me", func() {
 		actual := helpers.GetMapKeys(responseBody["user"])
 		elements := helpers.GetStructFields(models.User{}, []interface{}{"password", "otp_secret_key", "phone_no"})
 		Expect(actual).To(ContainElements(elements...))
+
+		By("returning a body that contains the user's unread messages count")
+		Expect(responseBody).To(HaveKeyWithValue("unread_messages_count", BeNumerically("==", 0)))
 	})
 
 	It("should be a success", func() {
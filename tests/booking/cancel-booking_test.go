@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings/:id/cancel", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		hostId       string
+		renterId     string
+		responseBody gin.H
+		startAt      time.Time
+		status       string
+	)
+
+	var ExecuteRequest = func(method string, path string) (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(method, "/bookings/"+bookingId+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	var GenerateAccessToken = func(userId string) string {
+		user := &models.User{ID: userId}
+		token, err := user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	BeforeEach(func() {
+		startAt = time.Now().Add(72 * time.Hour)
+		status = models.BookingStatusConfirmed
+		responseBody = gin.H{}
+	})
+
+	// A two day booking under the moderate policy whose payment is authorised
+	JustBeforeEach(func() {
+		vehicleId := ""
+		options := models.SQLOptions{
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+			options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+			options.Destination = []interface{}{id}
+			Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+		}
+
+		sql := `
+		INSERT INTO vehicles (address, cancellation_policy, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', 'moderate', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = `
+		INSERT INTO bookings (cancellation_policy, end_at, start_at, status, total_amount, user_id, vehicle_id) 
+		VALUES ('moderate', $1, $2, $3, 20000, $4, $5) 
+		RETURNING id`
+		arguments := []interface{}{startAt.Add(48 * time.Hour), startAt, status, renterId, vehicleId}
+		Expect(pool.QueryRow(ctx, sql, arguments...).Scan(&bookingId)).To(Succeed())
+
+		provider := services.GetPaymentProvider()
+		intent, err := provider.CreateIntent(ctx, 20000, config.Currency, "booking:"+bookingId)
+		Expect(err).NotTo(HaveOccurred())
+		intent, err = provider.Authorise(ctx, intent.ID, services.FakePaymentMethodVisa)
+		Expect(err).NotTo(HaveOccurred())
+
+		payment := &models.Payment{
+			Amount:           intent.Amount,
+			BookingID:        bookingId,
+			Currency:         intent.Currency,
+			PaymentMethodID:  services.FakePaymentMethodVisa,
+			Provider:         provider.Name(),
+			ProviderIntentID: intent.ID,
+			Status:           intent.Status,
+		}
+		Expect(models.InsertPayment(ctx, pool, payment)).To(Succeed())
+		accessToken = GenerateAccessToken(renterId)
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("previewing the cancellation as the renter three days before the trip")
+		response, err := ExecuteRequest(http.MethodGet, "/cancellation")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains half of the total as the refund")
+		Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("refund_amount", BeNumerically("==", 10000))))
+		Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("host_compensation", BeNumerically("==", 9000))))
+
+		By("not cancelling the booking")
+		Expect(pool.QueryRow(ctx, "SELECT status FROM bookings WHERE id = $1", bookingId).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(models.BookingStatusConfirmed))
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the renter three days before the trip")
+		response, err := ExecuteRequest(http.MethodPost, "/cancel")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the cancellation")
+		Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("cancelled_by", models.CancelledByRenter)))
+
+		By("capturing the part of the payment that isn't refunded")
+		amountCaptured := 0
+		sql := "SELECT status, amount_captured FROM payments WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountCaptured)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		Expect(amountCaptured).To(Equal(10000))
+
+		By("crediting the host with the compensation")
+		balance, err := models.SelectHostBalance(ctx, pool, hostId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(balance).To(Equal(9000))
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the host")
+		accessToken = GenerateAccessToken(hostId)
+		response, err := ExecuteRequest(http.MethodPost, "/cancel")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("refunding the renter in full")
+		Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("refund_amount", BeNumerically("==", 20000))))
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE booking_id = $1", bookingId).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCancelled))
+
+		By("recording the cancellation against the host")
+		count := 0
+		sql := "SELECT COUNT(*) FROM cancellations WHERE user_id = $1 AND cancelled_by = 'host'"
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			startAt = time.Now().Add(12 * time.Hour)
+		})
+
+		It("should be a success", func() {
+			By("sending a request as the renter less than a day before the trip")
+			response, err := ExecuteRequest(http.MethodPost, "/cancel")
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 200")
+			Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+			By("returning a body that contains no refund")
+			Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("refund_amount", BeNumerically("==", 0))))
+		})
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			status = models.BookingStatusCompleted
+		})
+
+		It("should be an error", func() {
+			By("sending a request for a completed booking")
+			response, err := ExecuteRequest(http.MethodPost, "/cancel")
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 400")
+			Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+			By("returning a body that contains error messages")
+			Expect(responseBody).To(HaveKey("message"))
+		})
+	})
+})
@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Co-hosts", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		cohostId     string
+		hostId       string
+		permissions  []string
+		renterId     string
+		responseBody gin.H
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func(method string, path string, requestBody gin.H) (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(method, path, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	var InsertCohost = func(accepted bool) {
+		cohost := &models.Cohost{InvitedBy: hostId, Permissions: permissions, UserID: cohostId, VehicleID: vehicleId}
+		Expect(models.UpsertCohost(ctx, pool, cohost)).To(Succeed())
+		if accepted {
+			Expect(cohost.Accept(ctx, pool)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		permissions = []string{models.CohostPermissionCalendar}
+		hostId, renterId, vehicleId, bookingId = insertBooking(time.Now().Add(72*time.Hour), models.BookingStatusPending, false)
+
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"cohost@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&cohostId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("inviting the co-host as the owner")
+		accessToken = generateAccessToken(hostId)
+		requestBody := gin.H{"email": "cohost@test.com", "permissions": permissions}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/cohosts", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+		cohost, ok := responseBody["cohost"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(cohost).To(HaveKeyWithValue("accepted_at", BeNil()))
+
+		By("not letting the co-host accept the booking before accepting the invitation")
+		accessToken = generateAccessToken(cohostId)
+		response, err = ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/accept", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("accepting the invitation as the co-host")
+		response, err = ExecuteRequest(http.MethodPost, "/account/cohost-invitations/"+cohost["id"].(string)+"/accept", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("accepting the booking as the co-host")
+		response, err = ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/accept", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("status", models.BookingStatusConfirmed)))
+
+		By("listing the booking among the co-host's bookings as a host")
+		response, err = ExecuteRequest(http.MethodGet, "/bookings?role=host", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody).To(HaveKeyWithValue("bookings", HaveLen(1)))
+	})
+
+	It("should be a success", func() {
+		By("cancelling the booking as a co-host who manages the calendar")
+		InsertCohost(true)
+		accessToken = generateAccessToken(cohostId)
+		response, err := ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/cancel", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("recording the cancellation against the host")
+		Expect(responseBody).To(HaveKeyWithValue("cancellation", HaveKeyWithValue("cancelled_by", models.CancelledByHost)))
+		count := 0
+		sql := "SELECT COUNT(*) FROM cancellations WHERE user_id = $1 AND cancelled_by = 'host'"
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be an error", func() {
+		By("accepting the booking as a co-host who only handles messages")
+		permissions = []string{models.CohostPermissionMessages}
+		InsertCohost(true)
+		accessToken = generateAccessToken(cohostId)
+		response, err := ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/accept", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("inviting a co-host as someone other than the owner")
+		accessToken = generateAccessToken(renterId)
+		requestBody := gin.H{"email": "cohost@test.com", "permissions": permissions}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/cohosts", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be a success", func() {
+		By("a co-host who handles messages replying to the renter")
+		permissions = []string{models.CohostPermissionMessages}
+		InsertCohost(true)
+		conversationId := ""
+		sql := "INSERT INTO conversations (host_id, renter_id, vehicle_id) VALUES ($1, $2, $3) RETURNING id"
+		Expect(pool.QueryRow(ctx, sql, hostId, renterId, vehicleId).Scan(&conversationId)).To(Succeed())
+
+		accessToken = generateAccessToken(cohostId)
+		response, err := ExecuteRequest(http.MethodPost, "/conversations/"+conversationId+"/messages", gin.H{"body": "Hello"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("emailing the co-host about the renter's unread messages")
+		sql = "INSERT INTO messages (body, conversation_id, created_at, sender_id) VALUES ('Hi', $1, NOW() - INTERVAL '1 hour', $2)"
+		_, err = pool.Exec(ctx, sql, conversationId, renterId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs.NotifyUnreadMessages(ctx)).To(Succeed())
+
+		count := 0
+		sql = "SELECT COUNT(*) FROM notifications WHERE user_id = ANY($1) AND type = $2"
+		arguments := []interface{}{[]string{hostId, cohostId}, models.NotificationTypeUnreadMessages}
+		Expect(pool.QueryRow(ctx, sql, arguments...).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+	})
+
+	It("should be a success", func() {
+		By("checking in the trip as a co-host who handles check-ins")
+		permissions = []string{models.CohostPermissionCheckIn}
+		InsertCohost(true)
+		_, err := pool.Exec(ctx, "UPDATE bookings SET status = $1 WHERE id = $2", models.BookingStatusInProgress, bookingId)
+		Expect(err).NotTo(HaveOccurred())
+
+		photo := &bytes.Buffer{}
+		Expect(png.Encode(photo, image.NewRGBA(image.Rect(0, 0, 1, 1)))).To(Succeed())
+		requestBody := &bytes.Buffer{}
+		writer := multipart.NewWriter(requestBody)
+		Expect(writer.WriteField("fuel_level", "100")).To(Succeed())
+		Expect(writer.WriteField("odometer", "1000")).To(Succeed())
+		for _, side := range models.InspectionSides {
+			part, err := writer.CreateFormFile(side, side+".png")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = part.Write(photo.Bytes())
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(writer.Close()).To(Succeed())
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/check-in", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(cohostId)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("recording the check-in as acknowledged by the host's side")
+		count := 0
+		sql := "SELECT COUNT(*) FROM inspections WHERE booking_id = $1 AND host_acknowledged_at IS NOT NULL AND user_id = $2"
+		Expect(pool.QueryRow(ctx, sql, bookingId, cohostId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be a success", func() {
+		By("reading the vehicle's earnings as a co-host who can see them")
+		permissions = []string{models.CohostPermissionEarnings}
+		InsertCohost(true)
+		accessToken = generateAccessToken(cohostId)
+		response, err := ExecuteRequest(http.MethodGet, "/vehicles/"+vehicleId+"/earnings", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+	})
+
+	It("should be an error", func() {
+		By("reading the vehicle's earnings as a co-host who only manages the calendar")
+		InsertCohost(true)
+		accessToken = generateAccessToken(cohostId)
+		response, err := ExecuteRequest(http.MethodGet, "/vehicles/"+vehicleId+"/earnings", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
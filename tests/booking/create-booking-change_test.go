@@ -0,0 +1,312 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings/:id/changes", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		endAt        time.Time
+		hostId       string
+		instantBook  bool
+		renterId     string
+		responseBody gin.H
+		startAt      time.Time
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"end_at": endAt, "start_at": startAt}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/changes", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	var ApproveChange = func() {
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		changeId := responseBody["change"].(map[string]interface{})["id"].(string)
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/changes/"+changeId+"/approve", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(hostId)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+	}
+
+	var CancelBooking = func(userId string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/cancel", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(userId)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+		return response
+	}
+
+	BeforeEach(func() {
+		instantBook = false
+		startAt = time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+		endAt = startAt.Add(72 * time.Hour)
+		responseBody = gin.H{}
+	})
+
+	JustBeforeEach(func() {
+		hostId, renterId, vehicleId, bookingId = insertBooking(startAt, models.BookingStatusConfirmed, instantBook)
+		accessToken = generateAccessToken(renterId)
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the renter to extend the trip by a day")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the pending change priced for three days")
+		Expect(responseBody).To(HaveKeyWithValue("change", HaveKeyWithValue("status", models.BookingChangeStatusPending)))
+		Expect(responseBody).To(HaveKeyWithValue("change", HaveKeyWithValue("total_amount", BeNumerically("==", 30000))))
+	})
+
+	It("should be a success", func() {
+		By("the host approving the change")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		changeId := responseBody["change"].(map[string]interface{})["id"].(string)
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/changes/"+changeId+"/approve", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(hostId)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("moving the booking to the new dates")
+		bookingEndAt, totalAmount := time.Time{}, 0
+		sql := "SELECT end_at, total_amount FROM bookings WHERE id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&bookingEndAt, &totalAmount)).To(Succeed())
+		Expect(bookingEndAt.Equal(endAt)).To(BeTrue())
+		Expect(totalAmount).To(Equal(30000))
+
+		By("charging the renter for the extra day")
+		status, amount := "", 0
+		sql = "SELECT status, amount FROM booking_charges WHERE booking_id = $1 AND type = $2"
+		Expect(pool.QueryRow(ctx, sql, bookingId, models.BookingChargeTypeModification).Scan(&status, &amount)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		Expect(amount).To(Equal(10000))
+	})
+
+	It("should be a success", func() {
+		By("asking for the invoice as the renter before the trip changes")
+		request, err := http.NewRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		firstNumber := ""
+		Expect(pool.QueryRow(ctx, "SELECT number FROM invoices WHERE booking_id = $1", bookingId).Scan(&firstNumber)).To(Succeed())
+
+		By("the host approving a change that extends the trip")
+		ApproveChange()
+
+		By("issuing a new revision of the invoice for what the renter paid")
+		number, revision, totalAmount := "", 0, 0
+		sql := "SELECT number, revision, total_amount FROM invoices WHERE booking_id = $1 ORDER BY revision DESC LIMIT 1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&number, &revision, &totalAmount)).To(Succeed())
+		Expect(number).NotTo(Equal(firstNumber))
+		Expect(revision).To(Equal(2))
+		Expect(totalAmount).To(Equal(30000))
+
+		By("returning the new revision, which says which invoice it replaces")
+		request, err = http.NewRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response = httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(response.Header().Get("Content-Disposition")).To(ContainSubstring(number + ".pdf"))
+		Expect(response.Body.String()).To(ContainSubstring(firstNumber))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for dates that overlap another booking")
+		sql := "INSERT INTO bookings (end_at, start_at, status, total_amount, user_id, vehicle_id) VALUES ($1, $2, 'confirmed', 0, $3, $4)"
+		_, err := pool.Exec(ctx, sql, endAt, endAt.Add(-time.Hour), hostId, vehicleId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 409")
+		Expect(response).To(HaveHTTPStatus(http.StatusConflict))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as the host")
+		accessToken = generateAccessToken(hostId)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for a trip of a vehicle that has been unlisted")
+		_, err := pool.Exec(ctx, "UPDATE vehicles SET unlisted_at = NOW() WHERE id = $1", vehicleId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			instantBook = true
+			endAt = startAt.Add(24 * time.Hour)
+		})
+
+		It("should be a success", func() {
+			By("sending a request as an eligible renter to shorten a trip of an instant book vehicle")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 201")
+			Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+			By("returning a body that contains the approved change")
+			Expect(responseBody).To(HaveKeyWithValue("change", HaveKeyWithValue("status", models.BookingChangeStatusApproved)))
+			Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("total_amount", BeNumerically("==", 10000))))
+
+			By("only capturing the new total when the trip starts")
+			amount := 0
+			Expect(pool.QueryRow(ctx, "SELECT amount FROM payments WHERE booking_id = $1", bookingId).Scan(&amount)).To(Succeed())
+			Expect(amount).To(Equal(10000))
+		})
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			startAt = time.Now().Add(12 * time.Hour).UTC().Truncate(time.Second)
+			endAt = startAt.Add(72 * time.Hour)
+		})
+
+		It("should be a success", func() {
+			By("the renter cancelling an extended trip less than a day before it starts")
+			ApproveChange()
+			response := CancelBooking(renterId)
+
+			By("returning a status code of 200")
+			Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+			By("capturing the original total from the payment")
+			status, amountCaptured := "", 0
+			sql := "SELECT status, amount_captured FROM payments WHERE booking_id = $1"
+			Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountCaptured)).To(Succeed())
+			Expect(status).To(Equal(services.PaymentStatusCaptured))
+			Expect(amountCaptured).To(Equal(20000))
+
+			By("keeping the charge for the extra day")
+			amountRefunded := 0
+			sql = "SELECT status, amount_refunded FROM booking_charges WHERE booking_id = $1"
+			Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountRefunded)).To(Succeed())
+			Expect(status).To(Equal(services.PaymentStatusCaptured))
+			Expect(amountRefunded).To(Equal(0))
+		})
+
+		It("should be a success", func() {
+			By("the host cancelling an extended trip")
+			ApproveChange()
+			response := CancelBooking(hostId)
+
+			By("returning a status code of 200")
+			Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+			By("cancelling the payment")
+			status := ""
+			Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE booking_id = $1", bookingId).Scan(&status)).To(Succeed())
+			Expect(status).To(Equal(services.PaymentStatusCancelled))
+
+			By("refunding the charge for the extra day")
+			amountRefunded := 0
+			sql := "SELECT status, amount_refunded FROM booking_charges WHERE booking_id = $1"
+			Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountRefunded)).To(Succeed())
+			Expect(status).To(Equal(services.PaymentStatusRefunded))
+			Expect(amountRefunded).To(Equal(10000))
+
+			By("taking the refunded charge back from the host")
+			balance := 0
+			sql = `
+			SELECT COALESCE(-SUM(e.amount), 0)
+			FROM ledger_entries AS e
+			JOIN ledger_accounts AS a ON e.account_id = a.id
+			WHERE a.type = $1 AND a.user_id = $2`
+			Expect(pool.QueryRow(ctx, sql, models.LedgerAccountHost, hostId).Scan(&balance)).To(Succeed())
+			Expect(balance).To(Equal(0))
+		})
+	})
+})
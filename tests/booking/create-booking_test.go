@@ -0,0 +1,254 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings", func() {
+	var (
+		accessToken     string
+		endAt           time.Time
+		hostId          string
+		instantBook     bool
+		isVerified      bool
+		minTrips        int
+		paymentMethodId string
+		responseBody    gin.H
+		renterId        string
+		startAt         time.Time
+		vehicleId       string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"end_at": endAt, "payment_method_id": paymentMethodId, "start_at": startAt, "vehicle_id": vehicleId}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		startAt = time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+		endAt = startAt.Add(36 * time.Hour)
+		instantBook = false
+		isVerified = true
+		minTrips = 0
+		paymentMethodId = services.FakePaymentMethodVisa
+		responseBody = gin.H{}
+	})
+
+	JustBeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&hostId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		options.Arguments = []interface{}{"renter@test.com", "Test", "Test", "Test"}
+		options.Destination = []interface{}{&renterId}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		sql := `
+		INSERT INTO vehicles (address, instant_book, instant_book_min_trips, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', $1, $2, POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $3) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, instantBook, minTrips, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = "UPDATE users SET email_verified_at = NOW(), phone_verified_at = NOW() WHERE id = $1"
+		_, err := pool.Exec(ctx, sql, renterId)
+		Expect(err).NotTo(HaveOccurred())
+
+		if isVerified {
+			sql = "INSERT INTO verification_cases (status, type, user_id) VALUES ('approved', 'driver_licence', $1)"
+			_, err = pool.Exec(ctx, sql, renterId)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		renter := &models.User{ID: renterId}
+		token, err := renter.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with valid inputs as a verified renter")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the pending booking priced per started day")
+		booking, ok := responseBody["booking"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(booking).To(HaveKeyWithValue("status", models.BookingStatusPending))
+		Expect(booking).To(HaveKeyWithValue("total_amount", BeNumerically("==", 20000)))
+
+		By("returning a body that contains the authorised payment")
+		payment, ok := responseBody["payment"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(payment).To(HaveKeyWithValue("status", services.PaymentStatusAuthorised))
+		Expect(payment).To(HaveKeyWithValue("amount", BeNumerically("==", 20000)))
+	})
+
+	It("should be a success", func() {
+		By("the booking request expiring before the host answers it")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		booking, ok := responseBody["booking"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(booking).To(HaveKeyWithValue("expires_at", Not(BeNil())))
+
+		_, err = pool.Exec(ctx, "UPDATE bookings SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1", booking["id"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jobs.ExpireBookingRequests(ctx)).To(Succeed())
+
+		By("expiring the booking and releasing the payment")
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM bookings WHERE id = $1", booking["id"]).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(models.BookingStatusExpired))
+
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE booking_id = $1", booking["id"]).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCancelled))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a payment method that is declined")
+		paymentMethodId = services.FakePaymentMethodDeclined
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 402")
+		Expect(response).To(HaveHTTPStatus(http.StatusPaymentRequired))
+
+		By("not creating the booking")
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM bookings").Scan(&count)).To(Succeed())
+		Expect(count).To(BeZero())
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an end date before the start date")
+		endAt = startAt.Add(-time.Hour)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("end_at"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for dates that overlap another booking")
+		sql := "INSERT INTO bookings (end_at, start_at, total_amount, user_id, vehicle_id) VALUES ($1, $2, 0, $3, $4)"
+		_, err := pool.Exec(ctx, sql, endAt, startAt.Add(time.Hour), hostId, vehicleId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 409")
+		Expect(response).To(HaveHTTPStatus(http.StatusConflict))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			instantBook = true
+		})
+
+		It("should be a success", func() {
+			By("sending a request for an instant book vehicle as an eligible renter")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 201")
+			Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+			By("returning a body that contains the confirmed booking")
+			Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("status", models.BookingStatusConfirmed)))
+			Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("expires_at", BeNil())))
+		})
+
+		Context("", func() {
+			BeforeEach(func() {
+				minTrips = 3
+			})
+
+			It("should be a success", func() {
+				By("sending a request for an instant book vehicle as a renter without enough trips")
+				response, err := ExecuteRequest()
+				Expect(err).NotTo(HaveOccurred())
+
+				By("returning a status code of 201")
+				Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+				By("returning a body that contains a booking request for the host")
+				Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("status", models.BookingStatusPending)))
+			})
+		})
+	})
+
+	Context("", func() {
+		BeforeEach(func() {
+			isVerified = false
+		})
+
+		It("should be an error", func() {
+			By("sending a request as a renter whose driver's licence is not verified")
+			response, err := ExecuteRequest()
+			Expect(err).NotTo(HaveOccurred())
+
+			By("returning a status code of 403")
+			Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+			By("returning a body that contains error messages")
+			Expect(responseBody).To(HaveKey("message"))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBookingRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Booking")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+		services.CreateRedisClient(ctx)
+		services.CreateFileStorage()
+		services.CreatePaymentProvider()
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+	})
+)
+
+// Inserts a host, a renter, an instant book vehicle without requirements when
+// instantBook is true and a two day booking of it from startAt under the moderate
+// policy, whose payment is authorised
+func insertBooking(startAt time.Time, status string, instantBook bool) (string, string, string, string) {
+	hostId, renterId, vehicleId, bookingId := "", "", "", ""
+	options := models.SQLOptions{
+		InsertColumns: []string{"email", "firstname", "lastname", "password"},
+		ReturnColumns: []string{"id"},
+	}
+	for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+		options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+		options.Destination = []interface{}{id}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	}
+
+	sql := "UPDATE users SET email_verified_at = NOW(), phone_verified_at = NOW() WHERE id = $1"
+	_, err := pool.Exec(ctx, sql, renterId)
+	Expect(err).NotTo(HaveOccurred())
+
+	sql = `
+	INSERT INTO vehicles (address, cancellation_policy, instant_book, location, make, name, rental_fee, user_id) 
+	VALUES ('Lagos', 'moderate', $1, POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $2) 
+	RETURNING id`
+	Expect(pool.QueryRow(ctx, sql, instantBook, hostId).Scan(&vehicleId)).To(Succeed())
+
+	sql = `
+	INSERT INTO bookings (cancellation_policy, end_at, start_at, status, total_amount, user_id, vehicle_id) 
+	VALUES ('moderate', $1, $2, $3, 20000, $4, $5) 
+	RETURNING id`
+	arguments := []interface{}{startAt.Add(48 * time.Hour), startAt, status, renterId, vehicleId}
+	Expect(pool.QueryRow(ctx, sql, arguments...).Scan(&bookingId)).To(Succeed())
+
+	provider := services.GetPaymentProvider()
+	intent, err := provider.CreateIntent(ctx, 20000, config.Currency, "booking:"+bookingId)
+	Expect(err).NotTo(HaveOccurred())
+	intent, err = provider.Authorise(ctx, intent.ID, services.FakePaymentMethodVisa)
+	Expect(err).NotTo(HaveOccurred())
+
+	payment := &models.Payment{
+		Amount:           intent.Amount,
+		BookingID:        bookingId,
+		Currency:         intent.Currency,
+		PaymentMethodID:  services.FakePaymentMethodVisa,
+		Provider:         provider.Name(),
+		ProviderIntentID: intent.ID,
+		Status:           intent.Status,
+	}
+	Expect(models.InsertPayment(ctx, pool, payment)).To(Succeed())
+	return hostId, renterId, vehicleId, bookingId
+}
+
+func generateAccessToken(userId string) string {
+	user := &models.User{ID: userId}
+	token, err := user.GenerateAccessToken()
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /bookings/:id/invoice", func() {
+	var (
+		accessToken string
+		bookingId   string
+		hostId      string
+		renterId    string
+	)
+
+	var ExecuteRequest = func(method string, path string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(method, path, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		return response
+	}
+
+	var CountInvoices = func() int {
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM invoices WHERE booking_id = $1", bookingId).Scan(&count)).To(Succeed())
+		return count
+	}
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("accepting a booking request as the host")
+		hostId, renterId, _, bookingId = insertBooking(time.Now().Add(72*time.Hour), models.BookingStatusPending, false)
+		accessToken = generateAccessToken(hostId)
+		response := ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/accept")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("issuing the booking's invoice")
+		Expect(CountInvoices()).To(Equal(1))
+		number := ""
+		Expect(pool.QueryRow(ctx, "SELECT number FROM invoices WHERE booking_id = $1", bookingId).Scan(&number)).To(Succeed())
+		Expect(number).To(MatchRegexp(`^INV-\d{4}-\d{6}$`))
+
+		By("returning the invoice as a PDF to the renter")
+		accessToken = generateAccessToken(renterId)
+		response = ExecuteRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(response.Header().Get("Content-Type")).To(Equal("application/pdf"))
+		Expect(response.Header().Get("Content-Disposition")).To(ContainSubstring(number + ".pdf"))
+		Expect(response.Body.String()).To(HavePrefix("%PDF-"))
+		Expect(response.Body.String()).To(ContainSubstring(number))
+
+		By("not issuing another invoice when it's asked for again")
+		response = ExecuteRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(CountInvoices()).To(Equal(1))
+	})
+
+	It("should be a success", func() {
+		By("asking for the invoice of a booking confirmed before invoices were issued")
+		hostId, _, _, bookingId = insertBooking(time.Now().Add(72*time.Hour), models.BookingStatusConfirmed, false)
+		accessToken = generateAccessToken(hostId)
+		response := ExecuteRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice")
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("issuing the invoice")
+		Expect(CountInvoices()).To(Equal(1))
+	})
+
+	It("should be an error", func() {
+		By("asking for the invoice of a booking request")
+		_, renterId, _, bookingId = insertBooking(time.Now().Add(72*time.Hour), models.BookingStatusPending, false)
+		accessToken = generateAccessToken(renterId)
+		response := ExecuteRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice")
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("not issuing an invoice")
+		Expect(CountInvoices()).To(Equal(0))
+	})
+})
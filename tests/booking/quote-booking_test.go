@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings/quote", func() {
+	var (
+		accessToken  string
+		endAt        time.Time
+		promoCode    string
+		renterId     string
+		responseBody gin.H
+		startAt      time.Time
+		useCredits   bool
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"end_at": endAt, "promo_code": promoCode, "start_at": startAt, "use_credits": useCredits, "vehicle_id": vehicleId}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/quote", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		startAt = time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+		endAt = startAt.Add(48 * time.Hour)
+		promoCode = "welcome10"
+		responseBody = gin.H{}
+		useCredits = true
+
+		// The renter's earlier booking makes this one not their first trip
+		_, renterId, vehicleId, _ = insertBooking(time.Now().Add(24*time.Hour), models.BookingStatusConfirmed, false)
+		accessToken = generateAccessToken(renterId)
+
+		sql := `
+		INSERT INTO promo_codes (code, discount_type, discount_value, first_trip_only, min_trip_days)
+		VALUES ('WELCOME10', 'percentage', 10, FALSE, 2), ('FIRSTTRIP', 'fixed', 5000, TRUE, 1)`
+		_, err := pool.Exec(ctx, sql)
+		Expect(err).NotTo(HaveOccurred())
+
+		entry := &models.CreditEntry{Amount: 3000, Description: "Referral reward for your first trip", UserID: renterId}
+		Expect(models.InsertCreditEntry(ctx, pool, entry)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM promo_codes")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM tax_rules")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with a promo code and credits")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the quote with the discount taken off before the credits")
+		quote, ok := responseBody["quote"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(quote).To(HaveKeyWithValue("subtotal", BeNumerically("==", 20000)))
+		Expect(quote).To(HaveKeyWithValue("discount_amount", BeNumerically("==", 2000)))
+		Expect(quote).To(HaveKeyWithValue("credit_amount", BeNumerically("==", 3000)))
+		Expect(quote).To(HaveKeyWithValue("total_amount", BeNumerically("==", 15000)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a vehicle in the jurisdictions of tax rules")
+		sql := `
+		INSERT INTO tax_rules (address_keyword, jurisdiction, max_latitude, max_longitude, min_latitude, min_longitude, per_day_surcharge, rental_tax_basis_points, vat_basis_points)
+		VALUES ('lagos', 'Lagos', NULL, NULL, NULL, NULL, 500, 500, 0),
+			(NULL, 'Nigeria', 14, 15, 4, 2, 0, 0, 750),
+			(NULL, 'Everywhere', NULL, NULL, NULL, NULL, 0, 0, 500),
+			('lag', 'Lag', NULL, NULL, NULL, NULL, 0, 1000, 0),
+			('abuja', 'Abuja', NULL, NULL, NULL, NULL, 0, 1000, 0)`
+		_, err := pool.Exec(ctx, sql)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the quote with the taxes on the discounted rental before the credits")
+		quote, ok := responseBody["quote"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(quote).To(HaveKeyWithValue("tax_amount", BeNumerically("==", 3392)))
+		Expect(quote).To(HaveKeyWithValue("total_amount", BeNumerically("==", 18392)))
+
+		By("returning a body that contains the tax lines of the most specific rules that apply to the vehicle")
+		taxLines, ok := quote["tax_lines"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(taxLines).To(HaveLen(3))
+		amounts := map[string]interface{}{}
+		for _, taxLine := range taxLines {
+			line := taxLine.(map[string]interface{})
+			Expect(line["jurisdiction"]).To(BeElementOf("Lagos", "Nigeria"))
+			amounts[line["type"].(string)] = line["amount"]
+		}
+		Expect(amounts).To(HaveKeyWithValue(models.TaxLineTypeRentalTax, BeNumerically("==", 900)))
+		Expect(amounts).To(HaveKeyWithValue(models.TaxLineTypeSurcharge, BeNumerically("==", 1000)))
+		Expect(amounts).To(HaveKeyWithValue(models.TaxLineTypeVAT, BeNumerically("==", 1492)))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a promo code for a trip shorter than it allows")
+		endAt = startAt.Add(24 * time.Hour)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("promo_code"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a first trip promo code as a renter with a trip")
+		promoCode = "firsttrip"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKeyWithValue("promo_code", "This promo code is only for your first trip"))
+	})
+})
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Booking taxes", func() {
+	var (
+		bookingId    string
+		hostId       string
+		renterId     string
+		responseBody gin.H
+		startAt      time.Time
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func(method string, path string, userId string, requestBody gin.H) *httptest.ResponseRecorder {
+		requestBodyBytes, err := json.Marshal(requestBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		request, err := http.NewRequest(method, path, bytes.NewReader(requestBodyBytes))
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(userId)})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		if strings.HasPrefix(response.Header().Get("Content-Type"), "application/json") {
+			Expect(json.Unmarshal(response.Body.Bytes(), &responseBody)).To(Succeed())
+		}
+
+		return response
+	}
+
+	var ChangeBooking = func(endAt time.Time) {
+		requestBody := gin.H{"end_at": endAt, "start_at": startAt}
+		response := ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/changes", renterId, requestBody)
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+		Expect(responseBody).To(HaveKeyWithValue("change", HaveKeyWithValue("status", models.BookingChangeStatusApproved)))
+	}
+
+	var SelectTaxLines = func() []models.TaxLine {
+		lines, err := models.SelectBookingTaxLines(ctx, pool, bookingId)
+		Expect(err).NotTo(HaveOccurred())
+		return lines
+	}
+
+	var SelectTaxAmounts = func() []int {
+		amounts := []int{}
+		for _, line := range SelectTaxLines() {
+			amounts = append(amounts, line.Amount)
+		}
+
+		return amounts
+	}
+
+	var SelectTaxBalance = func() int {
+		balance := 0
+		sql := `
+		SELECT COALESCE(-SUM(e.amount), 0)
+		FROM ledger_entries AS e
+		JOIN ledger_accounts AS a ON e.account_id = a.id
+		WHERE a.type = $1`
+		Expect(pool.QueryRow(ctx, sql, models.LedgerAccountTax).Scan(&balance)).To(Succeed())
+		return balance
+	}
+
+	BeforeEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		sql := "INSERT INTO tax_rules (jurisdiction, vat_basis_points) VALUES ('Nigeria', 1000)"
+		_, err = pool.Exec(ctx, sql)
+		Expect(err).NotTo(HaveOccurred())
+
+		options := models.SQLOptions{
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+			options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+			options.Destination = []interface{}{id}
+			Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+		}
+
+		sql = "UPDATE users SET email_verified_at = NOW(), phone_verified_at = NOW() WHERE id = $1"
+		_, err = pool.Exec(ctx, sql, renterId)
+		Expect(err).NotTo(HaveOccurred())
+
+		sql = "INSERT INTO verification_cases (status, type, user_id) VALUES ('approved', 'driver_licence', $1)"
+		_, err = pool.Exec(ctx, sql, renterId)
+		Expect(err).NotTo(HaveOccurred())
+
+		sql = `
+		INSERT INTO vehicles (address, instant_book, location, make, name, rental_fee, user_id)
+		VALUES ('Ikeja, Lagos', true, POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1)
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		startAt = time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second)
+		requestBody := gin.H{
+			"end_at":            startAt.Add(48 * time.Hour),
+			"payment_method_id": services.FakePaymentMethodVisa,
+			"start_at":          startAt,
+			"vehicle_id":        vehicleId,
+		}
+		response := ExecuteRequest(http.MethodPost, "/bookings", renterId, requestBody)
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+		bookingId = responseBody["booking"].(map[string]interface{})["id"].(string)
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM tax_rules")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("storing the taxes the booking was priced with")
+		Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("tax_amount", BeNumerically("==", 2000))))
+		Expect(responseBody).To(HaveKeyWithValue("booking", HaveKeyWithValue("total_amount", BeNumerically("==", 22000))))
+		Expect(SelectTaxAmounts()).To(Equal([]int{2000}))
+
+		By("keeping the booking's rates when it's extended after a new rule was added")
+		_, err := pool.Exec(ctx, "INSERT INTO tax_rules (address_keyword, jurisdiction, rental_tax_basis_points) VALUES ('lagos', 'Lagos', 500)")
+		Expect(err).NotTo(HaveOccurred())
+
+		ChangeBooking(startAt.Add(72 * time.Hour))
+		lines := SelectTaxLines()
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0].Type).To(Equal(models.TaxLineTypeVAT))
+		Expect(lines[0].Amount).To(Equal(3000))
+
+		By("charging only the tax the extra day adds with the change")
+		amount, taxAmount := 0, 0
+		sql := "SELECT amount, tax_amount FROM booking_charges WHERE booking_id = $1 AND type = $2"
+		Expect(pool.QueryRow(ctx, sql, bookingId, models.BookingChargeTypeModification).Scan(&amount, &taxAmount)).To(Succeed())
+		Expect(amount).To(Equal(11000))
+		Expect(taxAmount).To(Equal(1000))
+
+		By("showing the taxes of the extended trip on its invoice")
+		response := ExecuteRequest(http.MethodGet, "/bookings/"+bookingId+"/invoice", renterId, nil)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(response.Body.String()).To(ContainSubstring("Nigeria VAT"))
+		Expect(response.Body.String()).To(ContainSubstring("NGN 30.00"))
+		Expect(response.Body.String()).NotTo(ContainSubstring("Lagos rental tax"))
+
+		By("posting the payment's own tax when the trip starts")
+		response = ExecuteRequest(http.MethodPost, "/bookings/"+bookingId+"/start", hostId, nil)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(SelectTaxBalance()).To(Equal(3000))
+
+		By("giving back the tax of the day that's refunded when the trip is shortened")
+		ChangeBooking(startAt.Add(48 * time.Hour))
+		Expect(SelectTaxBalance()).To(Equal(2000))
+		Expect(SelectTaxAmounts()).To(Equal([]int{2000}))
+
+		By("exporting the tax collected on the host's bookings as CSV")
+		response = ExecuteRequest(http.MethodGet, "/account/tax-summary", hostId, nil)
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(response.Header().Get("Content-Type")).To(HavePrefix("text/csv"))
+
+		records, err := csv.NewReader(response.Body).ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(14))
+		Expect(records[0]).To(Equal([]string{"month", "currency", "bookings", "gross_amount", "tax_amount", "platform_fee", "earnings"}))
+
+		now := time.Now().UTC()
+		month := fmt.Sprintf("%d-%02d", now.Year(), now.Month())
+		Expect(records).To(ContainElement(Equal([]string{month, config.Currency, "1", "22000", "2000", "2000", "18000"})))
+		Expect(records[13]).To(Equal([]string{"total", config.Currency, "", "22000", "2000", "2000", "18000"}))
+	})
+})
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /claims/:id/accept", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		claim        *models.Claim
+		hostId       string
+		renterId     string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodPost, "/claims/"+claim.ID+"/accept", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId, renterId, bookingId = insertCompletedTrip(20000)
+		claim = &models.Claim{
+			ID:          uuid.NewString(),
+			Amount:      15000,
+			BookingID:   bookingId,
+			Description: "The rear bumper was dented",
+			Photos:      []string{},
+			UserID:      hostId,
+		}
+		Expect(models.InsertClaim(ctx, pool, claim)).To(Succeed())
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the renter")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the accepted claim")
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("status", models.ClaimStatusAccepted)))
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("settled_amount", BeNumerically("==", claim.Amount))))
+
+		By("capturing the amount claimed from the deposit")
+		status, amountCaptured := "", 0
+		sql := "SELECT status, amount_captured FROM security_deposits WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountCaptured)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		Expect(amountCaptured).To(Equal(claim.Amount))
+
+		By("leaving the ledger reconciled with the provider")
+		discrepancies, err := models.ReconcileLedger(ctx, pool, services.GetPaymentProvider())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discrepancies).To(BeEmpty())
+	})
+
+	It("should be an error", func() {
+		By("sending a request for a claim that was already disputed")
+		_, err := pool.Exec(ctx, "UPDATE claims SET status = $1 WHERE id = $2", models.ClaimStatusDisputed, claim.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("leaving the deposit held")
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM security_deposits WHERE booking_id = $1", bookingId).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusAuthorised))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as the host")
+		accessToken = generateAccessToken(hostId, config.RoleHost)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
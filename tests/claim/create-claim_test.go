@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings/:id/claims", func() {
+	var (
+		accessToken  string
+		amount       int
+		bookingId    string
+		hostId       string
+		photo        []byte
+		photosCount  int
+		renterId     string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBody := &bytes.Buffer{}
+		writer := multipart.NewWriter(requestBody)
+		if err := writer.WriteField("amount", strconv.Itoa(amount)); err != nil {
+			return nil, err
+		}
+
+		if err := writer.WriteField("description", "The rear bumper was dented"); err != nil {
+			return nil, err
+		}
+
+		for index := 0; index < photosCount; index++ {
+			part, err := writer.CreateFormFile("photos", "bumper.png")
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err = part.Write(photo); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/claims", requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId, renterId, bookingId = insertCompletedTrip(20000)
+		buffer := &bytes.Buffer{}
+		Expect(png.Encode(buffer, image.NewRGBA(image.Rect(0, 0, 1, 1)))).To(Succeed())
+
+		accessToken = generateAccessToken(hostId, config.RoleHost)
+		amount = 15000
+		photo = buffer.Bytes()
+		photosCount = 1
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the host within the claim window")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the pending claim")
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("status", models.ClaimStatusPending)))
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("photos_count", BeNumerically("==", 1))))
+	})
+
+	It("should be a success", func() {
+		By("the renter accepting the claim")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		claimId := responseBody["claim"].(map[string]interface{})["id"].(string)
+		request, err := http.NewRequest(http.MethodPost, "/claims/"+claimId+"/accept", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(renterId, config.RoleRenter)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("capturing the amount claimed from the deposit")
+		status, amountCaptured := "", 0
+		sql := "SELECT status, amount_captured FROM security_deposits WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amountCaptured)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		Expect(amountCaptured).To(Equal(amount))
+
+		By("crediting the host with all of it")
+		balance, err := models.SelectHostBalance(ctx, pool, hostId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(balance).To(Equal(amount))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with more photos than a claim can have")
+		photosCount = config.MaxClaimPhotos + 1
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("photos"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for more than the deposit")
+		amount = 25000
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("amount"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request after the claim window")
+		_, err := pool.Exec(ctx, "UPDATE bookings SET completed_at = NOW() - $1::interval WHERE id = $2", "49 hours", bookingId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as the renter")
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
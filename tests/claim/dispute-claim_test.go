@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /claims/:id/dispute", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		claim        *models.Claim
+		hostId       string
+		reason       string
+		renterId     string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(gin.H{"reason": reason})
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/claims/"+claim.ID+"/dispute", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId, renterId, bookingId = insertCompletedTrip(20000)
+		claim = &models.Claim{
+			ID:          uuid.NewString(),
+			Amount:      15000,
+			BookingID:   bookingId,
+			Description: "The rear bumper was dented",
+			Photos:      []string{},
+			UserID:      hostId,
+		}
+		Expect(models.InsertClaim(ctx, pool, claim)).To(Succeed())
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+		reason = "The dent was there before"
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the renter with a reason")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the disputed claim")
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("status", models.ClaimStatusDisputed)))
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("dispute_reason", reason)))
+
+		By("leaving the deposit held for an admin to resolve")
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM security_deposits WHERE booking_id = $1", bookingId).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusAuthorised))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without a reason")
+		reason = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("reason"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for a claim that was already accepted")
+		_, err := pool.Exec(ctx, "UPDATE claims SET status = $1 WHERE id = $2", models.ClaimStatusAccepted, claim.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as the host")
+		accessToken = generateAccessToken(hostId, config.RoleHost)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+	})
+})
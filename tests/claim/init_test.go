@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestClaimRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Claim")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+		services.CreateRedisClient(ctx)
+		services.CreateFileStorage()
+		services.CreatePaymentProvider()
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+		Expect(os.RemoveAll(config.StorageDirectory)).To(Succeed())
+	})
+)
+
+// Inserts a host, a renter and a trip that was just completed with its security
+// deposit held by the fake provider
+func insertCompletedTrip(securityDeposit int) (string, string, string) {
+	hostId, renterId, vehicleId, bookingId := "", "", "", ""
+	options := models.SQLOptions{
+		InsertColumns: []string{"email", "firstname", "lastname", "password"},
+		ReturnColumns: []string{"id"},
+	}
+	for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+		options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+		options.Destination = []interface{}{id}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	}
+
+	sql := `
+	INSERT INTO vehicles (address, location, make, name, rental_fee, security_deposit, user_id) 
+	VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1, $2) 
+	RETURNING id`
+	Expect(pool.QueryRow(ctx, sql, securityDeposit, hostId).Scan(&vehicleId)).To(Succeed())
+
+	sql = `
+	INSERT INTO bookings (completed_at, end_at, security_deposit, start_at, status, total_amount, user_id, vehicle_id) 
+	VALUES (NOW(), NOW(), $1, NOW() - INTERVAL '1 day', 'completed', 10000, $2, $3) 
+	RETURNING id`
+	Expect(pool.QueryRow(ctx, sql, securityDeposit, renterId, vehicleId).Scan(&bookingId)).To(Succeed())
+
+	provider := services.GetPaymentProvider()
+	intent, err := provider.CreateIntent(ctx, securityDeposit, config.Currency, "security_deposit:"+bookingId)
+	Expect(err).NotTo(HaveOccurred())
+	intent, err = provider.Authorise(ctx, intent.ID, services.FakePaymentMethodVisa)
+	Expect(err).NotTo(HaveOccurred())
+
+	deposit := &models.SecurityDeposit{
+		Amount:           intent.Amount,
+		BookingID:        bookingId,
+		Currency:         intent.Currency,
+		Provider:         provider.Name(),
+		ProviderIntentID: intent.ID,
+		Status:           intent.Status,
+	}
+	Expect(models.InsertSecurityDeposit(ctx, pool, deposit)).To(Succeed())
+	return hostId, renterId, bookingId
+}
+
+func generateAccessToken(userId string, role string) string {
+	user := &models.User{ID: userId, Role: role}
+	token, err := user.GenerateAccessToken()
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
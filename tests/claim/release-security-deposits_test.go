@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"fmt"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("jobs.ReleaseSecurityDeposits", func() {
+	var (
+		bookingId string
+		claim     *models.Claim
+		hostId    string
+	)
+
+	var SelectStatus = func(table string, column string, id string) string {
+		status := ""
+		sql := fmt.Sprintf("SELECT status FROM %v WHERE %v = $1", table, column)
+		Expect(pool.QueryRow(ctx, sql, id).Scan(&status)).To(Succeed())
+		return status
+	}
+
+	var Age = func(table string, column string, id string, age float64) {
+		sql := fmt.Sprintf("UPDATE %v SET %v = NOW() - $1::INTERVAL WHERE id = $2", table, column)
+		_, err := pool.Exec(ctx, sql, fmt.Sprintf("%v seconds", age+60), id)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		hostId, _, bookingId = insertCompletedTrip(20000)
+		claim = &models.Claim{
+			ID:          uuid.NewString(),
+			Amount:      5000,
+			BookingID:   bookingId,
+			Description: "The rear bumper was dented",
+			Photos:      []string{},
+			UserID:      hostId,
+		}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("running the job after the claim window has passed")
+		Age("bookings", "completed_at", bookingId, config.ClaimWindow.Seconds())
+		Expect(jobs.ReleaseSecurityDeposits(ctx)).To(Succeed())
+
+		By("releasing the deposit")
+		Expect(SelectStatus("security_deposits", "booking_id", bookingId)).To(Equal(services.PaymentStatusCancelled))
+
+		By("leaving the ledger reconciled with the provider")
+		discrepancies, err := models.ReconcileLedger(ctx, pool, services.GetPaymentProvider())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discrepancies).To(BeEmpty())
+	})
+
+	It("should be a success", func() {
+		By("running the job within the claim window")
+		Expect(jobs.ReleaseSecurityDeposits(ctx)).To(Succeed())
+
+		By("keeping the deposit held")
+		Expect(SelectStatus("security_deposits", "booking_id", bookingId)).To(Equal(services.PaymentStatusAuthorised))
+	})
+
+	It("should be a success", func() {
+		By("running the job for a trip with a recent claim after the claim window")
+		Expect(models.InsertClaim(ctx, pool, claim)).To(Succeed())
+		Age("bookings", "completed_at", bookingId, config.ClaimWindow.Seconds())
+		Expect(jobs.ReleaseSecurityDeposits(ctx)).To(Succeed())
+
+		By("keeping the deposit held for the claim")
+		Expect(SelectStatus("security_deposits", "booking_id", bookingId)).To(Equal(services.PaymentStatusAuthorised))
+
+		By("leaving the claim for the renter to answer")
+		Expect(SelectStatus("claims", "id", claim.ID)).To(Equal(models.ClaimStatusPending))
+	})
+
+	It("should be a success", func() {
+		By("running the job for a claim the renter didn't answer in time")
+		Expect(models.InsertClaim(ctx, pool, claim)).To(Succeed())
+		Age("claims", "created_at", claim.ID, config.ClaimResponseWindow.Seconds())
+		Expect(jobs.ReleaseSecurityDeposits(ctx)).To(Succeed())
+
+		By("handing the claim to admins as if it had been disputed")
+		Expect(SelectStatus("claims", "id", claim.ID)).To(Equal(models.ClaimStatusDisputed))
+
+		By("keeping the deposit held for the resolution")
+		Expect(SelectStatus("security_deposits", "booking_id", bookingId)).To(Equal(services.PaymentStatusAuthorised))
+	})
+})
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /admin/claims/:id/resolve", func() {
+	var (
+		accessToken  string
+		amount       int
+		bookingId    string
+		claim        *models.Claim
+		renterId     string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func(method string, path string, requestBodyMap gin.H) (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(method, path, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId := ""
+		hostId, renterId, bookingId = insertCompletedTrip(20000)
+		claim = &models.Claim{
+			ID:          "9b2f5d3e-4a1c-4e7b-8f0a-2c6d1e3b5a7f",
+			Amount:      15000,
+			BookingID:   bookingId,
+			Description: "The rear bumper was dented",
+			Photos:      []string{},
+			UserID:      hostId,
+		}
+		Expect(models.InsertClaim(ctx, pool, claim)).To(Succeed())
+
+		adminId := ""
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"admin@test.com", "Test", "Test", "Test", config.RoleAdmin},
+			InsertColumns: []string{"email", "firstname", "lastname", "password", "role"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&adminId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+		response, err := ExecuteRequest(http.MethodPost, "/claims/"+claim.ID+"/dispute", gin.H{"reason": "The dent was there before"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		accessToken = generateAccessToken(adminId, config.RoleAdmin)
+		amount = 5000
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM audit_logs")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("resolving a disputed claim for part of the amount")
+		response, err := ExecuteRequest(http.MethodPost, "/admin/claims/"+claim.ID+"/resolve", gin.H{"amount": amount, "reason": "Only the scratch is new"})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the resolved claim")
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("status", models.ClaimStatusResolved)))
+		Expect(responseBody).To(HaveKeyWithValue("claim", HaveKeyWithValue("settled_amount", BeNumerically("==", amount))))
+
+		By("capturing only the resolved amount from the deposit")
+		amountCaptured := 0
+		sql := "SELECT amount_captured FROM security_deposits WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&amountCaptured)).To(Succeed())
+		Expect(amountCaptured).To(Equal(amount))
+	})
+
+	It("should be a success", func() {
+		By("resolving a disputed claim for nothing")
+		amount = 0
+		response, err := ExecuteRequest(http.MethodPost, "/admin/claims/"+claim.ID+"/resolve", gin.H{"amount": amount, "reason": "The dent was there before"})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("releasing the deposit")
+		status := ""
+		sql := "SELECT status FROM security_deposits WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCancelled))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for more than was claimed")
+		response, err := ExecuteRequest(http.MethodPost, "/admin/claims/"+claim.ID+"/resolve", gin.H{"amount": 20000, "reason": "Too much"})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("amount"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as the renter")
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+		response, err := ExecuteRequest(http.MethodPost, "/admin/claims/"+claim.ID+"/resolve", gin.H{"amount": amount, "reason": "Not allowed"})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 403")
+		Expect(response).To(HaveHTTPStatus(http.StatusForbidden))
+	})
+})
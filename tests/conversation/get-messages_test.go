@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /conversations/:id/messages", func() {
+	var (
+		accessToken    string
+		conversationId string
+		query          string
+		responseBody   gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		url := fmt.Sprintf("/conversations/%v/messages?%v", conversationId, query)
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId, renterId, vehicleId := "", "", ""
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+			Destination:   []interface{}{&hostId},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		options.Arguments = []interface{}{"renter@test.com", "Test", "Test", "Test"}
+		options.Destination = []interface{}{&renterId}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = "INSERT INTO conversations (host_id, renter_id, vehicle_id) VALUES ($1, $2, $3) RETURNING id"
+		Expect(pool.QueryRow(ctx, sql, hostId, renterId, vehicleId).Scan(&conversationId)).To(Succeed())
+
+		sql = "INSERT INTO messages (body, conversation_id, created_at, sender_id) VALUES ($1, $2, $3, $4)"
+		for i := 0; i < 3; i++ {
+			createdAt := time.Now().Add(time.Duration(i) * time.Minute)
+			_, err := pool.Exec(ctx, sql, fmt.Sprintf("Message %v", i), conversationId, createdAt, hostId)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		renter := &models.User{ID: renterId}
+		token, err := renter.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken = token
+		query = "limit=2"
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request for the first page")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning the newest messages with a cursor to the older ones")
+		Expect(responseBody["messages"]).To(HaveLen(2))
+		Expect(responseBody["next_cursor"]).NotTo(BeNil())
+
+		By("sending a request for the next page with the cursor")
+		query = fmt.Sprintf("limit=2&before=%v", responseBody["next_cursor"])
+		response, err = ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning the remaining message without a cursor")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody["messages"]).To(HaveLen(1))
+		Expect(responseBody["next_cursor"]).To(BeNil())
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an invalid cursor")
+		query = "before=invalid"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("before"))
+	})
+})
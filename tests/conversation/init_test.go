@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConversationRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Conversation")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+		services.CreateRedisClient(ctx)
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+	})
+)
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /conversations/:id/messages", func() {
+	var (
+		accessToken    string
+		body           string
+		conversationId string
+		hostId         string
+		outsiderId     string
+		renterId       string
+		responseBody   gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"body": body}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		url := fmt.Sprintf("/conversations/%v/messages", conversationId)
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	var GenerateAccessToken = func(userId string) string {
+		user := &models.User{ID: userId}
+		token, err := user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	BeforeEach(func() {
+		options := models.SQLOptions{
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId, "outsider@test.com": &outsiderId} {
+			options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+			options.Destination = []interface{}{id}
+			Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+		}
+
+		vehicleId := ""
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = "INSERT INTO conversations (host_id, renter_id, vehicle_id) VALUES ($1, $2, $3) RETURNING id"
+		Expect(pool.QueryRow(ctx, sql, hostId, renterId, vehicleId).Scan(&conversationId)).To(Succeed())
+
+		accessToken = GenerateAccessToken(renterId)
+		body = "Can I pick the car up at 9am?"
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as a participant with a valid body")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the unread message")
+		Expect(responseBody).To(HaveKeyWithValue("message", HaveKeyWithValue("read_at", BeNil())))
+
+		By("counting the message as unread for the other participant")
+		count, err := models.CountUnreadMessages(ctx, pool, hostId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an empty body")
+		body = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("body"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request as a user who is not a participant")
+		accessToken = GenerateAccessToken(outsiderId)
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
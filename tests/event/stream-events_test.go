@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /events", func() {
+	var (
+		accessToken string
+		server      *httptest.Server
+		userId      string
+	)
+
+	var ExecuteRequest = func() (*http.Response, error) {
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		return http.DefaultClient.Do(request)
+	}
+
+	BeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"test@test.com", "Test", "Test", "Test"},
+			Destination:   []interface{}{&userId},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		user := &models.User{ID: userId}
+		token, err := user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		accessToken = token
+		server = httptest.NewServer(routes.SetupRouter())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		_, err := pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success if the user's event is published", func() {
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+
+		By("Checking the response headers")
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("Content-Type")).To(HavePrefix("text/event-stream"))
+		Expect(response.Header.Get("Cache-Control")).To(Equal("no-cache"))
+
+		lines := make(chan string)
+		go func() {
+			defer GinkgoRecover()
+			scanner := bufio.NewScanner(response.Body)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			close(lines)
+		}()
+
+		By("Publishing an event for the user")
+		event := services.Event{Type: services.EventMessageCreated, Data: map[string]string{"body": "Hello"}}
+		Expect(services.PublishEvent(ctx, userId, event)).To(Succeed())
+
+		By("Reading the event from the stream")
+		received := []string{}
+		readStream := func() string {
+			select {
+			case line := <-lines:
+				received = append(received, line)
+			case <-time.After(100 * time.Millisecond):
+			}
+			return strings.Join(received, "\n")
+		}
+		Eventually(readStream, 5*time.Second).Should(ContainSubstring(`"body":"Hello"`))
+		Expect(strings.Join(received, "\n")).To(ContainSubstring("event:" + services.EventMessageCreated))
+		Expect(received[0]).To(Equal(": connected"))
+	})
+
+	It("should be an error if the user is not logged in", func() {
+		accessToken = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+
+		By("Checking the status code")
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
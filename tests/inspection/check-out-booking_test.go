@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /bookings/:id/check-out", func() {
+	var (
+		accessToken  string
+		bookingId    string
+		fuelLevel    int
+		hostId       string
+		odometer     int
+		photo        []byte
+		renterId     string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBody := &bytes.Buffer{}
+		writer := multipart.NewWriter(requestBody)
+		if err := writer.WriteField("fuel_level", strconv.Itoa(fuelLevel)); err != nil {
+			return nil, err
+		}
+
+		if err := writer.WriteField("odometer", strconv.Itoa(odometer)); err != nil {
+			return nil, err
+		}
+
+		for _, side := range models.InspectionSides {
+			part, err := writer.CreateFormFile(side, side+".png")
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err = part.Write(photo); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/check-out", requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId, renterId, bookingId = insertTripInProgress()
+		buffer := &bytes.Buffer{}
+		Expect(png.Encode(buffer, image.NewRGBA(image.Rect(0, 0, 1, 1)))).To(Succeed())
+
+		accessToken = generateAccessToken(renterId, config.RoleRenter)
+		fuelLevel = 90
+		odometer = 1150
+		photo = buffer.Bytes()
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the renter")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the fees for 50km over the limit and 10% of fuel")
+		Expect(responseBody).To(HaveKeyWithValue("inspection", HaveKeyWithValue("distance", BeNumerically("==", 150))))
+		Expect(responseBody).To(HaveKeyWithValue("inspection", HaveKeyWithValue("mileage_fee", BeNumerically("==", 2500))))
+		Expect(responseBody).To(HaveKeyWithValue("inspection", HaveKeyWithValue("fuel_fee", BeNumerically("==", 1000))))
+		Expect(responseBody).To(HaveKeyWithValue("inspection", HaveKeyWithValue("host_acknowledged_at", BeNil())))
+	})
+
+	It("should be a success", func() {
+		By("the host acknowledging the check-out")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		request, err := http.NewRequest(http.MethodPost, "/bookings/"+bookingId+"/inspections/check_out/acknowledge", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: generateAccessToken(hostId, config.RoleHost)})
+		response := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(response, request)
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("capturing the fees from the renter")
+		status, amount := "", 0
+		sql := "SELECT status, amount FROM booking_charges WHERE booking_id = $1"
+		Expect(pool.QueryRow(ctx, sql, bookingId).Scan(&status, &amount)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		Expect(amount).To(Equal(3500))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an odometer lower than at check-in")
+		odometer = 999
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("odometer"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request after the check-out was recorded")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 409")
+		Expect(response).To(HaveHTTPStatus(http.StatusConflict))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request when the trip isn't in progress")
+		_, err := pool.Exec(ctx, "UPDATE bookings SET status = 'completed' WHERE id = $1", bookingId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})
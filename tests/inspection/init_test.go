@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestInspectionRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Inspection")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+		services.CreateRedisClient(ctx)
+		services.CreateFileStorage()
+		services.CreatePaymentProvider()
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+		Expect(os.RemoveAll(config.StorageDirectory)).To(Succeed())
+	})
+)
+
+// Inserts a host, a renter and a one day trip in progress with a daily mileage
+// limit of 100km, a captured payment and a check-in recorded by the host
+func insertTripInProgress() (string, string, string) {
+	hostId, renterId, vehicleId, bookingId := "", "", "", ""
+	options := models.SQLOptions{
+		InsertColumns: []string{"email", "firstname", "lastname", "password"},
+		ReturnColumns: []string{"id"},
+	}
+	for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+		options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+		options.Destination = []interface{}{id}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	}
+
+	sql := `
+	INSERT INTO vehicles (address, location, make, name, rental_fee, user_id) 
+	VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+	RETURNING id`
+	Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+	sql = `
+	INSERT INTO bookings (daily_mileage_limit, end_at, fuel_fee, mileage_fee, start_at, status, total_amount, user_id, vehicle_id) 
+	VALUES (100, NOW() + INTERVAL '1 day', 100, 50, NOW(), 'in_progress', 10000, $1, $2) 
+	RETURNING id`
+	Expect(pool.QueryRow(ctx, sql, renterId, vehicleId).Scan(&bookingId)).To(Succeed())
+
+	payment := &models.Payment{
+		Amount:           10000,
+		AmountCaptured:   10000,
+		BookingID:        bookingId,
+		Currency:         config.Currency,
+		PaymentMethodID:  services.FakePaymentMethodVisa,
+		Provider:         services.GetPaymentProvider().Name(),
+		ProviderIntentID: "pi_" + bookingId,
+		Status:           services.PaymentStatusCaptured,
+	}
+	Expect(models.InsertPayment(ctx, pool, payment)).To(Succeed())
+
+	sql = `
+	INSERT INTO inspections (booking_id, fuel_level, host_acknowledged_at, odometer, photos, type, user_id) 
+	VALUES ($1, 100, NOW(), 1000, $2, 'check_in', $3)`
+	_, err := pool.Exec(ctx, sql, bookingId, []string{"front.png", "back.png", "left.png", "right.png"}, hostId)
+	Expect(err).NotTo(HaveOccurred())
+	return hostId, renterId, bookingId
+}
+
+func generateAccessToken(userId string, role string) string {
+	user := &models.User{ID: userId, Role: role}
+	token, err := user.GenerateAccessToken()
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
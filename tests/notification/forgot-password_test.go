@@ -74,6 +74,12 @@ var _ = Describe("POST /notification/forgot-password", func() {
 
 		By("returning a body that contains a success message")
 		Expect(responseBody).To(HaveKey("message"))
+
+		By("adding a notification to the user's inbox")
+		count := 0
+		sql := "SELECT COUNT(*) FROM notifications WHERE type = $1"
+		Expect(pool.QueryRow(ctx, sql, models.NotificationTypePasswordReset).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
 	})
 
 	It("should be an error", func() {
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /notification", func() {
+	var (
+		accessToken  string
+		query        string
+		responseBody gin.H
+		userId       string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodGet, "/notification"+query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"test@test.com", "Test", "Test", "Test"},
+			Destination:   []interface{}{&userId},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		for _, title := range []string{"First", "Second"} {
+			notification := &models.Notification{Body: title, Title: title, Type: models.NotificationTypeBookingUpdated, UserID: userId}
+			Expect(models.InsertNotification(ctx, pool, notification)).To(Succeed())
+		}
+
+		_, err := pool.Exec(ctx, "UPDATE notifications SET read_at = NOW() WHERE title = 'First'")
+		Expect(err).NotTo(HaveOccurred())
+
+		user := &models.User{ID: userId}
+		accessToken, err = user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		query = ""
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the owner of the notifications")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning every notification and the unread count")
+		Expect(responseBody["notifications"]).To(HaveLen(2))
+		Expect(responseBody["unread_count"]).To(BeEquivalentTo(1))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for unread notifications only")
+		query = "?unread=true"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning only the unread notification")
+		notifications := responseBody["notifications"].([]interface{})
+		Expect(notifications).To(HaveLen(1))
+		Expect(notifications[0]).To(HaveKeyWithValue("title", "Second"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without an access token")
+		accessToken = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 401")
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+	})
+})
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PUT /notification/preferences/:type", func() {
+	var (
+		accessToken      string
+		email            interface{}
+		notificationType string
+		responseBody     gin.H
+		userId           string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyMap := gin.H{"email": email, "in_app": true, "sms": false}
+		requestBodyBytes, err := json.Marshal(requestBodyMap)
+		if err != nil {
+			return nil, err
+		}
+
+		url := "/notification/preferences/" + notificationType
+		request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		options := models.SQLOptions{
+			Arguments:     []interface{}{"test@test.com", "Test", "Test", "Test"},
+			Destination:   []interface{}{&userId},
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+
+		user := &models.User{ID: userId}
+		token, err := user.GenerateAccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		accessToken = token
+		email = false
+		notificationType = models.NotificationTypeUnreadMessages
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("turning off emails for unread messages")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("saving the preference")
+		preference, err := models.SelectNotificationPreference(ctx, pool, userId, notificationType)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(preference.Email).To(BeFalse())
+		Expect(preference.InApp).To(BeTrue())
+	})
+
+	It("should be an error", func() {
+		By("turning off emails for password resets")
+		notificationType = models.NotificationTypePasswordReset
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+		Expect(responseBody).To(HaveKey("email"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without the email channel")
+		email = nil
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+		Expect(responseBody).To(HaveKey("email"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an unknown notification type")
+		notificationType = "unknown"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+	})
+})
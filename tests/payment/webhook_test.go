@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /payments/webhook", func() {
+	var (
+		event        *services.PaymentWebhookEvent
+		hostId       string
+		payment      *models.Payment
+		responseBody gin.H
+		signature    string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if signature == "" {
+			signature = services.SignWebhookPayload(payload, config.PaymentWebhookSecret, time.Now())
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/payments/webhook", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Payment-Signature", signature)
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		renterId, vehicleId, bookingId := "", "", ""
+		options := models.SQLOptions{
+			InsertColumns: []string{"email", "firstname", "lastname", "password"},
+			ReturnColumns: []string{"id"},
+		}
+		for email, id := range map[string]*string{"host@test.com": &hostId, "renter@test.com": &renterId} {
+			options.Arguments = []interface{}{email, "Test", "Test", "Test"}
+			options.Destination = []interface{}{id}
+			Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+		}
+
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id) 
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Corolla', 10000, $1) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+
+		sql = `
+		INSERT INTO bookings (end_at, start_at, status, total_amount, user_id, vehicle_id) 
+		VALUES (NOW() + INTERVAL '2 days', NOW() + INTERVAL '1 day', 'confirmed', 10000, $1, $2) 
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, renterId, vehicleId).Scan(&bookingId)).To(Succeed())
+
+		provider := services.GetPaymentProvider()
+		intent, err := provider.CreateIntent(ctx, 10000, config.Currency, bookingId)
+		Expect(err).NotTo(HaveOccurred())
+		intent, err = provider.Authorise(ctx, intent.ID, services.FakePaymentMethodVisa)
+		Expect(err).NotTo(HaveOccurred())
+
+		payment = &models.Payment{
+			Amount:           intent.Amount,
+			BookingID:        bookingId,
+			Currency:         intent.Currency,
+			Provider:         provider.Name(),
+			ProviderIntentID: intent.ID,
+			Status:           intent.Status,
+		}
+		Expect(models.InsertPayment(ctx, pool, payment)).To(Succeed())
+
+		intent, err = provider.Capture(ctx, intent.ID, intent.Amount, "capture_"+intent.ID)
+		Expect(err).NotTo(HaveOccurred())
+		event = &services.PaymentWebhookEvent{ID: "evt_" + bookingId, Intent: *intent, Type: "payment_intent.captured"}
+		responseBody = gin.H{}
+		signature = ""
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM payment_webhook_events")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM ledger_transactions")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a signed event for a captured intent")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("updating the payment and recording the transition")
+		status, transitions := "", 0
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE id = $1", payment.ID).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+		sql := "SELECT COUNT(*) FROM payment_transitions WHERE payment_id = $1 AND to_status = $2"
+		Expect(pool.QueryRow(ctx, sql, payment.ID, services.PaymentStatusCaptured).Scan(&transitions)).To(Succeed())
+		Expect(transitions).To(Equal(1))
+
+		By("crediting the host with the charge less the platform fee")
+		balance, err := models.SelectHostBalance(ctx, pool, hostId)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(balance).To(Equal(10000 - models.CalculatePlatformFee(10000)))
+
+		By("leaving the ledger reconciled with the provider")
+		discrepancies, err := models.ReconcileLedger(ctx, pool, services.GetPaymentProvider())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(discrepancies).To(BeEmpty())
+	})
+
+	It("should be a success", func() {
+		By("sending the same event twice")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		signature = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("processing the event only once")
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM payment_webhook_events").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(1))
+	})
+
+	It("should be a success", func() {
+		By("sending an older event after the captured one")
+		_, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		authorised := event.Intent
+		authorised.AmountCaptured = 0
+		authorised.Status = services.PaymentStatusAuthorised
+		event = &services.PaymentWebhookEvent{ID: event.ID + "_old", Intent: authorised, Type: "payment_intent.authorised"}
+		signature = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("keeping the payment captured")
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE id = $1", payment.ID).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusCaptured))
+	})
+
+	It("should be an error", func() {
+		By("sending an event with an invalid signature")
+		signature = "t=1,v1=invalid"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 401")
+		Expect(response).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+		By("leaving the payment untouched")
+		status := ""
+		Expect(pool.QueryRow(ctx, "SELECT status FROM payments WHERE id = $1", payment.ID).Scan(&status)).To(Succeed())
+		Expect(status).To(Equal(services.PaymentStatusAuthorised))
+
+		By("reporting the missed capture when reconciling")
+		discrepancies, err := models.ReconcileLedger(ctx, pool, services.GetPaymentProvider())
+		Expect(err).NotTo(HaveOccurred())
+		fields := []string{}
+		for _, discrepancy := range discrepancies {
+			fields = append(fields, discrepancy.Field)
+		}
+		Expect(fields).To(ContainElement("held_amount"))
+	})
+})
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /users/:id", func() {
+	var (
+		query        string
+		responseBody gin.H
+		userId       string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodGet, "/users/"+userId+"?"+query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		query = ""
+		responseBody = gin.H{}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a renter without vehicles")
+		userId = insertUser("renter@test.com")
+		_, err := pool.Exec(ctx, "UPDATE users SET email_verified_at = NOW() WHERE id = $1", userId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the profile without vehicles")
+		Expect(responseBody).To(HaveKeyWithValue("reviews", BeEmpty()))
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", BeEmpty()))
+		user, ok := responseBody["user"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(user).To(HaveKeyWithValue("badges", ConsistOf("email_verified")))
+		Expect(user["host"]).To(HaveKeyWithValue("listings_count", BeNumerically("==", 0)))
+		Expect(user["host"]).To(HaveKeyWithValue("response_rate_30_days", BeNil()))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a page of a host's vehicles")
+		userId = insertUser("host@test.com")
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id)
+		SELECT 'Lagos', POINT(6.5, 3.3), 'Toyota', 'Toyota Camry ' || n, 20000, $1 FROM generate_series(1, 3) AS n`
+		_, err := pool.Exec(ctx, sql, userId)
+		Expect(err).NotTo(HaveOccurred())
+
+		query = "limit=2&page=2"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the page of vehicles")
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", HaveLen(1)))
+		user, ok := responseBody["user"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(user["host"]).To(HaveKeyWithValue("listings_count", BeNumerically("==", 3)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for a host whose response metrics have been worked out")
+		userId = insertUser("host@test.com")
+		renterId := insertUser("renter@test.com")
+		vehicleId := ""
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id)
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Toyota', 'Toyota Camry', 20000, $1)
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, userId).Scan(&vehicleId)).To(Succeed())
+
+		// One request answered after 30 minutes and one left to expire
+		sql = `
+		INSERT INTO bookings (cancellation_policy, created_at, end_at, expires_at, responded_at, start_at, status, total_amount, user_id, vehicle_id)
+		VALUES 
+			('moderate', NOW() - INTERVAL '2 days', NOW() + INTERVAL '5 days', NOW() - INTERVAL '1 day', NOW() - INTERVAL '2 days' + INTERVAL '30 minutes', NOW() + INTERVAL '3 days', 'confirmed', 20000, $1, $2),
+			('moderate', NOW() - INTERVAL '3 days', NOW() + INTERVAL '9 days', NOW() - INTERVAL '2 days', NULL, NOW() + INTERVAL '7 days', 'expired', 20000, $1, $2)`
+		_, err := pool.Exec(ctx, sql, renterId, vehicleId)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(jobs.UpdateHostResponseMetrics(ctx)).To(Succeed())
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the host's response metrics")
+		user, ok := responseBody["user"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(user["host"]).To(HaveKeyWithValue("response_rate_30_days", BeNumerically("==", 0.5)))
+		Expect(user["host"]).To(HaveKeyWithValue("response_time_30_days", BeNumerically("==", 30)))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for a user that doesn't exist")
+		userId = "d5c1f1d2-3c4b-4a5e-9f6a-7b8c9d0e1f2a"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKeyWithValue("message", "User not found"))
+	})
+})
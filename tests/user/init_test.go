@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUserRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "User")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+	})
+)
+
+func insertUser(email string) string {
+	userId := ""
+	options := models.SQLOptions{
+		Arguments:     []interface{}{email, "Test", "Test", "Test"},
+		InsertColumns: []string{"email", "firstname", "lastname", "password"},
+		ReturnColumns: []string{"id"},
+		Destination:   []interface{}{&userId},
+	}
+	Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	return userId
+}
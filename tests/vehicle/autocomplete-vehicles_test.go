@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /vehicles/autocomplete", func() {
+	var (
+		text         string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodGet, "/vehicles/autocomplete?q="+url.QueryEscape(text), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		responseBody = gin.H{}
+		hostId := insertHost()
+		sql := `
+		INSERT INTO vehicles (address, location, make, model, name, rental_fee, unlisted_at, user_id)
+		VALUES 
+			('Lagos', POINT(6.5, 3.3), 'Tesla', 'Model 3', 'Tesla Model 3', 30000, NULL, $1),
+			('Lagos', POINT(6.5, 3.3), 'Tesla', 'Model 3', 'Tesla Model 3', 32000, NULL, $1),
+			('Lagos', POINT(6.5, 3.3), 'Tesla', 'Model Y', 'Tesla Model Y', 35000, NOW(), $1),
+			('Lagos', POINT(6.5, 3.3), 'Toyota', 'Camry', 'Toyota Camry', 20000, NULL, $1)`
+		_, err := pool.Exec(ctx, sql, hostId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with the start of a make")
+		text = "tes"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the models of listed vehicles of that make")
+		suggestions, ok := responseBody["suggestions"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(suggestions).To(HaveLen(1))
+		Expect(suggestions[0]).To(HaveKeyWithValue("model", "Model 3"))
+		Expect(suggestions[0]).To(HaveKeyWithValue("vehicles_count", BeNumerically("==", 2)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request with a misspelled make")
+		text = "toyta"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the make it looks like")
+		suggestions, ok := responseBody["suggestions"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(suggestions).To(HaveLen(1))
+		Expect(suggestions[0]).To(HaveKeyWithValue("make", "Toyota"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request without text")
+		text = ""
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("q"))
+	})
+})
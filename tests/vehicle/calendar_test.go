@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/jobs"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vehicle calendars", func() {
+	var (
+		accessToken  string
+		feed         string
+		feedStatus   int
+		responseBody gin.H
+		server       *httptest.Server
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func(method string, path string, requestBody gin.H) (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(method, path, bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		responseBody = gin.H{}
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	var WriteFeed = func(startAt time.Time, days int) {
+		startAt = startAt.UTC()
+		feed = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Other//EN\r\n" +
+			"BEGIN:VEVENT\r\nUID:reservation-1\r\nSUMMARY:Reserved\r\n" +
+			"DTSTART;VALUE=DATE:" + startAt.Format("20060102") + "\r\n" +
+			"DTEND;VALUE=DATE:" + startAt.AddDate(0, 0, days).Format("20060102") + "\r\n" +
+			"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	}
+
+	var CountBlackouts = func() int {
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM vehicle_blackouts WHERE vehicle_id = $1", vehicleId).Scan(&count)).To(Succeed())
+		return count
+	}
+
+	BeforeEach(func() {
+		// The feeds are served locally, which calendar links aren't allowed to reach
+		services.AllowPrivateCalendarAddresses = true
+		feedStatus = http.StatusOK
+		WriteFeed(time.Now().AddDate(0, 0, 10), 3)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/calendar")
+			w.WriteHeader(feedStatus)
+			fmt.Fprint(w, feed)
+		}))
+
+		hostId := insertHost()
+		accessToken = generateAccessToken(hostId)
+		sql := `
+		INSERT INTO vehicles (address, category, doors, features, fuel_type, location, make, model, name, rental_fee, seats, transmission, user_id, year)
+		VALUES ('Lagos', 'sedan', 4, '{}', 'petrol', POINT(6.5, 3.3), 'Honda', 'Accord', 'Honda Accord', 15000, 5, 'automatic', $1, 2020)
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		services.AllowPrivateCalendarAddresses = false
+
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("adding an external calendar")
+		requestBody := gin.H{"name": "Other platform", "url": server.URL}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/calendars", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("importing its busy period as a blackout")
+		Expect(CountBlackouts()).To(Equal(1))
+
+		By("leaving the vehicle out of searches for those dates")
+		startAt := time.Now().AddDate(0, 0, 11).UTC()
+		query := url.Values{}
+		query.Set("start_at", startAt.Format(time.RFC3339))
+		query.Set("end_at", startAt.Add(24*time.Hour).Format(time.RFC3339))
+		response, err = ExecuteRequest(http.MethodGet, "/vehicles?"+query.Encode(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", BeEmpty()))
+
+		By("replacing the blackout when the calendar changes and the sync job runs")
+		WriteFeed(time.Now().AddDate(0, 0, 20), 2)
+		Expect(jobs.SyncVehicleCalendars(ctx)).To(Succeed())
+		Expect(CountBlackouts()).To(Equal(1))
+		startAt = time.Time{}
+		sql := "SELECT start_at FROM vehicle_blackouts WHERE vehicle_id = $1"
+		Expect(pool.QueryRow(ctx, sql, vehicleId).Scan(&startAt)).To(Succeed())
+		Expect(startAt.UTC().Format("20060102")).To(Equal(time.Now().AddDate(0, 0, 20).UTC().Format("20060102")))
+
+		By("serving the blackout in the vehicle's own feed")
+		response, err = ExecuteRequest(http.MethodGet, "/vehicles/"+vehicleId+"/calendars", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		feedURL, err := url.Parse(responseBody["feed_url"].(string))
+		Expect(err).NotTo(HaveOccurred())
+
+		request, err := http.NewRequest(http.MethodGet, feedURL.RequestURI(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		recorder := httptest.NewRecorder()
+		routes.SetupRouter().ServeHTTP(recorder, request)
+		Expect(recorder).To(HaveHTTPStatus(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/calendar"))
+		Expect(recorder.Body.String()).To(HavePrefix("BEGIN:VCALENDAR\r\n"))
+		Expect(strings.Count(recorder.Body.String(), "BEGIN:VEVENT")).To(Equal(1))
+	})
+
+	It("should be a success", func() {
+		By("syncing a calendar that can no longer be read")
+		requestBody := gin.H{"url": server.URL}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/calendars", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		feedStatus = http.StatusNotFound
+		Expect(jobs.SyncVehicleCalendars(ctx)).To(Succeed())
+
+		By("keeping the blackouts of the last sync")
+		Expect(CountBlackouts()).To(Equal(1))
+
+		By("recording why the sync failed")
+		response, err = ExecuteRequest(http.MethodGet, "/vehicles/"+vehicleId+"/calendars", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody).To(HaveKeyWithValue("calendars", ContainElement(HaveKeyWithValue("sync_error", Not(BeNil())))))
+	})
+
+	It("should be an error", func() {
+		By("adding a link that isn't a calendar")
+		feed = "<html></html>"
+		requestBody := gin.H{"url": server.URL}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/calendars", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("url"))
+		Expect(CountBlackouts()).To(Equal(0))
+	})
+
+	It("should be an error", func() {
+		By("adding a link to the server's own network")
+		services.AllowPrivateCalendarAddresses = false
+		requestBody := gin.H{"url": server.URL}
+		response, err := ExecuteRequest(http.MethodPost, "/vehicles/"+vehicleId+"/calendars", requestBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that doesn't say why the calendar couldn't be read")
+		Expect(responseBody).To(HaveKeyWithValue("url", "Calendar could not be read"))
+		Expect(CountBlackouts()).To(Equal(0))
+	})
+
+	It("should be an error", func() {
+		By("reading the feed with the wrong token")
+		response, err := ExecuteRequest(http.MethodGet, "/vehicles/"+vehicleId+"/calendar.ics?token=wrong", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /vehicles", func() {
+	var (
+		accessToken  string
+		hostId       string
+		requestBody  gin.H
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/vehicles", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId = insertHost()
+		accessToken = generateAccessToken(hostId)
+		responseBody = gin.H{}
+		requestBody = gin.H{
+			"address":      "Lagos",
+			"category":     "sedan",
+			"doors":        4,
+			"fuel_type":    "petrol",
+			"latitude":     6.5,
+			"longitude":    3.3,
+			"model":        "Accord",
+			"name":         "Honda Accord",
+			"rental_fee":   20000,
+			"seats":        5,
+			"transmission": "automatic",
+			"vin":          "1hgcm82633a004352",
+		}
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request without a make or year")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the vehicle pre-filled from the vin")
+		vehicle, ok := responseBody["vehicle"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicle).To(HaveKeyWithValue("make", "Honda"))
+		Expect(vehicle).To(HaveKeyWithValue("manufacturer", "American Honda Motor Company"))
+		Expect(vehicle).To(HaveKeyWithValue("vin", "1HGCM82633A004352"))
+		Expect(vehicle).To(HaveKeyWithValue("year", BeNumerically("==", 2003)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request with a european vin, which has no check digit")
+		requestBody["vin"] = "WVWZZZ1JZXW000001"
+		requestBody["year"] = 1999
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 201")
+		Expect(response).To(HaveHTTPStatus(http.StatusCreated))
+
+		By("returning a body that contains the vehicle pre-filled from the vin")
+		vehicle, ok := responseBody["vehicle"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicle).To(HaveKeyWithValue("make", "Volkswagen"))
+		Expect(vehicle).To(HaveKeyWithValue("vin", "WVWZZZ1JZXW000001"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a vin whose check digit is wrong")
+		requestBody["vin"] = "1HGCM82633A004353"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKeyWithValue("vin", "Vin is invalid"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with the vin of a listed vehicle")
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id, vin) 
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Honda', 'Honda Accord', 20000, $1, '1HGCM82633A004352')`
+		_, err := pool.Exec(ctx, sql, hostId)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 409")
+		Expect(response).To(HaveHTTPStatus(http.StatusConflict))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("vin"))
+	})
+})
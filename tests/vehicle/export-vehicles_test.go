@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /vehicles/export", func() {
+	var (
+		accessToken string
+		hostId      string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, [][]string, error) {
+		request, err := http.NewRequest(http.MethodGet, "/vehicles/export", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		records, err := csv.NewReader(response.Body).ReadAll()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return response, records, nil
+	}
+
+	BeforeEach(func() {
+		hostId = insertHost()
+		accessToken = generateAccessToken(hostId)
+		// A vehicle with every attribute and one listed before attributes were required
+		sql := `
+		INSERT INTO vehicles (
+			address, category, doors, external_id, features, fuel_type, location, make, model, name, rental_fee, seats, transmission, user_id, vin, year
+		)
+		VALUES 
+			('Lagos', 'sedan', 4, 'CAR-1', '{bluetooth,gps}', 'petrol', POINT(6.5, 3.3), 'Honda', 'Accord', 'Honda Accord', 15000, 5, 'automatic', $1, '1HGCM82633A004352', 2003),
+			('Lagos', NULL, NULL, NULL, '{}', NULL, POINT(6.5, 3.3), 'Toyota', NULL, 'Toyota Corolla', 10000, NULL, NULL, $1, NULL, NULL)`
+		_, err := pool.Exec(ctx, sql, hostId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the host")
+		response, records, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning the vehicles that can be imported again")
+		Expect(records).To(HaveLen(2))
+		Expect(records[0]).To(ContainElements("external_id", "vin", "features"))
+		Expect(records[1]).To(ContainElements("CAR-1", "1HGCM82633A004352", "bluetooth;gps"))
+
+		By("counting the vehicles that were left out")
+		Expect(response.Header().Get("X-Skipped-Vehicles")).To(Equal("1"))
+	})
+})
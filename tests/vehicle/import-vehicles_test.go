@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /vehicles/import", func() {
+	var (
+		accessToken  string
+		dryRun       bool
+		file         string
+		hostId       string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBody := &bytes.Buffer{}
+		writer := multipart.NewWriter(requestBody)
+		if err := writer.WriteField("dry_run", strconv.FormatBool(dryRun)); err != nil {
+			return nil, err
+		}
+
+		part, err := writer.CreateFormFile("file", "vehicles.csv")
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = part.Write([]byte(file)); err != nil {
+			return nil, err
+		}
+
+		if err = writer.Close(); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/vehicles/import", requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		dryRun = false
+		file = "external_id,vin,name,model,category,doors,seats,fuel_type,transmission,features,rental_fee,address,latitude,longitude\n" +
+			"CAR-1,1HGCM82633A004352,Honda Accord,Accord,sedan,4,5,petrol,automatic,bluetooth;gps,15000,Lagos,6.5,3.3\n" +
+			"CAR-2,1HGCM82653A004353,Honda Accord,Accord,sedan,4,5,petrol,automatic,,16000,Lagos,6.5,3.3\n"
+		responseBody = gin.H{}
+		hostId = insertHost()
+		accessToken = generateAccessToken(hostId)
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("importing a file of new vehicles")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("creating every vehicle with the make and year from its vin")
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", HaveLen(2)))
+		count := 0
+		sql := "SELECT COUNT(*) FROM vehicles WHERE user_id = $1 AND make = 'Honda' AND year = 2003"
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+
+		By("updating the vehicle with the same external id when imported again")
+		file = "external_id,vin,name,model,category,doors,seats,fuel_type,transmission,rental_fee,address,latitude,longitude\n" +
+			"CAR-1,1HGCM82633A004352,Honda Accord,Accord,sedan,4,5,petrol,automatic,20000,Lagos,6.5,3.3\n"
+		response, err = ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", ContainElement(HaveKeyWithValue("action", "updated"))))
+
+		rentalFee := 0
+		sql = "SELECT rental_fee FROM vehicles WHERE user_id = $1 AND external_id = 'CAR-1'"
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&rentalFee)).To(Succeed())
+		Expect(rentalFee).To(Equal(20000))
+	})
+
+	It("should be a success", func() {
+		By("sending a dry run")
+		dryRun = true
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains what would be created")
+		Expect(responseBody).To(HaveKeyWithValue("vehicles", ContainElement(HaveKeyWithValue("action", "created"))))
+
+		By("not creating any vehicle")
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM vehicles").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(0))
+	})
+
+	It("should be an error", func() {
+		By("sending a file with an invalid row")
+		file += "CAR-3,1HGCM82673A004354,Honda Accord,Accord,sedan,many,5,petrol,automatic,,0,Lagos,6.5,3.3\n"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains the errors of the row")
+		rowErrors, ok := responseBody["errors"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(rowErrors).To(HaveLen(1))
+		Expect(rowErrors[0]).To(HaveKeyWithValue("row", BeNumerically("==", 4)))
+		Expect(rowErrors[0]).To(HaveKeyWithValue("errors", HaveKey("doors")))
+		Expect(rowErrors[0]).To(HaveKeyWithValue("errors", HaveKey("rental_fee")))
+
+		By("not creating any vehicle")
+		count := 0
+		Expect(pool.QueryRow(ctx, "SELECT COUNT(*) FROM vehicles").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(0))
+	})
+})
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ekenzy-101/Pentahire-API/models"
+	"github.com/Ekenzy-101/Pentahire-API/services"
+	"github.com/jackc/pgx/v4/pgxpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestVehicleRoutes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Vehicle")
+}
+
+var (
+	pool *pgxpool.Pool
+	ctx  = context.Background()
+
+	_ = BeforeSuite(func() {
+		pool = services.CreatePostgresConnectionPool(ctx)
+		services.CreateRedisClient(ctx)
+		services.CreateVINDecoder()
+	})
+
+	_ = AfterSuite(func() {
+		pool.Close()
+	})
+)
+
+func insertHost() string {
+	hostId := ""
+	options := models.SQLOptions{
+		Arguments:     []interface{}{"host@test.com", "Test", "Test", "Test"},
+		InsertColumns: []string{"email", "firstname", "lastname", "password"},
+		ReturnColumns: []string{"id"},
+		Destination:   []interface{}{&hostId},
+	}
+	Expect(models.InsertUserRow(ctx, options)).To(BeNil())
+	return hostId
+}
+
+func generateAccessToken(userId string) string {
+	user := &models.User{ID: userId}
+	token, err := user.GenerateAccessToken()
+	Expect(err).NotTo(HaveOccurred())
+	return token
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GET /vehicles", func() {
+	var (
+		query        string
+		responseBody gin.H
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		request, err := http.NewRequest(http.MethodGet, "/vehicles?"+query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		responseBody = gin.H{}
+		hostId := insertHost()
+		// An electric SUV in Lagos, a petrol van in Ibadan and an unlisted SUV in Lagos
+		sql := `
+		INSERT INTO vehicles (
+			address, category, doors, ev_range, features, fuel_type, location, make, model, name, rental_fee, seats, transmission, unlisted_at, user_id, year
+		)
+		VALUES 
+			('Lagos', 'suv', 4, 400, '{bluetooth,all_wheel_drive}', 'electric', POINT(6.5, 3.3), 'Tesla', 'Model Y', 'Tesla Model Y', 30000, 5, 'automatic', NULL, $1, 2022),
+			('Ibadan', 'van', 4, NULL, '{bluetooth}', 'petrol', POINT(7.4, 3.9), 'Toyota', 'Hiace', 'Toyota Hiace', 20000, 12, 'manual', NULL, $1, 2018),
+			('Lagos', 'suv', 4, NULL, '{}', 'petrol', POINT(6.5, 3.3), 'Toyota', 'RAV4', 'Toyota RAV4', 25000, 5, 'automatic', NOW(), $1, 2020)`
+		_, err := pool.Exec(ctx, sql, hostId)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request with attribute filters")
+		query = "category=suv&features=bluetooth&features=all_wheel_drive&fuel_type=electric&min_seats=5"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains only the listed vehicles that match")
+		vehicles, ok := responseBody["vehicles"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicles).To(HaveLen(1))
+		Expect(vehicles[0]).To(HaveKeyWithValue("model", "Model Y"))
+		Expect(vehicles[0]).To(HaveKeyWithValue("ev_range", BeNumerically("==", 400)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request for vehicles near a point")
+		query = "latitude=6.45&longitude=3.39&radius=20"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the listed vehicles within the radius with their distance")
+		vehicles, ok := responseBody["vehicles"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicles).To(HaveLen(1))
+		Expect(vehicles[0]).To(HaveKeyWithValue("address", "Lagos"))
+		Expect(vehicles[0]).To(HaveKeyWithValue("distance_in_km", BeNumerically("<", 20)))
+	})
+
+	It("should be a success", func() {
+		By("sending a request with misspelled text")
+		query = "q=tesla+modle+y"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the listed vehicles that look like it")
+		vehicles, ok := responseBody["vehicles"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicles).To(HaveLen(1))
+		Expect(vehicles[0]).To(HaveKeyWithValue("name", "Tesla Model Y"))
+	})
+
+	It("should be a success", func() {
+		By("sending a request with a misspelled address")
+		query = "q=ibadn"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the listed vehicles there")
+		vehicles, ok := responseBody["vehicles"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicles).To(HaveLen(1))
+		Expect(vehicles[0]).To(HaveKeyWithValue("name", "Toyota Hiace"))
+	})
+
+	It("should be a success", func() {
+		By("sending a request with text asking for a number of seats")
+		query = "q=12+seater+van"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("returning a body that contains the listed vehicles with enough seats")
+		vehicles, ok := responseBody["vehicles"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(vehicles).To(HaveLen(1))
+		Expect(vehicles[0]).To(HaveKeyWithValue("name", "Toyota Hiace"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with a fuel type that doesn't exist")
+		query = "fuel_type=steam"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("fuel_type"))
+	})
+})
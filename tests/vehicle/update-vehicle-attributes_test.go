@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Ekenzy-101/Pentahire-API/config"
+	"github.com/Ekenzy-101/Pentahire-API/routes"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PUT /vehicles/:id/attributes", func() {
+	var (
+		accessToken  string
+		hostId       string
+		requestBody  gin.H
+		responseBody gin.H
+		vehicleId    string
+	)
+
+	var ExecuteRequest = func() (*httptest.ResponseRecorder, error) {
+		requestBodyBytes, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodPut, "/vehicles/"+vehicleId+"/attributes", bytes.NewReader(requestBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		request.AddCookie(&http.Cookie{Name: config.AccessTokenCookieName, Value: accessToken})
+		response := httptest.NewRecorder()
+		router := routes.SetupRouter()
+		router.ServeHTTP(response, request)
+		err = json.NewDecoder(response.Body).Decode(&responseBody)
+		if err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	BeforeEach(func() {
+		hostId = insertHost()
+		accessToken = generateAccessToken(hostId)
+		responseBody = gin.H{}
+		requestBody = gin.H{
+			"category":     "suv",
+			"doors":        4,
+			"ev_range":     400,
+			"features":     []string{"bluetooth", "gps"},
+			"fuel_type":    "electric",
+			"model":        "Model Y",
+			"seats":        5,
+			"transmission": "automatic",
+			"year":         2022,
+		}
+
+		sql := `
+		INSERT INTO vehicles (address, location, make, name, rental_fee, user_id)
+		VALUES ('Lagos', POINT(6.5, 3.3), 'Tesla', 'Tesla Model Y', 30000, $1)
+		RETURNING id`
+		Expect(pool.QueryRow(ctx, sql, hostId).Scan(&vehicleId)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := pool.Exec(ctx, "DELETE FROM vehicles")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pool.Exec(ctx, "DELETE FROM users")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should be a success", func() {
+		By("sending a request as the host")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 200")
+		Expect(response).To(HaveHTTPStatus(http.StatusOK))
+
+		By("storing the attributes on the vehicle")
+		category, evRange, features := "", 0, []string{}
+		sql := "SELECT category, ev_range, features FROM vehicles WHERE id = $1"
+		Expect(pool.QueryRow(ctx, sql, vehicleId).Scan(&category, &evRange, &features)).To(Succeed())
+		Expect(category).To(Equal("suv"))
+		Expect(evRange).To(Equal(400))
+		Expect(features).To(ConsistOf("bluetooth", "gps"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an unknown feature")
+		requestBody["features"] = []string{"jetpack"}
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).NotTo(BeEmpty())
+	})
+
+	It("should be an error", func() {
+		By("sending a request with an ev_range for a petrol vehicle")
+		requestBody["fuel_type"] = "petrol"
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 400")
+		Expect(response).To(HaveHTTPStatus(http.StatusBadRequest))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("ev_range"))
+	})
+
+	It("should be an error", func() {
+		By("sending a request for another host's vehicle")
+		accessToken = generateAccessToken("00000000-0000-0000-0000-000000000000")
+		response, err := ExecuteRequest()
+		Expect(err).NotTo(HaveOccurred())
+
+		By("returning a status code of 404")
+		Expect(response).To(HaveHTTPStatus(http.StatusNotFound))
+
+		By("returning a body that contains error messages")
+		Expect(responseBody).To(HaveKey("message"))
+	})
+})